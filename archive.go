@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+)
+
+// ArchiveFormat selects the format of the domain files written by SaveLocaleArchiveFormat
+type ArchiveFormat int
+
+const (
+	// ArchiveMo writes the domains as GNU gettext .mo files. It's the format of SaveLocaleArchive.
+	ArchiveMo ArchiveFormat = iota
+
+	// ArchivePo writes the domains as .po files, i.e. to hand them to translators
+	ArchivePo
+)
+
+// SaveLocaleArchive writes all the domains loaded in l as .mo files into a zip archive, with the standard
+// "<lang>/LC_MESSAGES/<domain>.mo" layout, so the archive can be extracted into a library root and loaded with NewLocale.
+func SaveLocaleArchive(l *Locale, w io.Writer) error {
+	return SaveLocaleArchiveFormat(l, w, ArchiveMo)
+}
+
+// SaveLocaleArchiveFormat works like SaveLocaleArchive, writing the domains in the given format.
+// Hierarchical domain names are written to nested directories, see Locale.SetDomainSeparator.
+// Like Mo.MarshalBinary, .mo files leave out untranslated and obsolete entries.
+func SaveLocaleArchiveFormat(l *Locale, w io.Writer, format ArchiveFormat) error {
+	l.RLock()
+	lang := l.lang
+	names := l.domainNames()
+	domains := make(map[string]Translator, len(l.Domains))
+	for name, tr := range l.Domains {
+		domains[name] = tr
+	}
+	l.RUnlock()
+
+	ext := "mo"
+	if format == ArchivePo {
+		ext = "po"
+	}
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		tr := domains[name]
+		if tr == nil {
+			continue
+		}
+
+		data, err := archiveData(tr, format)
+		if err != nil {
+			return fmt.Errorf("domain %q: %w", name, err)
+		}
+
+		f, err := zw.Create(path.Join(lang, "LC_MESSAGES", l.domainPath(name)+"."+ext))
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// archiveData returns the content of the domain file of tr in the given format
+func archiveData(tr Translator, format ArchiveFormat) ([]byte, error) {
+	if format == ArchivePo {
+		return tr.GetDomain().MarshalText()
+	}
+	if mo, ok := tr.(*Mo); ok {
+		return mo.MarshalBinary()
+	}
+	return tr.GetDomain().marshalMo(false)
+}
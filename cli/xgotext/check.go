@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/tanyinloo/gotext"
+)
+
+// problem is an issue found by the check subcommand, at a line of the checked file
+type problem struct {
+	line int
+	msg  string
+}
+
+// check parses the .po file in strictly and returns every problem found:
+// parse errors, format strings with mismatching verbs, plural entries with a wrong number of forms (see Domain.ValidatePlurals)
+// and duplicate msgids.
+// Fuzzy entries are only checked for duplicates, as they aren't used until reviewed.
+func check(in string) ([]problem, error) {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []problem
+
+	po := gotext.NewPo()
+	po.SetStrict(true)
+	if err = po.ParseBytes(data); err != nil {
+		problems = append(problems, problem{0, err.Error()})
+	}
+
+	domain := po.GetDomain()
+
+	domain.ForEachC(func(ctx, id string, trans *gotext.Translation) bool {
+		if id == "" || trans.IsFuzzy() {
+			return true
+		}
+
+		if err := trans.CheckFormat(); err != nil {
+			problems = append(problems, problem{trans.Line, err.Error()})
+		}
+		return true
+	})
+
+	for _, perr := range domain.ValidatePlurals() {
+		problems = append(problems, problem{perr.Line, perr.Error()})
+	}
+
+	for _, group := range domain.FindDuplicates(false) {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Line < group[j].Line
+		})
+		for _, dup := range group[1:] {
+			problems = append(problems, problem{dup.Line,
+				fmt.Sprintf("duplicate msgid %q, first defined at line %d", dup.ID, group[0].Line)})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].line < problems[j].line
+	})
+
+	return problems, nil
+}
+
+// runCheck implements the "xgotext check <file.po>..." subcommand
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		log.Printf("Usage of xgotext check: xgotext check <file.po>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		log.Fatal("No input file given")
+	}
+
+	count := 0
+	for _, in := range fs.Args() {
+		problems, err := check(in)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, p := range problems {
+			if p.line > 0 {
+				log.Printf("%s:%d: %s", in, p.line, p.msg)
+			} else {
+				log.Printf("%s: %s", in, p.msg)
+			}
+		}
+		count += len(problems)
+	}
+
+	if count > 0 {
+		log.Printf("%d problem(s) found", count)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+
+	"github.com/tanyinloo/gotext"
+)
+
+// compileStats holds the counters reported by the compile subcommand, like msgfmt --statistics
+type compileStats struct {
+	translated   int
+	fuzzy        int
+	untranslated int
+}
+
+// compile parses the .po file in and writes it in the .mo format to out.
+// Fuzzy entries are left out unless useFuzzy is set.
+func compile(in, out string, useFuzzy, hashTable bool) (compileStats, error) {
+	var stats compileStats
+
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return stats, err
+	}
+
+	po := gotext.NewPo()
+	if err = po.ParseBytes(data); err != nil {
+		return stats, err
+	}
+
+	domain := po.GetDomain()
+	if !useFuzzy {
+		stats.fuzzy = domain.DropFuzzyTranslations()
+	}
+	domain.ForEachC(func(ctx, id string, trans *gotext.Translation) bool {
+		switch {
+		case id == "":
+			// header entry
+		case trans.IsTranslated():
+			stats.translated++
+		case len(trans.Trs) == 0 && trans.IsFuzzy() && !useFuzzy:
+			// already counted as fuzzy
+		default:
+			stats.untranslated++
+		}
+		return true
+	})
+
+	mo := gotext.NewMoFromDomain(domain)
+	mo.SetWriteHashTable(hashTable)
+	bin, err := mo.MarshalBinary()
+	if err != nil {
+		return stats, err
+	}
+
+	return stats, ioutil.WriteFile(out, bin, 0644)
+}
+
+// runCompile implements the "xgotext compile <in.po> -o <out.mo>" subcommand
+func runCompile(args []string) {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	output := fs.String("o", "", "output file: /path/to/messages.mo")
+	useFuzzy := fs.Bool("use-fuzzy", false, "include fuzzy entries in the output")
+	noHashTable := fs.Bool("no-hash", false, "don't write the lookup hash table")
+	fs.Usage = func() {
+		log.Printf("Usage of xgotext compile: xgotext compile [flags] <in.po>")
+		fs.PrintDefaults()
+	}
+
+	// Allow flags after the input file
+	var in string
+	for {
+		if err := fs.Parse(args); err != nil {
+			log.Fatal(err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if in != "" {
+			log.Fatal("Only one input file can be compiled")
+		}
+		in, args = fs.Arg(0), fs.Args()[1:]
+	}
+
+	if in == "" {
+		fs.Usage()
+		log.Fatal("No input file given")
+	}
+	if *output == "" {
+		log.Fatal("No output file given")
+	}
+
+	stats, err := compile(in, *output, *useFuzzy, !*noHashTable)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("%d translated messages, %d fuzzy translations skipped, %d untranslated messages.",
+		stats.translated, stats.fuzzy, stats.untranslated)
+}
@@ -0,0 +1,24 @@
+package dynamic
+
+// Translator is implemented by the application, gotext isn't imported here
+type Translator interface {
+	T(str string) string
+	Tn(str, plural string, n int) string
+}
+
+const (
+	greeting = "constant greeting"
+	prefix   = "concatenated "
+)
+
+type Handler struct {
+	i18n Translator
+}
+
+func (h Handler) greet(name string, n int) {
+	h.i18n.T(greeting)
+	h.i18n.T(prefix + "string")
+	h.i18n.Tn("constant singular", prefix+"plural", n)
+	h.i18n.T(name)
+	h.i18n.T("Hello " + name)
+}
@@ -0,0 +1,20 @@
+package injected
+
+// Translator is implemented by the application, gotext isn't imported here
+type Translator interface {
+	T(str string) string
+	Tn(str, plural string, n int) string
+	Tpc(ctx, str string) string
+}
+
+type Handler struct {
+	i18n Translator
+}
+
+func (h Handler) greet(n int) {
+	// Greeting of the home page,
+	// keep it short
+	h.i18n.T("injected call")
+	h.i18n.Tn("injected singular", "injected plural", n)
+	h.i18n.Tpc("injected-ctx", "injected context")
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/tanyinloo/gotext"
 	alias "github.com/tanyinloo/gotext"
+	_ "github.com/tanyinloo/gotext/cli/xgotext/fixtures/injected"
 	"github.com/tanyinloo/gotext/cli/xgotext/fixtures/pkg"
 )
 
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tanyinloo/gotext"
+)
+
+// keyName returns an exported Go identifier for a msgid used as key,
+// i.e. "checkout.button.pay" gives "CheckoutButtonPay"
+func keyName(id string) string {
+	var name strings.Builder
+	upper := true
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name.WriteRune(r)
+	}
+
+	// Identifiers must start with a letter, and be exported
+	if name.Len() == 0 || !unicode.IsUpper([]rune(name.String())[0]) {
+		return "Key" + name.String()
+	}
+
+	return name.String()
+}
+
+// generateKeys returns the source of a Go file declaring a string constant for every msgid of the .po file in,
+// sorted by msgid so the output only changes with the catalog
+func generateKeys(in, pkg string) ([]byte, error) {
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return nil, err
+	}
+
+	po := gotext.NewPo()
+	if err = po.ParseBytes(data); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	po.GetDomain().ForEachC(func(ctx, id string, trans *gotext.Translation) bool {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		return true
+	})
+	sort.Strings(ids)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by xgotext keys from %s; DO NOT EDIT.\n\n", strconv.Quote(filepath.Base(in)))
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("// Translation keys\nconst (\n")
+
+	names := make(map[string]bool)
+	for _, id := range ids {
+		name := keyName(id)
+		for i := 2; names[name]; i++ {
+			name = keyName(id) + strconv.Itoa(i)
+		}
+		names[name] = true
+
+		fmt.Fprintf(&buf, "\t%s = %s\n", name, strconv.Quote(id))
+	}
+	buf.WriteString(")\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// runKeys implements the "xgotext keys <in.po> -o <keys.go>" subcommand
+func runKeys(args []string) {
+	fs := flag.NewFlagSet("keys", flag.ExitOnError)
+	output := fs.String("o", "", "output file: /path/to/keys/keys.go")
+	pkg := fs.String("pkg", "keys", "package name of the generated file")
+	fs.Usage = func() {
+		log.Printf("Usage of xgotext keys: xgotext keys [flags] <in.po>")
+		fs.PrintDefaults()
+	}
+
+	// Allow flags after the input file
+	var in string
+	for {
+		if err := fs.Parse(args); err != nil {
+			log.Fatal(err)
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if in != "" {
+			log.Fatal("Only one input file can be read")
+		}
+		in, args = fs.Arg(0), fs.Args()[1:]
+	}
+
+	if in == "" {
+		fs.Usage()
+		log.Fatal("No input file given")
+	}
+	if *output == "" {
+		log.Fatal("No output file given")
+	}
+
+	src, err := generateKeys(in, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/tanyinloo/gotext/cli/xgotext/parser"
@@ -17,14 +19,87 @@ var (
 	defaultDomain = flag.String("default", "default", "Name of default domain")
 	excludeDirs   = flag.String("exclude", ".git", "Comma separated list of directories to exclude")
 	verbose       = flag.Bool("v", false, "print currently handled directory")
+	noGoFormat    = flag.Bool("no-go-format", false, "don't add the go-format flag to strings containing fmt verbs")
+	errOnDynamic  = flag.Bool("error-on-dynamic", false, "fail when a translation function is called with non-constant strings")
+	dynDomain     = flag.Bool("dynamic-domain-default", false, "extract the strings of calls with a non-constant domain into the default domain instead of skipping them")
+	tagKeys       = flag.String("tag", "", "Comma separated list of struct tag keys whose values are extracted, i.e. label (only with -in)")
+	columns       = flag.Bool("columns", false, "add the column to the source references, as file:line:col")
+	keywords      = keywordFlag{}
+	addComments   = commentsFlag{}
 )
 
-func main() {
-	flag.Parse()
+func init() {
+	flag.Var(keywords, "keyword", "method extracted whatever its receiver, as name[:msgid[,plural]] with an optional Nc context position, i.e. Tn:1,2 (repeatable)")
+	flag.Var(&addComments, "add-comments", "write the comments directly above calls as extracted comments, or with =TAG only the ones starting with TAG, i.e. -add-comments=TRANSLATORS:")
+}
+
+// keywordFlag collects the -keyword flags
+type keywordFlag map[string]parser.Keyword
+
+func (k keywordFlag) String() string {
+	names := make([]string, 0, len(k))
+	for name := range k {
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (k keywordFlag) Set(spec string) error {
+	name, kw, err := parser.ParseKeyword(spec)
+	if err != nil {
+		return err
+	}
+	k[name] = kw
+	return nil
+}
 
+// commentsFlag is the -add-comments flag, given alone to extract every comment above a call,
+// or as -add-comments=TAG to extract only the ones starting with TAG
+type commentsFlag struct {
+	enabled bool
+	tag     string
+}
+
+func (c *commentsFlag) String() string {
+	return c.tag
+}
+
+func (c *commentsFlag) IsBoolFlag() bool {
+	return true
+}
+
+func (c *commentsFlag) Set(tag string) error {
+	switch tag {
+	case "true":
+		c.enabled, c.tag = true, ""
+	case "false":
+		c.enabled, c.tag = false, ""
+	default:
+		c.enabled, c.tag = true, tag
+	}
+	return nil
+}
+
+func main() {
 	// Init logger
 	log.SetFlags(0)
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compile":
+			runCompile(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "keys":
+			runKeys(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Parse()
+
 	if *pkgTree == "" && *dirName == "" {
 		log.Fatal("No input directory given")
 	}
@@ -36,7 +111,16 @@ func main() {
 	}
 
 	data := &parser.DomainMap{
-		Default: *defaultDomain,
+		Default:                *defaultDomain,
+		NoGoFormat:             *noGoFormat,
+		Keywords:               keywords,
+		Columns:                *columns,
+		DynamicDomainToDefault: *dynDomain,
+		AddComments:            addComments.enabled,
+		CommentTag:             addComments.tag,
+	}
+	if *tagKeys != "" {
+		data.TagKeys = strings.Split(*tagKeys, ",")
 	}
 
 	if *pkgTree != "" {
@@ -51,8 +135,25 @@ func main() {
 		}
 	}
 
+	if *errOnDynamic {
+		if err := checkDynamic(data); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	err := data.Save(*outputDir)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// checkDynamic logs the calls with non-constant translation arguments and returns an error if there are any
+func checkDynamic(data *parser.DomainMap) error {
+	if len(data.UnsupportedCalls) == 0 {
+		return nil
+	}
+	for _, call := range data.UnsupportedCalls {
+		log.Printf("non-constant translation argument at %s", call)
+	}
+	return fmt.Errorf("%d call(s) with non-constant translation arguments", len(data.UnsupportedCalls))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tanyinloo/gotext"
+	"github.com/tanyinloo/gotext/cli/xgotext/parser"
+	"github.com/tanyinloo/gotext/cli/xgotext/parser/dir"
+)
+
+func writePo(t *testing.T, src string) string {
+	t.Helper()
+
+	in := filepath.Join(t.TempDir(), "messages.po")
+	if err := os.WriteFile(in, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func readMo(t *testing.T, path string) *gotext.Mo {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo, err := gotext.NewMoFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mo
+}
+
+func TestCompile(t *testing.T) {
+	in := writePo(t, `msgid ""
+msgstr ""
+"Language: en\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello"
+msgstr "Hi"
+
+#, fuzzy
+msgid "Bye"
+msgstr "Ciao"
+
+msgid "Untranslated"
+msgstr ""
+
+msgid "One apple"
+msgid_plural "%d apples"
+msgstr[0] "One apple"
+msgstr[1] "%d apples"
+`)
+	out := filepath.Join(t.TempDir(), "messages.mo")
+
+	stats, err := compile(in, out, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.translated != 2 || stats.fuzzy != 1 || stats.untranslated != 1 {
+		t.Errorf("Expected 2 translated, 1 fuzzy and 1 untranslated but got %+v", stats)
+	}
+
+	mo := readMo(t, out)
+	if tr := mo.Get("Hello"); tr != "Hi" {
+		t.Errorf("Expected 'Hi' but got '%s'", tr)
+	}
+	if tr := mo.Get("Bye"); tr != "Bye" {
+		t.Errorf("Expected the fuzzy entry to be left out but got '%s'", tr)
+	}
+
+	if _, err = compile(in, out, true, false); err != nil {
+		t.Fatal(err)
+	}
+	mo = readMo(t, out)
+	if tr := mo.Get("Bye"); tr != "Ciao" {
+		t.Errorf("Expected 'Ciao' but got '%s'", tr)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	in := writePo(t, `msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "Hello %s"
+msgstr "Привет %d"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+
+msgid "Dup"
+msgstr "Первый"
+
+msgid "Dup"
+msgstr "Второй"
+
+msgid "Gap"
+
+msgstr "Пробел"
+`)
+
+	problems, err := check(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"blank line or comment", "Hello %s", "One file", "duplicate msgid \"Dup\""}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems but got %d: %v", len(expected), len(problems), problems)
+	}
+	for _, msg := range expected {
+		found := false
+		for _, p := range problems {
+			if strings.Contains(p.msg, msg) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a problem about '%s' in %v", msg, problems)
+		}
+	}
+
+	clean := writePo(t, `msgid ""
+msgstr ""
+"Language: ru\n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+`)
+	if problems, err = check(clean); err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems but got %v", problems)
+	}
+}
+
+func TestGenerateKeys(t *testing.T) {
+	in := writePo(t, `msgid ""
+msgstr ""
+
+msgid "checkout.button.pay"
+msgstr "Pay"
+
+msgid "1st.item"
+msgstr "First"
+
+msgctxt "menu"
+msgid "checkout.button.pay"
+msgstr "Pay now"
+`)
+
+	src, err := generateKeys(in, "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := string(src)
+	for _, expected := range []string{
+		"// Code generated by xgotext keys from \"messages.po\"; DO NOT EDIT.",
+		"package keys",
+		"CheckoutButtonPay = \"checkout.button.pay\"",
+		"Key1stItem        = \"1st.item\"",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected '%s' in:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "checkout.button.pay") != 1 {
+		t.Errorf("Expected a single constant for 'checkout.button.pay' in:\n%s", out)
+	}
+}
+
+func TestCheckDynamic(t *testing.T) {
+	data := &parser.DomainMap{
+		Default:  "default",
+		Keywords: make(map[string]parser.Keyword),
+	}
+	name, kw, err := parser.ParseKeyword("T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.Keywords[name] = kw
+
+	if err = dir.ParseDirRec(filepath.Join("fixtures", "injected"), nil, data, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkDynamic(data); err != nil {
+		t.Errorf("Expected no error for constant arguments but got '%v'", err)
+	}
+
+	if err = dir.ParseDirRec(filepath.Join("fixtures", "dynamic"), nil, data, false); err != nil {
+		t.Fatal(err)
+	}
+	err = checkDynamic(data)
+	if err == nil || err.Error() != "2 call(s) with non-constant translation arguments" {
+		t.Errorf("Expected an error for the 2 non-constant calls but got '%v'", err)
+	}
+}
+
+func TestCommentsFlag(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		enabled bool
+		tag     string
+	}{
+		{nil, false, ""},
+		{[]string{"-add-comments"}, true, ""},
+		{[]string{"-add-comments=TRANSLATORS:"}, true, "TRANSLATORS:"},
+		{[]string{"-add-comments=false"}, false, ""},
+	} {
+		var comments commentsFlag
+		fs := flag.NewFlagSet("xgotext", flag.ContinueOnError)
+		fs.Var(&comments, "add-comments", "")
+		if err := fs.Parse(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		if comments.enabled != tc.enabled || comments.tag != tc.tag {
+			t.Errorf("Expected %v and tag '%s' for %v but got %v and '%s'", tc.enabled, tc.tag, tc.args, comments.enabled, comments.tag)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package dir
 
 import (
-	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
 
@@ -87,11 +88,17 @@ func goParser(dirPath, basePath string, data *parser.DomainMap) error {
 			basePath: basePath,
 			data:     data,
 			fileSet:  fileSet,
+			comments: node.Comments,
 
 			importedPackages: map[string]*packages.Package{
 				pkgs[0].Name: pkgs[0],
 			},
 		}
+		if data.AddComments {
+			if file.src, err = os.ReadFile(file.filePath); err != nil {
+				log.Printf("failed to read %s for its comments: %s", file.filePath, err)
+			}
+		}
 
 		ast.Inspect(node, file.inspectFile)
 	}
@@ -107,6 +114,10 @@ type GoFile struct {
 	fileSet *token.FileSet
 	pkgConf *packages.Config
 
+	// content and comments of the file, for the extracted comments of the translations
+	src      []byte
+	comments []*ast.CommentGroup
+
 	importedPackages map[string]*packages.Package
 }
 
@@ -183,6 +194,37 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 		return
 	}
 
+	// configured keywords are extracted whatever the receiver
+	kw, isKeyword := g.data.Keywords[expr.Sel.Name]
+	if !isKeyword && !g.isGotextReceiver(expr) {
+		return
+	}
+
+	// convert args
+	args := make([]*ast.BasicLit, len(n.Args))
+	for idx, arg := range n.Args {
+		args[idx] = g.stringArg(arg)
+	}
+
+	// get position
+	path, _ := filepath.Rel(g.basePath, g.filePath)
+	position := g.data.Reference(path, g.fileSet.Position(n.Lparen))
+	comments := g.data.CommentsBefore(g.fileSet, g.src, g.comments, n.Pos())
+
+	if isKeyword {
+		g.parseGetter(GetterDef{kw.Id, kw.Plural, kw.Context, -1}, args, position, comments)
+		return
+	}
+
+	// handle getters
+	if def, ok := gotextGetter[expr.Sel.String()]; ok {
+		g.parseGetter(def, args, position, comments)
+		return
+	}
+}
+
+// isGotextReceiver reports whether the call is a package function of gotext or a method of one of its types
+func (g *GoFile) isGotextReceiver(expr *ast.SelectorExpr) bool {
 	switch e := expr.X.(type) {
 	// direct call
 	case *ast.Ident:
@@ -190,14 +232,14 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 		if e.Obj == nil {
 			pkg, ok := g.importedPackages[e.Name]
 			if !ok || pkg.PkgPath != "github.com/tanyinloo/gotext" {
-				return
+				return false
 			}
 
 		} else {
 			// validate type of object
 			t := g.getType(e)
 			if t == nil || !g.checkType(t.Type()) {
-				return
+				return false
 			}
 		}
 
@@ -206,64 +248,79 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 		// validate type of object
 		t := g.getType(e.Sel)
 		if t == nil || !g.checkType(t.Type()) {
-			return
+			return false
 		}
 
 	default:
-		return
+		return false
 	}
 
-	// convert args
-	args := make([]*ast.BasicLit, len(n.Args))
-	for idx, arg := range n.Args {
-		args[idx], _ = arg.(*ast.BasicLit)
-	}
+	return true
+}
 
-	// get position
-	path, _ := filepath.Rel(g.basePath, g.filePath)
-	position := fmt.Sprintf("%s:%d", path, g.fileSet.Position(n.Lparen).Line)
+// stringArg returns the argument as literal if it's a literal or a constant expression, or nil otherwise
+func (g *GoFile) stringArg(expr ast.Expr) *ast.BasicLit {
+	if lit, ok := expr.(*ast.BasicLit); ok {
+		return lit
+	}
 
-	// handle getters
-	if def, ok := gotextGetter[expr.Sel.String()]; ok {
-		g.parseGetter(def, args, position)
-		return
+	for _, pkg := range g.importedPackages {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		if tv, ok := pkg.TypesInfo.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return &ast.BasicLit{ValuePos: expr.Pos(), Kind: token.STRING, Value: tv.Value.ExactString()}
+		}
 	}
+	return nil
 }
 
-func (g *GoFile) parseGetter(def GetterDef, args []*ast.BasicLit, pos string) {
+func (g *GoFile) parseGetter(def GetterDef, args []*ast.BasicLit, pos string, comments []string) {
 	// check if enough arguments are given
-	if len(args) < def.maxArgIndex() {
+	if len(args) <= def.maxArgIndex() {
 		return
 	}
 
-	// get domain
+	// get domain, the default one if it isn't a string and DynamicDomainToDefault is set
 	var domain string
 	if def.Domain != -1 {
-		domain, _ = strconv.Unquote(args[def.Domain].Value)
+		if args[def.Domain] != nil && args[def.Domain].Kind == token.STRING {
+			domain, _ = strconv.Unquote(args[def.Domain].Value)
+		} else if g.data.DynamicDomainToDefault {
+			log.Printf("WARN: Domain not a string at %s, using the default domain", pos)
+		} else {
+			log.Printf("ERR: Unsupported call at %s (Domain not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Domain not a string")
+			return
+		}
 	}
 
 	// only handle function calls with strings as ID
 	if args[def.Id] == nil || args[def.Id].Kind != token.STRING {
 		log.Printf("ERR: Unsupported call at %s (ID not a string)", pos)
+		g.data.AddUnsupportedCall(pos, "ID not a string")
 		return
 	}
 
 	trans := parser.Translation{
-		MsgId:           args[def.Id].Value,
-		SourceLocations: []string{pos},
+		MsgId:             args[def.Id].Value,
+		SourceLocations:   []string{pos},
+		ExtractedComments: comments,
 	}
-	if def.Plural > 0 {
+	if def.Plural != -1 {
 		// plural ID must be a string
 		if args[def.Plural] == nil || args[def.Plural].Kind != token.STRING {
 			log.Printf("ERR: Unsupported call at %s (Plural not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Plural not a string")
 			return
 		}
 		trans.MsgIdPlural = args[def.Plural].Value
 	}
-	if def.Context > 0 {
+	if def.Context != -1 {
 		// Context must be a string
 		if args[def.Context] == nil || args[def.Context].Kind != token.STRING {
 			log.Printf("ERR: Unsupported call at %s (Context not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Context not a string")
 			return
 		}
 		trans.Context = args[def.Context].Value
@@ -0,0 +1,140 @@
+package dir
+
+import (
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tanyinloo/gotext/cli/xgotext/parser"
+)
+
+func TestParseGetterDomain(t *testing.T) {
+	str := func(s string) *ast.BasicLit {
+		return &ast.BasicLit{Kind: token.STRING, Value: `"` + s + `"`}
+	}
+
+	data := &parser.DomainMap{}
+	g := &GoFile{data: data}
+	g.parseGetter(gotextGetter["GetD"], []*ast.BasicLit{str("errors"), str("Not found")}, "main.go:3", nil)
+	g.parseGetter(gotextGetter["GetNDC"], []*ast.BasicLit{str("errors"), str("One error"), str("%d errors"), nil, str("form")}, "main.go:4", nil)
+	g.parseGetter(gotextGetter["GetD"], []*ast.BasicLit{nil, str("Dynamic")}, "main.go:5", nil)
+
+	if _, ok := data.Domains["errors"].Translations[`"Not found"`]; !ok {
+		t.Error("Expected 'Not found' in the errors domain")
+	}
+	if _, ok := data.Domains["errors"].ContextTranslations[`"form"`][`"One error"`]; !ok {
+		t.Error("Expected 'One error' in the errors domain")
+	}
+	if _, ok := data.Domains["default"]; ok {
+		t.Error("Expected no default domain")
+	}
+	if len(data.UnsupportedCalls) != 1 || data.UnsupportedCalls[0] != "main.go:5 (Domain not a string)" {
+		t.Errorf("Expected the dynamic domain call as unsupported but got %v", data.UnsupportedCalls)
+	}
+
+	// Dynamic domains go to the default one when asked
+	data = &parser.DomainMap{DynamicDomainToDefault: true}
+	g = &GoFile{data: data}
+	g.parseGetter(gotextGetter["GetD"], []*ast.BasicLit{nil, str("Dynamic")}, "main.go:5", nil)
+	if _, ok := data.Domains["default"].Translations[`"Dynamic"`]; !ok || len(data.UnsupportedCalls) != 0 {
+		t.Errorf("Expected 'Dynamic' in the default domain but got %v", data.Domains)
+	}
+}
+
+func TestParseDirKeywords(t *testing.T) {
+	defaultDomain := "default"
+	data := &parser.DomainMap{
+		Default:     defaultDomain,
+		Keywords:    make(map[string]parser.Keyword),
+		AddComments: true,
+	}
+	for _, spec := range []string{"T", "Tn:1,2", "Tpc:1c,2"} {
+		name, kw, err := parser.ParseKeyword(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.Keywords[name] = kw
+	}
+
+	currentPath, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirPath := filepath.Join(filepath.Dir(filepath.Dir(currentPath)), "fixtures", "injected")
+	if err = ParseDir(dirPath, dirPath, data); err != nil {
+		t.Fatal(err)
+	}
+
+	translations := data.Domains[defaultDomain].Translations
+	for _, tr := range []string{"\"injected call\"", "\"injected singular\""} {
+		if _, ok := translations[tr]; !ok {
+			t.Errorf("translation '%v' not in result", tr)
+		}
+	}
+	if tr, ok := translations["\"injected call\""]; ok {
+		expected := "#. Greeting of the home page,\n#. keep it short\n#: injected.go:17\nmsgid \"injected call\"\nmsgstr \"\""
+		if dump := tr.Dump(); dump != expected {
+			t.Errorf("expected '%v' but got '%v'", expected, dump)
+		}
+	}
+	if tr, ok := translations["\"injected singular\""]; ok && len(tr.ExtractedComments) != 0 {
+		t.Errorf("expected no extracted comments but got %v", tr.ExtractedComments)
+	}
+	if tr, ok := translations["\"injected singular\""]; ok && tr.MsgIdPlural != "\"injected plural\"" {
+		t.Errorf("expected plural '\"injected plural\"' but got '%v'", tr.MsgIdPlural)
+	}
+	if _, ok := data.Domains[defaultDomain].ContextTranslations["\"injected-ctx\""]["\"injected context\""]; !ok {
+		t.Error("translation '\"injected context\"' not in result")
+	}
+}
+
+func TestParseDirConstantArgs(t *testing.T) {
+	defaultDomain := "default"
+	data := &parser.DomainMap{
+		Default:  defaultDomain,
+		Keywords: make(map[string]parser.Keyword),
+	}
+	for _, spec := range []string{"T", "Tn:1,2"} {
+		name, kw, err := parser.ParseKeyword(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.Keywords[name] = kw
+	}
+
+	currentPath, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirPath := filepath.Join(filepath.Dir(filepath.Dir(currentPath)), "fixtures", "dynamic")
+	if err = ParseDir(dirPath, dirPath, data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Constants and constant expressions are extracted with their value
+	translations := data.Domains[defaultDomain].Translations
+	for _, tr := range []string{"\"constant greeting\"", "\"concatenated string\"", "\"constant singular\""} {
+		if _, ok := translations[tr]; !ok {
+			t.Errorf("translation '%v' not in result", tr)
+		}
+	}
+	if tr, ok := translations["\"constant singular\""]; ok && tr.MsgIdPlural != "\"concatenated plural\"" {
+		t.Errorf("expected plural '\"concatenated plural\"' but got '%v'", tr.MsgIdPlural)
+	}
+	if len(translations) != 3 {
+		t.Errorf("expected 3 translations but got %d", len(translations))
+	}
+
+	// Variables, also concatenated to a literal, are reported as unsupported
+	expected := []string{"dynamic.go:22 (ID not a string)", "dynamic.go:23 (ID not a string)"}
+	if len(data.UnsupportedCalls) != len(expected) {
+		t.Fatalf("expected unsupported calls %v but got %v", expected, data.UnsupportedCalls)
+	}
+	for i, call := range expected {
+		if data.UnsupportedCalls[i] != call {
+			t.Errorf("expected unsupported call '%v' but got '%v'", call, data.UnsupportedCalls[i])
+		}
+	}
+}
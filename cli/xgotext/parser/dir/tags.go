@@ -0,0 +1,85 @@
+package dir
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/tanyinloo/gotext/cli/xgotext/parser"
+)
+
+// register struct tag parser
+func init() {
+	AddParser(tagParser)
+}
+
+// tagParser extracts the values of the struct field tags listed in data.TagKeys, i.e. the `label:"Email Address"`
+// of a form field, as msgids of the default domain. It does nothing when no tag key is set.
+func tagParser(dirPath, basePath string, data *parser.DomainMap) error {
+	if len(data.TagKeys) == 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dirPath, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	fileSet := token.NewFileSet()
+	for _, filePath := range files {
+		if strings.HasSuffix(filePath, "_test.go") {
+			continue
+		}
+
+		node, err := goparser.ParseFile(fileSet, filePath, nil, 0)
+		if err != nil {
+			// not a valid go file, reported by the go parser
+			continue
+		}
+
+		path, _ := filepath.Rel(basePath, filePath)
+		ast.Inspect(node, func(n ast.Node) bool {
+			field, ok := n.(*ast.Field)
+			if !ok || field.Tag == nil {
+				return true
+			}
+			parseFieldTag(field, data.Reference(path, fileSet.Position(field.Tag.Pos())), data)
+			return true
+		})
+	}
+	return nil
+}
+
+// parseFieldTag adds the values of the tags listed in data.TagKeys of a struct field
+func parseFieldTag(field *ast.Field, pos string, data *parser.DomainMap) {
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return
+	}
+
+	names := make([]string, 0, len(field.Names))
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+
+	for _, key := range data.TagKeys {
+		value, ok := reflect.StructTag(tag).Lookup(key)
+		if !ok || value == "" || value == "-" {
+			continue
+		}
+
+		trans := parser.Translation{
+			MsgId:           value,
+			SourceLocations: []string{pos},
+		}
+		if len(names) > 0 {
+			trans.ExtractedComments = []string{fmt.Sprintf("%s tag of %s", key, strings.Join(names, ", "))}
+		}
+		data.AddTranslation("", &trans)
+	}
+}
@@ -0,0 +1,86 @@
+package dir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tanyinloo/gotext/cli/xgotext/parser"
+)
+
+func TestTagParser(t *testing.T) {
+	dirPath := t.TempDir()
+	src := `package form
+
+type Signup struct {
+	Email    string ` + "`validate:\"required\" label:\"Email Address\"`" + `
+	Password string ` + "`label:\"Password\" hint:\"At least 8 characters\"`" + `
+	Internal string ` + "`label:\"-\"`" + `
+	Plain    string
+	Nested   struct {
+		City string ` + "`label:\"City\"`" + `
+	}
+}
+`
+	if err := os.WriteFile(filepath.Join(dirPath, "form.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dirPath, "form_test.go"), []byte("package form\n\ntype T struct {\n\tA string `label:\"Test only\"`\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &parser.DomainMap{}
+	if err := tagParser(dirPath, dirPath, data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Domains) != 0 {
+		t.Errorf("Expected no translation without tag keys but got %d domains", len(data.Domains))
+	}
+
+	data.TagKeys = []string{"label", "hint"}
+	if err := tagParser(dirPath, dirPath, data); err != nil {
+		t.Fatal(err)
+	}
+
+	translations := data.Domains["default"].Translations
+	expected := map[string]string{
+		"Email Address":         "form.go:4",
+		"Password":              "form.go:5",
+		"At least 8 characters": "form.go:5",
+		"City":                  "form.go:9",
+	}
+	if len(translations) != len(expected) {
+		t.Errorf("Expected %d translations but got %d", len(expected), len(translations))
+	}
+	for id, pos := range expected {
+		trans, ok := translations[id]
+		if !ok {
+			t.Errorf("Expected '%s' to be extracted", id)
+			continue
+		}
+		if len(trans.SourceLocations) != 1 || trans.SourceLocations[0] != pos {
+			t.Errorf("Expected '%s' but got '%v'", pos, trans.SourceLocations)
+		}
+	}
+	if trans := translations["Email Address"]; trans != nil && (len(trans.ExtractedComments) != 1 || trans.ExtractedComments[0] != "label tag of Email") {
+		t.Errorf("Expected 'label tag of Email' but got '%v'", trans.ExtractedComments)
+	}
+}
+
+func TestTagParserColumns(t *testing.T) {
+	dirPath := t.TempDir()
+	src := "package form\n\ntype Signup struct {\n\tEmail string `label:\"Email Address\"`\n}\n"
+	if err := os.WriteFile(filepath.Join(dirPath, "form.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data := &parser.DomainMap{TagKeys: []string{"label"}, Columns: true}
+	if err := tagParser(dirPath, dirPath, data); err != nil {
+		t.Fatal(err)
+	}
+
+	trans := data.Domains["default"].Translations["Email Address"]
+	if trans == nil || len(trans.SourceLocations) != 1 || trans.SourceLocations[0] != "form.go:4:15" {
+		t.Errorf("Expected 'form.go:4:15' but got '%v'", trans)
+	}
+}
@@ -2,8 +2,11 @@ package parser
 
 import (
 	"fmt"
+	"go/ast"
+	"go/token"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 )
@@ -14,6 +17,13 @@ type Translation struct {
 	MsgIdPlural     string
 	Context         string
 	SourceLocations []string
+
+	// Comments holds the translator comments (#)
+	Comments []string
+	// ExtractedComments holds the comments extracted from the source (#.)
+	ExtractedComments []string
+	// Flags holds the entry flags (#,), like "go-format"
+	Flags []string
 }
 
 // AddLocations to translation
@@ -25,13 +35,47 @@ func (t *Translation) AddLocations(locations []string) {
 	}
 }
 
+// AddComments merges the comments and flags of other into the translation, skipping duplicates
+func (t *Translation) AddComments(other *Translation) {
+	t.Comments = appendUnique(t.Comments, other.Comments...)
+	t.ExtractedComments = appendUnique(t.ExtractedComments, other.ExtractedComments...)
+	t.Flags = appendUnique(t.Flags, other.Flags...)
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if l == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
 // Dump translation as string
 func (t *Translation) Dump() string {
-	data := make([]string, 0, len(t.SourceLocations)+5)
+	data := make([]string, 0, len(t.Comments)+len(t.ExtractedComments)+len(t.SourceLocations)+6)
 
+	// comment groups are written in the same order as GNU xgettext:
+	// translator comments, extracted comments, references and flags
+	for _, comment := range t.Comments {
+		data = append(data, "# "+comment)
+	}
+	for _, comment := range t.ExtractedComments {
+		data = append(data, "#. "+comment)
+	}
 	for _, location := range t.SourceLocations {
 		data = append(data, "#: "+location)
 	}
+	if len(t.Flags) > 0 {
+		data = append(data, "#, "+strings.Join(t.Flags, ", "))
+	}
 
 	if t.Context != "" {
 		data = append(data, "msgctxt "+t.Context)
@@ -86,6 +130,7 @@ func (d *Domain) AddTranslation(translation *Translation) {
 	if translation.Context == "" {
 		if t, ok := d.Translations[translation.MsgId]; ok {
 			t.AddLocations(translation.SourceLocations)
+			t.AddComments(translation)
 		} else {
 			d.Translations[translation.MsgId] = translation
 		}
@@ -96,6 +141,7 @@ func (d *Domain) AddTranslation(translation *Translation) {
 
 		if t, ok := d.ContextTranslations[translation.Context][translation.MsgId]; ok {
 			t.AddLocations(translation.SourceLocations)
+			t.AddComments(translation)
 		} else {
 			d.ContextTranslations[translation.Context][translation.MsgId] = translation
 		}
@@ -152,6 +198,116 @@ msgstr ""
 type DomainMap struct {
 	Domains map[string]*Domain
 	Default string
+
+	// NoGoFormat disables marking translations containing fmt verbs with the go-format flag
+	NoGoFormat bool
+
+	// Keywords are the method names, with the positions of their arguments, extracted whatever the type of
+	// their receiver, i.e. to extract calls on an injected translator interface
+	Keywords map[string]Keyword
+
+	// TagKeys are the struct field tag keys whose values are extracted, i.e. "label" for `label:"Email Address"`
+	TagKeys []string
+
+	// DynamicDomainToDefault extracts the strings of calls whose domain argument isn't a constant string
+	// into the default domain, instead of skipping them as unsupported calls
+	DynamicDomainToDefault bool
+
+	// Columns adds the column to the source references, as "file:line:col"
+	Columns bool
+
+	// AddComments writes the comment group directly above a call as extracted comments (#.) of its strings,
+	// like xgettext --add-comments. With a CommentTag, only the groups starting with it are
+	AddComments bool
+	CommentTag  string
+
+	// UnsupportedCalls lists the calls which couldn't be extracted because an argument isn't a constant string,
+	// as "file:line (reason)"
+	UnsupportedCalls []string
+}
+
+// Reference returns the source reference of pos, in the file at path relative to the extracted root,
+// as "file:line", or "file:line:col" when Columns is set
+func (m *DomainMap) Reference(path string, pos token.Position) string {
+	if m.Columns && pos.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", path, pos.Line, pos.Column)
+	}
+	return fmt.Sprintf("%s:%d", path, pos.Line)
+}
+
+// AddUnsupportedCall records a call at position pos which couldn't be extracted
+func (m *DomainMap) AddUnsupportedCall(pos, reason string) {
+	m.UnsupportedCalls = append(m.UnsupportedCalls, pos+" ("+reason+")")
+}
+
+// CommentsBefore returns the lines of the comment group on the lines directly above the call at pos, i.e.
+// "// TRANSLATORS: greeting of the home page", to be written as extracted comments (#.) when AddComments is set.
+// A comment trailing the code of its line isn't part of the group, and with a CommentTag the group must start with it.
+// src is the content of the file, and comments its comment groups sorted by position, like the Comments of an ast.File.
+func (m *DomainMap) CommentsBefore(fileSet *token.FileSet, src []byte, comments []*ast.CommentGroup, pos token.Pos) []string {
+	if !m.AddComments {
+		return nil
+	}
+	line := fileSet.Position(pos).Line
+
+	var group *ast.CommentGroup
+	for _, c := range comments {
+		if c.End() > pos {
+			break
+		}
+		if fileSet.Position(c.End()).Line == line-1 {
+			group = c
+		}
+	}
+	if group == nil {
+		return nil
+	}
+
+	// Only the first comment of a group can follow code, the next ones would be in another group
+	list := group.List
+	if !startsLine(src, fileSet.Position(list[0].Pos()).Offset) {
+		list = list[1:]
+	}
+
+	var lines []string
+	for _, l := range strings.Split((&ast.CommentGroup{List: list}).Text(), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], m.CommentTag) {
+		return nil
+	}
+	return lines
+}
+
+// startsLine reports whether only blanks precede offset on its line of src
+func startsLine(src []byte, offset int) bool {
+	if offset > len(src) {
+		return false
+	}
+	for i := offset - 1; i >= 0 && src[i] != '\n'; i-- {
+		if src[i] != ' ' && src[i] != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// GoFormatFlag marks translations used as fmt format strings
+const GoFormatFlag = "go-format"
+
+// formatVerbRe matches fmt verbs, with their optional flags, argument indexes, width and precision, and escaped percents
+var formatVerbRe = regexp.MustCompile(`%%|%(\[\d+\])?[+\-# 0]*(\[\d+\])?(\d+|\*)?(\.(\[\d+\])?(\d+|\*)?)?[a-zA-Z]`)
+
+// IsGoFormat reports whether str contains at least one fmt verb, like "%d" or "%[1]s". Escaped percents ("%%") don't count.
+func IsGoFormat(str string) bool {
+	for _, match := range formatVerbRe.FindAllString(str, -1) {
+		if match != "%%" {
+			return true
+		}
+	}
+	return false
 }
 
 // AddTranslation to domain map
@@ -170,6 +326,10 @@ func (m *DomainMap) AddTranslation(domain string, translation *Translation) {
 		domain = m.Default
 	}
 
+	if !m.NoGoFormat && (IsGoFormat(translation.MsgId) || IsGoFormat(translation.MsgIdPlural)) {
+		translation.Flags = appendUnique(translation.Flags, GoFormatFlag)
+	}
+
 	if _, ok := m.Domains[domain]; !ok {
 		m.Domains[domain] = new(Domain)
 	}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommentsBefore(t *testing.T) {
+	src := `package main
+
+func main() {
+	// Greeting of the home page
+	T("Hello")
+
+	/* Inline */ T("Inline")
+
+	// Too far away
+
+	T("Far")
+	T("None")
+	x := 1 // Trailing
+	T("Trailing")
+	y := 2 // Trailing too
+	// Own line
+	T("Mixed")
+	// TRANSLATORS: tagged,
+	// on two lines
+	T("Tagged")
+}
+`
+	fileSet := token.NewFileSet()
+	file, err := goparser.ParseFile(fileSet, "main.go", src, goparser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		data     DomainMap
+		expected map[string][]string
+	}{
+		{DomainMap{}, map[string][]string{}},
+		{DomainMap{AddComments: true}, map[string][]string{
+			`"Hello"`:  {"Greeting of the home page"},
+			`"Mixed"`:  {"Own line"},
+			`"Tagged"`: {"TRANSLATORS: tagged,", "on two lines"},
+		}},
+		{DomainMap{AddComments: true, CommentTag: "TRANSLATORS:"}, map[string][]string{
+			`"Tagged"`: {"TRANSLATORS: tagged,", "on two lines"},
+		}},
+	} {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			id := call.Args[0].(*ast.BasicLit).Value
+			comments := tc.data.CommentsBefore(fileSet, []byte(src), file.Comments, call.Pos())
+			if !reflect.DeepEqual(comments, tc.expected[id]) {
+				t.Errorf("expected %v for %s with tag %q but got %v", tc.expected[id], id, tc.data.CommentTag, comments)
+			}
+			return true
+		})
+	}
+}
+
+func TestTranslationDump(t *testing.T) {
+	trans := Translation{
+		MsgId:             `"%d files"`,
+		Context:           `"menu"`,
+		SourceLocations:   []string{"main.go:5"},
+		Comments:          []string{"translator"},
+		ExtractedComments: []string{"Greeting of the home page"},
+		Flags:             []string{GoFormatFlag},
+	}
+
+	expected := strings.Join([]string{
+		"# translator",
+		"#. Greeting of the home page",
+		"#: main.go:5",
+		"#, go-format",
+		`msgctxt "menu"`,
+		`msgid "%d files"`,
+		`msgstr ""`,
+	}, "\n")
+	if dump := trans.Dump(); dump != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, dump)
+	}
+}
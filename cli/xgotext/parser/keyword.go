@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keyword describes a translation method extracted whatever its receiver, i.e. the Tn of translator.Tn(...).
+// Indexes are 0-based positions of the call arguments, -1 when the argument isn't used.
+type Keyword struct {
+	Id      int
+	Plural  int
+	Context int
+}
+
+// ParseKeyword parses a keyword specification in the xgettext --keyword syntax: the method name, optionally followed
+// by a colon and the comma separated 1-based positions of the msgid, the msgid_plural and, with a "c" suffix, the context.
+// The msgid is the first argument when no position is given.
+// i.e. "T", "Tn:1,2" or "Tpc:1c,2".
+func ParseKeyword(spec string) (string, Keyword, error) {
+	kw := Keyword{Id: 0, Plural: -1, Context: -1}
+
+	name, args := spec, ""
+	if idx := strings.Index(spec, ":"); idx != -1 {
+		name, args = spec[:idx], spec[idx+1:]
+	}
+	if name == "" {
+		return "", kw, fmt.Errorf("invalid keyword %q: missing name", spec)
+	}
+	if args == "" {
+		return name, kw, nil
+	}
+
+	var positions []int
+	for _, arg := range strings.Split(args, ",") {
+		isContext := strings.HasSuffix(arg, "c")
+		pos, err := strconv.Atoi(strings.TrimSuffix(arg, "c"))
+		if err != nil || pos < 1 {
+			return "", kw, fmt.Errorf("invalid keyword %q: bad argument position %q", spec, arg)
+		}
+
+		if isContext {
+			if kw.Context != -1 {
+				return "", kw, fmt.Errorf("invalid keyword %q: several contexts", spec)
+			}
+			kw.Context = pos - 1
+			continue
+		}
+		positions = append(positions, pos-1)
+	}
+
+	switch len(positions) {
+	case 0:
+		return "", kw, fmt.Errorf("invalid keyword %q: missing msgid position", spec)
+	case 1:
+		kw.Id = positions[0]
+	case 2:
+		kw.Id, kw.Plural = positions[0], positions[1]
+	default:
+		return "", kw, fmt.Errorf("invalid keyword %q: too many arguments", spec)
+	}
+
+	return name, kw, nil
+}
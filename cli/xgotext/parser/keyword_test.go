@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseKeyword(t *testing.T) {
+	for _, test := range []struct {
+		spec  string
+		name  string
+		kw    Keyword
+		isErr bool
+	}{
+		{spec: "T", name: "T", kw: Keyword{Id: 0, Plural: -1, Context: -1}},
+		{spec: "T:2", name: "T", kw: Keyword{Id: 1, Plural: -1, Context: -1}},
+		{spec: "Tn:1,2", name: "Tn", kw: Keyword{Id: 0, Plural: 1, Context: -1}},
+		{spec: "Tpc:1c,2", name: "Tpc", kw: Keyword{Id: 1, Plural: -1, Context: 0}},
+		{spec: "Tnpc:3,4,2c", name: "Tnpc", kw: Keyword{Id: 2, Plural: 3, Context: 1}},
+		{spec: "T:", name: "T", kw: Keyword{Id: 0, Plural: -1, Context: -1}},
+		{spec: "", isErr: true},
+		{spec: ":1", isErr: true},
+		{spec: "T:0", isErr: true},
+		{spec: "T:-1", isErr: true},
+		{spec: "T:x", isErr: true},
+		{spec: "T:1,", isErr: true},
+		{spec: "T:1c", isErr: true},
+		{spec: "T:1c,2c,3", isErr: true},
+		{spec: "T:1,2,3", isErr: true},
+	} {
+		name, kw, err := ParseKeyword(test.spec)
+		if test.isErr {
+			if err == nil {
+				t.Errorf("expected an error for %q but got %q %+v", test.spec, name, kw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.spec, err)
+			continue
+		}
+		if name != test.name || kw != test.kw {
+			t.Errorf("expected %q %+v for %q but got %q %+v", test.name, test.kw, test.spec, name, kw)
+		}
+	}
+}
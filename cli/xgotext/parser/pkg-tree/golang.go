@@ -3,11 +3,13 @@ package pkg_tree
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -67,7 +69,13 @@ func pkgParser(dirPath, basePath string, data *parser.DomainMap, verbose bool) e
 		return err
 	}
 
-	for _, pkg := range filterPkgs(mainPkg) {
+	pkgs := filterPkgs(mainPkg)
+	if len(data.Keywords) > 0 {
+		// keyword calls don't need gotext to be imported, i.e. with an injected translator interface
+		pkgs = appendLocalPkgs(pkgs, getPkgPath(mainPkg))
+	}
+
+	for _, pkg := range pkgs {
 		if verbose {
 			fmt.Println(pkg.ID)
 		}
@@ -77,11 +85,18 @@ func pkgParser(dirPath, basePath string, data *parser.DomainMap, verbose bool) e
 				basePath: basePath,
 				data:     data,
 				fileSet:  pkg.Fset,
+				comments: node.Comments,
 
 				importedPackages: map[string]*packages.Package{
 					pkg.Name: pkg,
 				},
 			}
+			if data.AddComments {
+				var err error
+				if file.src, err = os.ReadFile(file.filePath); err != nil {
+					log.Printf("failed to read %s for its comments: %s", file.filePath, err)
+				}
+			}
 
 			ast.Inspect(node, file.inspectFile)
 		}
@@ -141,6 +156,32 @@ func filterPkgsRec(pkg *packages.Package) []*packages.Package {
 	return result
 }
 
+// appendLocalPkgs appends to pkgs the loaded packages whose files are in the root directory or its subdirectories
+func appendLocalPkgs(pkgs []*packages.Package, root string) []*packages.Package {
+	seen := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		seen[pkg.ID] = true
+	}
+
+	ids := make([]string, 0, len(pkgCache))
+	for id := range pkgCache {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		pkg := pkgCache[id]
+		if seen[id] || len(pkg.GoFiles) == 0 {
+			continue
+		}
+		if dir := getPkgPath(pkg); dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			seen[id] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
 // GoFile handles the parsing of one go file
 type GoFile struct {
 	filePath string
@@ -150,6 +191,10 @@ type GoFile struct {
 	fileSet *token.FileSet
 	pkgConf *packages.Config
 
+	// content and comments of the file, for the extracted comments of the translations
+	src      []byte
+	comments []*ast.CommentGroup
+
 	importedPackages map[string]*packages.Package
 }
 
@@ -226,6 +271,37 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 		return
 	}
 
+	// configured keywords are extracted whatever the receiver
+	kw, isKeyword := g.data.Keywords[expr.Sel.Name]
+	if !isKeyword && !g.isGotextReceiver(expr) {
+		return
+	}
+
+	// convert args
+	args := make([]*ast.BasicLit, len(n.Args))
+	for idx, arg := range n.Args {
+		args[idx] = g.stringArg(arg)
+	}
+
+	// get position
+	path, _ := filepath.Rel(g.basePath, g.filePath)
+	position := g.data.Reference(path, g.fileSet.Position(n.Lparen))
+	comments := g.data.CommentsBefore(g.fileSet, g.src, g.comments, n.Pos())
+
+	if isKeyword {
+		g.parseGetter(GetterDef{kw.Id, kw.Plural, kw.Context, -1}, args, position, comments)
+		return
+	}
+
+	// handle getters
+	if def, ok := gotextGetter[expr.Sel.String()]; ok {
+		g.parseGetter(def, args, position, comments)
+		return
+	}
+}
+
+// isGotextReceiver reports whether the call is a package function of gotext or a method of one of its types
+func (g *GoFile) isGotextReceiver(expr *ast.SelectorExpr) bool {
 	switch e := expr.X.(type) {
 	// direct call
 	case *ast.Ident:
@@ -234,7 +310,7 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 			// validate type of object
 			t := g.getType(e)
 			if t == nil || !g.checkType(t.Type()) {
-				return
+				return false
 			}
 		}
 
@@ -243,64 +319,79 @@ func (g *GoFile) inspectCallExpr(n *ast.CallExpr) {
 		// validate type of object
 		t := g.getType(e.Sel)
 		if t == nil || !g.checkType(t.Type()) {
-			return
+			return false
 		}
 
 	default:
-		return
+		return false
 	}
 
-	// convert args
-	args := make([]*ast.BasicLit, len(n.Args))
-	for idx, arg := range n.Args {
-		args[idx], _ = arg.(*ast.BasicLit)
-	}
+	return true
+}
 
-	// get position
-	path, _ := filepath.Rel(g.basePath, g.filePath)
-	position := fmt.Sprintf("%s:%d", path, g.fileSet.Position(n.Lparen).Line)
+// stringArg returns the argument as literal if it's a literal or a constant expression, or nil otherwise
+func (g *GoFile) stringArg(expr ast.Expr) *ast.BasicLit {
+	if lit, ok := expr.(*ast.BasicLit); ok {
+		return lit
+	}
 
-	// handle getters
-	if def, ok := gotextGetter[expr.Sel.String()]; ok {
-		g.parseGetter(def, args, position)
-		return
+	for _, pkg := range g.importedPackages {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		if tv, ok := pkg.TypesInfo.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+			return &ast.BasicLit{ValuePos: expr.Pos(), Kind: token.STRING, Value: tv.Value.ExactString()}
+		}
 	}
+	return nil
 }
 
-func (g *GoFile) parseGetter(def GetterDef, args []*ast.BasicLit, pos string) {
+func (g *GoFile) parseGetter(def GetterDef, args []*ast.BasicLit, pos string, comments []string) {
 	// check if enough arguments are given
-	if len(args) < def.maxArgIndex() {
+	if len(args) <= def.maxArgIndex() {
 		return
 	}
 
-	// get domain
+	// get domain, the default one if it isn't a string and DynamicDomainToDefault is set
 	var domain string
 	if def.Domain != -1 {
-		domain, _ = strconv.Unquote(args[def.Domain].Value)
+		if args[def.Domain] != nil && args[def.Domain].Kind == token.STRING {
+			domain, _ = strconv.Unquote(args[def.Domain].Value)
+		} else if g.data.DynamicDomainToDefault {
+			log.Printf("WARN: Domain not a string at %s, using the default domain", pos)
+		} else {
+			log.Printf("ERR: Unsupported call at %s (Domain not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Domain not a string")
+			return
+		}
 	}
 
 	// only handle function calls with strings as ID
 	if args[def.Id] == nil || args[def.Id].Kind != token.STRING {
 		log.Printf("ERR: Unsupported call at %s (ID not a string)", pos)
+		g.data.AddUnsupportedCall(pos, "ID not a string")
 		return
 	}
 
 	trans := parser.Translation{
-		MsgId:           args[def.Id].Value,
-		SourceLocations: []string{pos},
+		MsgId:             args[def.Id].Value,
+		SourceLocations:   []string{pos},
+		ExtractedComments: comments,
 	}
-	if def.Plural > 0 {
+	if def.Plural != -1 {
 		// plural ID must be a string
 		if args[def.Plural] == nil || args[def.Plural].Kind != token.STRING {
 			log.Printf("ERR: Unsupported call at %s (Plural not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Plural not a string")
 			return
 		}
 		trans.MsgIdPlural = args[def.Plural].Value
 	}
-	if def.Context > 0 {
+	if def.Context != -1 {
 		// Context must be a string
 		if args[def.Context] == nil || args[def.Context].Kind != token.STRING {
 			log.Printf("ERR: Unsupported call at %s (Context not a string)", pos)
+			g.data.AddUnsupportedCall(pos, "Context not a string")
 			return
 		}
 		trans.Context = args[def.Context].Value
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Column names of the CSV format used by MarshalCSV and UnmarshalCSV.
+// Plural forms after the first one use "msgstr[n]" columns.
+const (
+	csvContext  = "context"
+	csvMsgid    = "msgid"
+	csvPlural   = "msgid_plural"
+	csvMsgstr   = "msgstr"
+	csvComments = "comments"
+)
+
+// MarshalCSV returns the domain as a CSV document (RFC 4180), to edit it in a spreadsheet.
+// The columns are context, msgid, msgid_plural, msgstr, one msgstr[n] column for every other plural form
+// declared by the domain, and comments, holding the translator comments separated by newlines.
+// Rows are sorted by context and msgid. The header entry and obsolete entries aren't exported.
+// Line breaks, including those inside cells, are written as CRLF as RFC 4180 requires, and read back as "\n".
+func (do *Domain) MarshalCSV() ([]byte, error) {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	do.pluralMutex.RLock()
+	forms := do.nplurals
+	do.pluralMutex.RUnlock()
+
+	// sort contexts and ids for a stable output
+	contexts := make([]string, 0, len(do.contexts)+1)
+	contexts = append(contexts, "")
+	for ctx := range do.contexts {
+		contexts = append(contexts, ctx)
+	}
+	sort.Strings(contexts[1:])
+
+	var rows []contextTranslation
+	for _, ctx := range contexts {
+		entries := do.translations
+		if ctx != "" {
+			entries = do.contexts[ctx]
+		}
+
+		ids := make([]string, 0, len(entries))
+		for id := range entries {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			trans := entries[id]
+			trans.load()
+			if trans.PluralID != "" {
+				if forms < 2 {
+					forms = 2
+				}
+				for n := range trans.Trs {
+					if n+1 > forms {
+						forms = n + 1
+					}
+				}
+			}
+			rows = append(rows, contextTranslation{ctx, trans})
+		}
+	}
+	if forms < 1 {
+		forms = 1
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+
+	header := []string{csvContext, csvMsgid, csvPlural, csvMsgstr}
+	for n := 1; n < forms; n++ {
+		header = append(header, csvMsgstr+"["+strconv.Itoa(n)+"]")
+	}
+	header = append(header, csvComments)
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		trans := row.trans
+		record := []string{row.context, trans.ID, trans.PluralID}
+		for n := 0; n < forms; n++ {
+			if n > 0 && trans.PluralID == "" {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, trans.Trs[n])
+		}
+		record = append(record, strings.Join(trans.Comments, "\n"))
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCSV loads the translations of a CSV document into the domain, as written by MarshalCSV.
+// Columns are found by the names of the first row, so they can be reordered, and only msgid and msgstr are required.
+// Existing entries get their Translation, and comments when the column is present, updated
+// and unknown entries are added. Rows with an empty msgid are skipped.
+// It returns an error matching ErrParse if the document isn't valid CSV or misses a required column.
+func (do *Domain) UnmarshalCSV(data []byte) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return newParseError("csv: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{csvMsgid, csvMsgstr} {
+		if _, ok := columns[name]; !ok {
+			return newParseError("csv: missing %q column", name)
+		}
+	}
+
+	// Every msgstr column, by plural form
+	forms := map[int]int{0: columns[csvMsgstr]}
+	for name, i := range columns {
+		if strings.HasPrefix(name, csvMsgstr+"[") && strings.HasSuffix(name, "]") {
+			if n, err := strconv.Atoi(name[len(csvMsgstr)+1 : len(name)-1]); err == nil && n > 0 {
+				forms[n] = i
+			}
+		}
+	}
+
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return newParseError("csv: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	for _, record := range records {
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		id := cell(csvMsgid)
+		if id == "" {
+			continue
+		}
+
+		plural := cell(csvPlural)
+		trans := do.xliffEntry(cell(csvContext), id, plural)
+		for n, i := range forms {
+			if n > 0 && plural == "" {
+				continue
+			}
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			trans.SetN(n, value)
+		}
+
+		if _, ok := columns[csvComments]; ok {
+			trans.Comments = nil
+			if comments := cell(csvComments); comments != "" {
+				trans.Comments = strings.Split(comments, "\n")
+			}
+		}
+	}
+
+	return nil
+}
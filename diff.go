@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EntryDiff describes an entry of a DomainDiff
+type EntryDiff struct {
+	Context  string
+	ID       string
+	PluralID string
+
+	// Translations by plural form in the old and new domains, nil for added and removed entries respectively
+	Old map[int]string
+	New map[int]string
+}
+
+// DomainDiff lists the changes between two versions of a domain, as returned by DiffDomains.
+// Entries are sorted by context and msgid.
+type DomainDiff struct {
+	// Added holds the entries missing from the old domain
+	Added []EntryDiff
+
+	// Removed holds the entries missing from the new domain
+	Removed []EntryDiff
+
+	// Changed holds the entries whose msgid_plural or translation, of any plural form, changed
+	Changed []EntryDiff
+
+	// BecameFuzzy holds the entries flagged as fuzzy in the new domain but not in the old one.
+	// They're also in Changed if their translation changed.
+	BecameFuzzy []EntryDiff
+}
+
+// Empty reports whether the domains hold the same entries and translations
+func (d DomainDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.BecameFuzzy) == 0
+}
+
+// String implements the fmt.Stringer interface.
+// It returns a summary of the changes, like "3 new strings, 5 translations changed, 2 became fuzzy, 0 removed".
+func (d DomainDiff) String() string {
+	return fmt.Sprintf("%d new strings, %d translations changed, %d became fuzzy, %d removed",
+		len(d.Added), len(d.Changed), len(d.BecameFuzzy), len(d.Removed))
+}
+
+// diffEntry is a copy of an entry taken while its domain is locked
+type diffEntry struct {
+	EntryDiff
+	trs   map[int]string
+	fuzzy bool
+}
+
+// diffEntries copies the entries of do by context and msgid, without the header entry and entries with an empty msgid
+func (do *Domain) diffEntries() map[string]map[string]diffEntry {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	entries := make(map[string]map[string]diffEntry)
+	add := func(ctx string, trans *Translation) {
+		if trans.ID == "" {
+			return
+		}
+		if _, ok := entries[ctx]; !ok {
+			entries[ctx] = make(map[string]diffEntry)
+		}
+
+		trans.load()
+		trs := make(map[int]string, len(trans.Trs))
+		for n, tr := range trans.Trs {
+			trs[n] = tr
+		}
+		entries[ctx][trans.ID] = diffEntry{
+			EntryDiff: EntryDiff{Context: ctx, ID: trans.ID, PluralID: trans.PluralID},
+			trs:       trs,
+			fuzzy:     trans.IsFuzzy(),
+		}
+	}
+
+	for _, trans := range do.translations {
+		add("", trans)
+	}
+	for ctx, translations := range do.contexts {
+		for _, trans := range translations {
+			add(ctx, trans)
+		}
+	}
+
+	return entries
+}
+
+// sameTranslations reports whether a and b hold the same translations, missing plural forms counting as empty
+func sameTranslations(a, b map[int]string) bool {
+	for n, tr := range a {
+		if b[n] != tr {
+			return false
+		}
+	}
+	for n, tr := range b {
+		if a[n] != tr {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffDomains compares two versions of a domain, i.e. a catalog before and after a translation update, and returns
+// the added, removed and changed entries, with their old and new translations. Entries are matched by context and msgid,
+// and the translations of every plural form are compared. The header and obsolete entries are ignored.
+func DiffDomains(old, updated *Domain) DomainDiff {
+	oldEntries := old.diffEntries()
+	newEntries := updated.diffEntries()
+
+	var diff DomainDiff
+	for ctx, entries := range newEntries {
+		for id, entry := range entries {
+			prev, ok := oldEntries[ctx][id]
+			if !ok {
+				entry.New = entry.trs
+				diff.Added = append(diff.Added, entry.EntryDiff)
+				continue
+			}
+
+			entry.Old, entry.New = prev.trs, entry.trs
+			if entry.PluralID != prev.PluralID || !sameTranslations(prev.trs, entry.trs) {
+				diff.Changed = append(diff.Changed, entry.EntryDiff)
+			}
+			if entry.fuzzy && !prev.fuzzy {
+				diff.BecameFuzzy = append(diff.BecameFuzzy, entry.EntryDiff)
+			}
+		}
+	}
+	for ctx, entries := range oldEntries {
+		for id, entry := range entries {
+			if _, ok := newEntries[ctx][id]; !ok {
+				entry.Old = entry.trs
+				diff.Removed = append(diff.Removed, entry.EntryDiff)
+			}
+		}
+	}
+
+	for _, list := range [][]EntryDiff{diff.Added, diff.Removed, diff.Changed, diff.BecameFuzzy} {
+		sortEntryDiffs(list)
+	}
+
+	return diff
+}
+
+// sortEntryDiffs sorts list by context and msgid
+func sortEntryDiffs(list []EntryDiff) {
+	sort.Slice(list, func(i, j int) bool {
+		if c := strings.Compare(list[i].Context, list[j].Context); c != 0 {
+			return c < 0
+		}
+		return list[i].ID < list[j].ID
+	})
+}
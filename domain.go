@@ -3,10 +3,13 @@ package gotext
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
+	"mime"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	"golang.org/x/text/language"
 
@@ -25,8 +28,9 @@ type Domain struct {
 	// Plural-Forms header
 	PluralForms string
 
-	// Preserve comments at head of PO for round-trip
-	headerComments []string
+	// HeaderComment is the raw block of comments before the header entry of a PO file (i.e. copyright and license notes),
+	// with one comment line per text line. It's written back verbatim at the top of MarshalText output.
+	HeaderComment string
 
 	// Parsed Plural-Forms header values
 	nplurals    int
@@ -38,6 +42,33 @@ type Domain struct {
 	contexts           map[string]map[string]*Translation
 	pluralTranslations map[string]*Translation
 
+	// Obsolete (#~) entries by context and ID, preserved for round-trip only
+	obsoleteTranslations map[string]map[string]*Translation
+
+	// Entries replaced while parsing because of a duplicated msgctxt/msgid
+	duplicates []contextTranslation
+
+	// Keys of the entries, as returned by orderKey, in the order they were parsed
+	order []string
+
+	// Options for MarshalText
+	marshalOptions MarshalOptions
+
+	// Whether MarshalText writes the numbers covered by each plural form, set with SetPluralComments
+	pluralComments bool
+
+	// Pool of the strings shared with other domains while parsing, nil unless interning is enabled
+	pool *stringPool
+
+	// Msgids looked up when the requested one is missing, set with SetAliases
+	aliases map[string]string
+
+	// What the Get* methods return for entries with an empty msgstr
+	emptyPolicy EmptyStringPolicy
+
+	// Separator of the hierarchical contexts looked up by the context methods, empty to disable the fallback
+	contextSeparator string
+
 	// Sync Mutex
 	trMutex     sync.RWMutex
 	pluralMutex sync.RWMutex
@@ -45,7 +76,81 @@ type Domain struct {
 	// Parsing buffers
 	trBuffer  *Translation
 	ctxBuffer string
-	refBuffer string
+	cmtBuffer *Translation
+	obsBuffer bool
+}
+
+// EmptyStringPolicy selects what the Get* methods of a Domain return for an entry whose msgstr is empty,
+// set with Domain.SetEmptyStringPolicy
+type EmptyStringPolicy int
+
+const (
+	// FallbackToID treats empty msgstrs as untranslated and returns the msgid, or msgid_plural. It's the default.
+	FallbackToID EmptyStringPolicy = iota
+
+	// ReturnEmpty treats empty msgstrs as translations to the empty string.
+	// Messages missing from the catalog still fall back to the source string.
+	ReturnEmpty
+)
+
+// MarshalOptions configures the output of Domain.MarshalText
+type MarshalOptions struct {
+	// OmitDates leaves out the POT-Creation-Date and PO-Revision-Date headers,
+	// so re-saving an unchanged catalog gives the same output
+	OmitDates bool
+
+	// OmitHeaders leaves out other headers by name, case insensitive (i.e. "X-Generator")
+	OmitHeaders []string
+
+	// Sort sets the order of the entries. The default, SortByAppearance, keeps the order of the parsed file.
+	Sort SortOrder
+}
+
+// SortOrder selects the order of the entries written by Domain.MarshalText, see MarshalOptions
+type SortOrder int
+
+const (
+	// SortByAppearance writes the entries in the order they were parsed, so re-saving a catalog arranged by hand
+	// gives a minimal diff. Entries added afterwards follow, ordered like SortByReference. It's the default.
+	SortByAppearance SortOrder = iota
+
+	// SortByReference orders the entries by their first source reference, then by context and msgid
+	SortByReference
+
+	// SortByID orders the entries alphabetically by context and msgid
+	SortByID
+)
+
+// omitHeader reports whether the header key is left out by the options
+func (opts MarshalOptions) omitHeader(key string) bool {
+	if opts.OmitDates && (strings.EqualFold(key, "POT-Creation-Date") || strings.EqualFold(key, "PO-Revision-Date")) {
+		return true
+	}
+	for _, omit := range opts.OmitHeaders {
+		if strings.EqualFold(key, omit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orderKey returns the key of an entry in Domain.order
+func orderKey(ctx, id string) string {
+	return ctx + EotSeparator + id
+}
+
+// recordOrder appends an entry parsed for the first time to the parse order. The domain must be locked.
+func (do *Domain) recordOrder(ctx, id string) {
+	if id != "" {
+		do.order = append(do.order, orderKey(ctx, id))
+	}
+}
+
+// contextTranslation holds a Translation along with the context it belongs to
+type contextTranslation struct {
+	context string
+	trans   *Translation
 }
 
 // Preserve MIMEHeader behaviour, without the canonicalisation
@@ -81,10 +186,10 @@ func NewDomain() *Domain {
 	domain := new(Domain)
 
 	domain.Headers = make(HeaderMap)
-	domain.headerComments = make([]string, 0)
 	domain.translations = make(map[string]*Translation)
 	domain.contexts = make(map[string]map[string]*Translation)
 	domain.pluralTranslations = make(map[string]*Translation)
+	domain.obsoleteTranslations = make(map[string]map[string]*Translation)
 
 	return domain
 }
@@ -94,8 +199,21 @@ func (do *Domain) pluralForm(n int) int {
 	do.pluralMutex.RLock()
 	defer do.pluralMutex.RUnlock()
 
+	// Languages with a single form (nplurals=1; plural=0;) don't need the rule to be evaluated
+	if do.nplurals == 1 {
+		return 0
+	}
+
 	// Failure fallback
 	if do.pluralforms == nil {
+		// Use the built-in rule for the language
+		if rule := findPluralRule(do.Language); rule != nil {
+			if rule.nplurals == 1 {
+				return 0
+			}
+			return rule.expr.Eval(uint32(n))
+		}
+
 		/* Use the Germanic plural rule.  */
 		if n == 1 {
 			return 0
@@ -105,8 +223,64 @@ func (do *Domain) pluralForm(n int) int {
 	return do.pluralforms.Eval(uint32(n))
 }
 
+// GetHeader returns the first value of the given header, as declared by the translation of the empty msgid,
+// for both .po and .mo files. Keys are case insensitive. It returns an empty string if the header is missing.
+func (do *Domain) GetHeader(key string) string {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	if v := do.Headers.Get(key); v != "" {
+		return v
+	}
+	for k, v := range do.Headers {
+		if strings.EqualFold(k, key) && len(v) > 0 {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// Charset returns the charset declared on the Content-Type header (i.e. "UTF-8"),
+// or an empty string if it's missing or invalid.
+func (do *Domain) Charset() string {
+	_, params, err := mime.ParseMediaType(do.GetHeader("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return params["charset"]
+}
+
+// PluralFormula returns the raw plural expression declared on the Plural-Forms header (i.e. "(n != 1)").
+// It returns an empty string if the header or the expression is missing.
+func (do *Domain) PluralFormula() string {
+	do.pluralMutex.RLock()
+	defer do.pluralMutex.RUnlock()
+
+	return strings.TrimSpace(do.plural)
+}
+
+// PluralIndex returns the plural form index selected by the Plural-Forms rule of the domain for n,
+// which is the msgstr[index] used by GetN. Without a valid rule the built-in one for the Language is used,
+// see DefaultPluralRule, or the Germanic one, (n != 1), for unknown languages.
+func (do *Domain) PluralIndex(n int) int {
+	return do.pluralForm(n)
+}
+
+// PluralCategory returns the CLDR plural category of n ("zero", "one", "two", "few", "many" or "other")
+// for the Language of the domain, so translations using named categories can be selected. Categories come from
+// the built-in rules, whatever the Plural-Forms header. Unknown languages use "one" for 1 and "other" otherwise.
+func (do *Domain) PluralCategory(n int) string {
+	do.pluralMutex.RLock()
+	lang := do.Language
+	do.pluralMutex.RUnlock()
+
+	return pluralCategory(lang, n)
+}
+
 // parseHeaders retrieves data from previously parsed headers. it's called by both Mo and Po when parsing
-func (do *Domain) parseHeaders() {
+func (do *Domain) parseHeaders() error {
 	raw := ""
 	if _, ok := do.translations[raw]; ok {
 		raw = do.translations[raw].Get()
@@ -146,7 +320,7 @@ func (do *Domain) parseHeaders() {
 
 	// Parse Plural-Forms formula
 	if do.PluralForms == "" {
-		return
+		return nil
 	}
 
 	// Split plural form header value
@@ -166,12 +340,16 @@ func (do *Domain) parseHeaders() {
 		case "plural":
 			do.plural = vs[1]
 
-			if expr, err := plurals.Compile(do.plural); err == nil {
-				do.pluralforms = expr
+			expr, err := plurals.Compile(do.plural)
+			if err != nil {
+				return fmt.Errorf("%w: %q: %v", ErrBadPluralForm, strings.TrimSpace(do.plural), err)
 			}
+			do.pluralforms = expr
 
 		}
 	}
+
+	return nil
 }
 
 // Drops any translations stored that have not been Set*() since 'po'
@@ -200,6 +378,408 @@ func (do *Domain) DropStaleTranslations() {
 	}
 }
 
+// UpdateMetadata refreshes the source references and extracted comments (#: and #.) of the entries
+// from those of other, i.e. a template extracted again after the code moved, matching entries by context and msgid.
+// Translations, flags and translator comments are left untouched. Entries missing from other are marked obsolete,
+// and obsolete entries found again in other are restored. Entries only in other aren't added.
+// The header entry is left untouched.
+func (do *Domain) UpdateMetadata(other *Domain) {
+	if other == do {
+		return
+	}
+
+	// Snapshot the metadata of other before locking do, so updating a and b from each other at once can't deadlock
+	type metadata struct {
+		refs     []string
+		comments []string
+	}
+	sources := make(map[string]metadata)
+	other.trMutex.RLock()
+	for id, trans := range other.translations {
+		sources[orderKey("", id)] = metadata{append([]string(nil), trans.Refs...), append([]string(nil), trans.ExtractedComments...)}
+	}
+	for ctx, translations := range other.contexts {
+		for id, trans := range translations {
+			sources[orderKey(ctx, id)] = metadata{append([]string(nil), trans.Refs...), append([]string(nil), trans.ExtractedComments...)}
+		}
+	}
+	other.trMutex.RUnlock()
+
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	// find returns the metadata of the entry of other for ctx and id
+	find := func(ctx, id string) (metadata, bool) {
+		source, ok := sources[orderKey(ctx, id)]
+		return source, ok
+	}
+	update := func(trans *Translation, source metadata) {
+		trans.Refs = append([]string(nil), source.refs...)
+		trans.ExtractedComments = append([]string(nil), source.comments...)
+	}
+	obsolete := func(ctx, id string, trans *Translation) {
+		if _, ok := do.obsoleteTranslations[ctx]; !ok {
+			do.obsoleteTranslations[ctx] = make(map[string]*Translation)
+		}
+		do.obsoleteTranslations[ctx][id] = trans
+		if trans.PluralID != "" && do.pluralTranslations[trans.PluralID] == trans {
+			delete(do.pluralTranslations, trans.PluralID)
+		}
+	}
+
+	for id, trans := range do.translations {
+		if id == "" {
+			continue
+		}
+		if source, ok := find("", id); ok {
+			update(trans, source)
+		} else {
+			delete(do.translations, id)
+			obsolete("", id, trans)
+		}
+	}
+	for ctx, translations := range do.contexts {
+		for id, trans := range translations {
+			if source, ok := find(ctx, id); ok {
+				update(trans, source)
+			} else {
+				delete(translations, id)
+				obsolete(ctx, id, trans)
+			}
+		}
+		if len(translations) == 0 {
+			delete(do.contexts, ctx)
+		}
+	}
+
+	for ctx, translations := range do.obsoleteTranslations {
+		for id, trans := range translations {
+			source, ok := find(ctx, id)
+			if !ok {
+				continue
+			}
+
+			update(trans, source)
+			delete(translations, id)
+			if ctx == "" {
+				do.translations[id] = trans
+			} else {
+				if _, ok := do.contexts[ctx]; !ok {
+					do.contexts[ctx] = make(map[string]*Translation)
+				}
+				do.contexts[ctx][id] = trans
+			}
+			if trans.PluralID != "" {
+				do.pluralTranslations[trans.PluralID] = trans
+			}
+		}
+		if len(translations) == 0 {
+			delete(do.obsoleteTranslations, ctx)
+		}
+	}
+}
+
+// Revive moves the obsolete (#~) entry of the given msgid, without context, back to the active entries,
+// i.e. when a string removed from the code is added again, so its previous Translation isn't lost.
+// It returns false if there's no obsolete entry for the msgid, or if there's already an active one.
+func (do *Domain) Revive(id string) bool {
+	return do.ReviveC(id, "")
+}
+
+// ReviveC works like Revive for the obsolete entry of the given msgid in the given context.
+func (do *Domain) ReviveC(id, ctx string) bool {
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	trans, ok := do.obsoleteTranslations[ctx][id]
+	if !ok || id == "" {
+		return false
+	}
+
+	if ctx == "" {
+		if _, ok := do.translations[id]; ok {
+			return false
+		}
+		do.translations[id] = trans
+	} else {
+		if _, ok := do.contexts[ctx][id]; ok {
+			return false
+		}
+		if _, ok := do.contexts[ctx]; !ok {
+			do.contexts[ctx] = make(map[string]*Translation)
+		}
+		do.contexts[ctx][id] = trans
+	}
+	if trans.PluralID != "" {
+		do.pluralTranslations[trans.PluralID] = trans
+	}
+	do.recordOrder(ctx, id)
+
+	delete(do.obsoleteTranslations[ctx], id)
+	if len(do.obsoleteTranslations[ctx]) == 0 {
+		delete(do.obsoleteTranslations, ctx)
+	}
+
+	return true
+}
+
+// DropFuzzyTranslations clears the Translation of every entry flagged as fuzzy, in all contexts,
+// like msgfmt does when compiling, and returns the number of entries cleared.
+// The entries themselves are kept, untranslated. The header entry is never cleared.
+func (do *Domain) DropFuzzyTranslations() int {
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	count := 0
+	drop := func(trans *Translation) {
+		if trans.ID != "" && trans.IsFuzzy() {
+			trans.load()
+			trans.Trs = make(map[int]string)
+			count++
+		}
+	}
+
+	for _, trans := range do.translations {
+		drop(trans)
+	}
+	for _, ctx := range do.contexts {
+		for _, trans := range ctx {
+			drop(trans)
+		}
+	}
+
+	return count
+}
+
+// SplitByContext returns a new domain per msgctxt holding the entries of that context, without context,
+// to migrate a single catalog to a domain per feature. Entries without context go to the "default" domain,
+// where entries of a context named "default" keep their context to avoid clashes.
+// Headers, plural forms, comments and obsolete entries are carried over. The entries are copied.
+func (do *Domain) SplitByContext() map[string]*Domain {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	do.pluralMutex.RLock()
+	defer do.pluralMutex.RUnlock()
+
+	domains := make(map[string]*Domain, len(do.contexts)+1)
+	get := func(name string) *Domain {
+		if split, ok := domains[name]; ok {
+			return split
+		}
+
+		split := do.emptyCopy()
+		domains[name] = split
+		return split
+	}
+
+	for id, trans := range do.translations {
+		if id != "" {
+			split := get("default")
+			split.translations[id] = trans.clone()
+			if trans.PluralID != "" {
+				split.pluralTranslations[trans.PluralID] = split.translations[id]
+			}
+		}
+	}
+
+	for ctx, translations := range do.contexts {
+		split := get(ctx)
+		entries := split.translations
+		if ctx == "default" {
+			entries = make(map[string]*Translation, len(translations))
+			split.contexts[ctx] = entries
+		}
+
+		for id, trans := range translations {
+			if id == "" {
+				continue
+			}
+			entries[id] = trans.clone()
+			if trans.PluralID != "" && ctx != "default" {
+				split.pluralTranslations[trans.PluralID] = entries[id]
+			}
+		}
+	}
+
+	for ctx, translations := range do.obsoleteTranslations {
+		name, obsCtx := ctx, ""
+		if ctx == "" || ctx == "default" {
+			name, obsCtx = "default", ctx
+		}
+
+		split := get(name)
+		if _, ok := split.obsoleteTranslations[obsCtx]; !ok {
+			split.obsoleteTranslations[obsCtx] = make(map[string]*Translation)
+		}
+		for id, trans := range translations {
+			split.obsoleteTranslations[obsCtx][id] = trans.clone()
+		}
+	}
+
+	return domains
+}
+
+// SubsetByPrefix returns a new domain holding copies of the entries whose msgid starts with prefix,
+// i.e. the keys of a feature in a key-based catalog, along with all the entries of the contexts starting with prefix
+// for context-based catalogs, so a client can be sent only the strings of the features it uses.
+// Headers, plural forms and the header comment are carried over, obsolete entries aren't.
+func (do *Domain) SubsetByPrefix(prefix string) *Domain {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	do.pluralMutex.RLock()
+	defer do.pluralMutex.RUnlock()
+
+	subset := do.emptyCopy()
+	for id, trans := range do.translations {
+		if id != "" && strings.HasPrefix(id, prefix) {
+			subset.translations[id] = trans.clone()
+			if trans.PluralID != "" {
+				subset.pluralTranslations[trans.PluralID] = subset.translations[id]
+			}
+		}
+	}
+	for ctx, translations := range do.contexts {
+		ctxMatch := strings.HasPrefix(ctx, prefix)
+		for id, trans := range translations {
+			if !ctxMatch && !strings.HasPrefix(id, prefix) {
+				continue
+			}
+			if _, ok := subset.contexts[ctx]; !ok {
+				subset.contexts[ctx] = make(map[string]*Translation)
+			}
+			subset.contexts[ctx][id] = trans.clone()
+		}
+	}
+
+	// Keep the parse order of the entries kept
+	for _, key := range do.order {
+		idx := strings.Index(key, EotSeparator)
+		ctx, id := key[:idx], key[idx+len(EotSeparator):]
+		if ctx == "" {
+			if _, ok := subset.translations[id]; ok {
+				subset.order = append(subset.order, key)
+			}
+		} else if _, ok := subset.contexts[ctx][id]; ok {
+			subset.order = append(subset.order, key)
+		}
+	}
+
+	return subset
+}
+
+// emptyCopy returns a new domain with the headers, plural forms and marshal options of the domain, but no entries.
+// The domain must be read-locked, along with its plural forms.
+func (do *Domain) emptyCopy() *Domain {
+	cp := NewDomain()
+	for k, v := range do.Headers {
+		cp.Headers[k] = append([]string(nil), v...)
+	}
+	cp.Language = do.Language
+	cp.tag = do.tag
+	cp.PluralForms = do.PluralForms
+	cp.HeaderComment = do.HeaderComment
+	cp.nplurals = do.nplurals
+	cp.plural = do.plural
+	cp.pluralforms = do.pluralforms
+	cp.marshalOptions = do.marshalOptions
+	if header, ok := do.translations[""]; ok {
+		cp.translations[""] = header.clone()
+	}
+
+	return cp
+}
+
+// retain keeps only the entries whose msgid is accepted by keep, along with the header entry.
+// Maps are rebuilt rather than pruned, so the memory of the dropped entries can be reclaimed.
+func (do *Domain) retain(keep func(id string) bool) {
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	translations := make(map[string]*Translation)
+	pluralTranslations := make(map[string]*Translation)
+	for id, trans := range do.translations {
+		if id == "" || keep(id) {
+			translations[id] = trans
+			if trans.PluralID != "" {
+				pluralTranslations[trans.PluralID] = trans
+			}
+		}
+	}
+
+	contexts := make(map[string]map[string]*Translation)
+	for ctx, entries := range do.contexts {
+		kept := make(map[string]*Translation)
+		for id, trans := range entries {
+			if keep(id) {
+				kept[id] = trans
+			}
+		}
+		if len(kept) > 0 {
+			contexts[ctx] = kept
+		}
+	}
+
+	do.translations = translations
+	do.pluralTranslations = pluralTranslations
+	do.contexts = contexts
+	do.obsoleteTranslations = make(map[string]map[string]*Translation)
+	do.duplicates = nil
+}
+
+// merge adds the entries of other to the domain, replacing the entries with the same context and msgid,
+// which are then reported by FindDuplicates. New entries keep their parse order in other.
+// The header and the obsolete entries of other are ignored.
+func (do *Domain) merge(other *Domain) {
+	other.trMutex.RLock()
+	defer other.trMutex.RUnlock()
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	added := make(map[string]bool)
+	for id, trans := range other.translations {
+		if id == "" {
+			continue
+		}
+		if prev, ok := do.translations[id]; ok {
+			do.duplicates = append(do.duplicates, contextTranslation{"", prev})
+		} else {
+			added[orderKey("", id)] = true
+		}
+		do.translations[id] = trans
+		if trans.PluralID != "" {
+			do.pluralTranslations[trans.PluralID] = trans
+		}
+	}
+	for ctx, translations := range other.contexts {
+		if _, ok := do.contexts[ctx]; !ok {
+			do.contexts[ctx] = make(map[string]*Translation)
+		}
+		for id, trans := range translations {
+			if prev, ok := do.contexts[ctx][id]; ok {
+				do.duplicates = append(do.duplicates, contextTranslation{ctx, prev})
+			} else if id != "" {
+				added[orderKey(ctx, id)] = true
+			}
+			do.contexts[ctx][id] = trans
+		}
+	}
+
+	for _, key := range other.order {
+		if added[key] {
+			do.order = append(do.order, key)
+			delete(added, key)
+		}
+	}
+	rest := make([]string, 0, len(added))
+	for key := range added {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	do.order = append(do.order, rest...)
+}
+
 // Set source references for a given translation
 func (do *Domain) SetRefs(str string, refs []string) {
 	do.trMutex.Lock()
@@ -208,7 +788,7 @@ func (do *Domain) SetRefs(str string, refs []string) {
 	defer do.pluralMutex.Unlock()
 
 	if trans, ok := do.translations[str]; ok {
-		trans.Refs = refs
+		trans.SetRefs(refs)
 	} else {
 		trans = NewTranslation()
 		trans.ID = str
@@ -217,6 +797,23 @@ func (do *Domain) SetRefs(str string, refs []string) {
 	}
 }
 
+// AddRef adds a source reference to a given translation, unless it already has it
+func (do *Domain) AddRef(str string, ref string) {
+	do.trMutex.Lock()
+	do.pluralMutex.Lock()
+	defer do.trMutex.Unlock()
+	defer do.pluralMutex.Unlock()
+
+	if trans, ok := do.translations[str]; ok {
+		trans.AddRef(ref)
+	} else {
+		trans = NewTranslation()
+		trans.ID = str
+		trans.AddRef(ref)
+		do.translations[str] = trans
+	}
+}
+
 // Get source references for a given translation
 func (do *Domain) GetRefs(str string) []string {
 	// Sync read
@@ -231,6 +828,25 @@ func (do *Domain) GetRefs(str string) []string {
 	return nil
 }
 
+// sourceRef returns the first source reference of the entry of str, in ctx or without context if ctx is empty,
+// as looked up by the Get* methods, or an empty string if there's none.
+func (do *Domain) sourceRef(str, ctx string) string {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	var trans *Translation
+	var ok bool
+	if ctx == "" {
+		trans, ok = do.lookup(str, nil)
+	} else {
+		trans, ok = do.lookup(str, &ctx)
+	}
+	if !ok || len(trans.Refs) == 0 {
+		return ""
+	}
+	return trans.Refs[0]
+}
+
 // Set the translation of a given string
 func (do *Domain) Set(id, str string) {
 	do.trMutex.Lock()
@@ -244,8 +860,84 @@ func (do *Domain) Set(id, str string) {
 		trans = NewTranslation()
 		trans.ID = id
 		trans.Set(str)
-		do.translations[str] = trans
+		do.translations[id] = trans
+	}
+}
+
+// SetAliases sets msgids to look up when the requested one has no entry, in any context,
+// i.e. {"old.key": "new.key"} to keep old call sites working while keys are renamed in the code and the catalog.
+// Aliases aren't followed recursively. It replaces the previous aliases, nil removes them.
+func (do *Domain) SetAliases(aliases map[string]string) {
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	do.aliases = make(map[string]string, len(aliases))
+	for from, to := range aliases {
+		do.aliases[from] = to
+	}
+}
+
+// SetContextFallback enables looking up hierarchical contexts, whose levels are separated by separator:
+// with SetContextFallback("."), a message missing from the "checkout.button" context is looked up in "checkout",
+// and then without context. So GetC(str, "checkout.button") returns the Translation of str in the "checkout.button"
+// context if there's one, else the one in the "checkout" context, else the one without context, else the source string.
+// The same order is used by GetNC, IsTranslatedC and IsTranslatedNC. Each level is looked up with the alias of str,
+// if any, before moving to the next one. An empty separator, the default, disables the fallback.
+func (do *Domain) SetContextFallback(separator string) {
+	do.trMutex.Lock()
+	do.contextSeparator = separator
+	do.trMutex.Unlock()
+}
+
+// lookup returns the entry of str in ctx, or in no context when ctx is nil, falling back to the alias of str
+// and to the parent contexts set up by SetContextFallback. The domain must be read-locked.
+func (do *Domain) lookup(str string, ctx *string) (*Translation, bool) {
+	find := func(entries map[string]*Translation) (*Translation, bool) {
+		if trans, ok := entries[str]; ok {
+			return trans, true
+		}
+		if alias, ok := do.aliases[str]; ok {
+			trans, ok := entries[alias]
+			return trans, ok
+		}
+		return nil, false
+	}
+
+	if ctx == nil {
+		return find(do.translations)
+	}
+	if trans, ok := find(do.contexts[*ctx]); ok || do.contextSeparator == "" {
+		return trans, ok
+	}
+
+	for parent := *ctx; ; {
+		idx := strings.LastIndex(parent, do.contextSeparator)
+		if idx == -1 {
+			return find(do.translations)
+		}
+		parent = parent[:idx]
+		if trans, ok := find(do.contexts[parent]); ok {
+			return trans, true
+		}
+	}
+}
+
+// SetEmptyStringPolicy sets what the Get* methods return for entries with an empty msgstr (or plural form):
+// the source string with FallbackToID, the default, or an empty string with ReturnEmpty.
+// IsTranslated and the related methods still report such entries as untranslated.
+func (do *Domain) SetEmptyStringPolicy(policy EmptyStringPolicy) {
+	do.trMutex.Lock()
+	do.emptyPolicy = policy
+	do.trMutex.Unlock()
+}
+
+// translated returns the given plural form of trans according to the empty string policy. The Domain must be read-locked.
+func (do *Domain) translated(trans *Translation, form int) string {
+	if do.emptyPolicy == ReturnEmpty {
+		trans.load()
+		return trans.Trs[form]
 	}
+	return trans.GetN(form)
 }
 
 func (do *Domain) Get(str string, vars ...interface{}) string {
@@ -253,10 +945,8 @@ func (do *Domain) Get(str string, vars ...interface{}) string {
 	do.trMutex.RLock()
 	defer do.trMutex.RUnlock()
 
-	if do.translations != nil {
-		if _, ok := do.translations[str]; ok {
-			return Printf(do.translations[str].Get(), vars...)
-		}
+	if trans, ok := do.lookup(str, nil); ok {
+		return trans.format(do.translated(trans, 0), vars)
 	}
 
 	// Return the same we received by default
@@ -280,7 +970,7 @@ func (do *Domain) SetN(id, plural string, n int, str string) {
 		trans.ID = id
 		trans.PluralID = plural
 		trans.SetN(pluralForm, str)
-		do.translations[str] = trans
+		do.translations[id] = trans
 	}
 }
 
@@ -291,10 +981,8 @@ func (do *Domain) GetN(str, plural string, n int, vars ...interface{}) string {
 	do.trMutex.RLock()
 	defer do.trMutex.RUnlock()
 
-	if do.translations != nil {
-		if _, ok := do.translations[str]; ok {
-			return Printf(do.translations[str].GetN(do.pluralForm(n)), vars...)
-		}
+	if trans, ok := do.lookup(str, nil); ok {
+		return trans.format(do.translated(trans, do.pluralForm(n)), vars)
 	}
 
 	// Parse plural forms to distinguish between plural and singular
@@ -304,6 +992,15 @@ func (do *Domain) GetN(str, plural string, n int, vars ...interface{}) string {
 	return Printf(plural, vars...)
 }
 
+// GetRange retrieves the plural form of Translation for the given string to be used with a numeric range (start-end),
+// like "1–5 items".
+// Gettext catalogs don't carry CLDR plural range data, so the form is selected using the end value of the range,
+// which is the CLDR default for most languages.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (do *Domain) GetRange(str, plural string, start, end int, vars ...interface{}) string {
+	return do.GetN(str, plural, end, vars...)
+}
+
 // Set the translation for the given string in the given context
 func (do *Domain) SetC(id, ctx, str string) {
 	do.trMutex.Lock()
@@ -336,14 +1033,8 @@ func (do *Domain) GetC(str, ctx string, vars ...interface{}) string {
 	do.trMutex.RLock()
 	defer do.trMutex.RUnlock()
 
-	if do.contexts != nil {
-		if _, ok := do.contexts[ctx]; ok {
-			if do.contexts[ctx] != nil {
-				if _, ok := do.contexts[ctx][str]; ok {
-					return Printf(do.contexts[ctx][str].Get(), vars...)
-				}
-			}
-		}
+	if trans, ok := do.lookup(str, &ctx); ok {
+		return trans.format(do.translated(trans, 0), vars)
 	}
 
 	// Return the string we received by default
@@ -385,14 +1076,8 @@ func (do *Domain) GetNC(str, plural string, n int, ctx string, vars ...interface
 	do.trMutex.RLock()
 	defer do.trMutex.RUnlock()
 
-	if do.contexts != nil {
-		if _, ok := do.contexts[ctx]; ok {
-			if do.contexts[ctx] != nil {
-				if _, ok := do.contexts[ctx][str]; ok {
-					return Printf(do.contexts[ctx][str].GetN(do.pluralForm(n)), vars...)
-				}
-			}
-		}
+	if trans, ok := do.lookup(str, &ctx); ok {
+		return trans.format(do.translated(trans, do.pluralForm(n)), vars)
 	}
 
 	if n == 1 {
@@ -401,6 +1086,241 @@ func (do *Domain) GetNC(str, plural string, n int, ctx string, vars ...interface
 	return Printf(plural, vars...)
 }
 
+// IsTranslated reports whether the given string has a non-empty Translation.
+func (do *Domain) IsTranslated(str string) bool {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	if trans, ok := do.lookup(str, nil); ok {
+		return trans.IsTranslatedN(0)
+	}
+	return false
+}
+
+// IsTranslatedN reports whether the plural form selected by n has a non-empty Translation for the given string.
+func (do *Domain) IsTranslatedN(str string, n int) bool {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	if trans, ok := do.lookup(str, nil); ok {
+		return trans.IsTranslatedN(do.pluralForm(n))
+	}
+	return false
+}
+
+// IsTranslatedC reports whether the given string has a non-empty Translation in the given context.
+func (do *Domain) IsTranslatedC(str, ctx string) bool {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	if trans, ok := do.lookup(str, &ctx); ok {
+		return trans.IsTranslatedN(0)
+	}
+	return false
+}
+
+// IsTranslatedNC reports whether the plural form selected by n has a non-empty Translation for the given string in the given context.
+func (do *Domain) IsTranslatedNC(str string, n int, ctx string) bool {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	if trans, ok := do.lookup(str, &ctx); ok {
+		return trans.IsTranslatedN(do.pluralForm(n))
+	}
+	return false
+}
+
+// hasTranslation reports whether the given string has a non-empty and non-fuzzy Translation in the given context,
+// or in no context if ctx is empty.
+func (do *Domain) hasTranslation(str, ctx string) bool {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	var trans *Translation
+	var ok bool
+	if ctx == "" {
+		trans, ok = do.lookup(str, nil)
+	} else {
+		trans, ok = do.lookup(str, &ctx)
+	}
+	return ok && trans.IsTranslatedN(0) && !trans.IsFuzzy()
+}
+
+// PluralCount returns the number of plural forms of the domain: the nplurals of the Plural-Forms header,
+// or when it's missing or invalid the one of the built-in rule for the language, or 2 for the Germanic rule
+// used when the language has no built-in rule either.
+func (do *Domain) PluralCount() int {
+	do.pluralMutex.RLock()
+	defer do.pluralMutex.RUnlock()
+
+	if do.nplurals > 0 {
+		return do.nplurals
+	}
+	if rule := findPluralRule(do.Language); rule != nil {
+		return rule.nplurals
+	}
+	return 2
+}
+
+// PluralError describes a plural entry whose number of translated forms doesn't match the plural count of its domain,
+// as reported by Domain.ValidatePlurals
+type PluralError struct {
+	Context string
+	ID      string
+
+	// Line of the msgid in the parsed .po file, or 0 if unknown
+	Line int
+
+	// Filled is the number of non-empty forms of the entry, and Expected the plural count of the domain
+	Filled   int
+	Expected int
+}
+
+func (e PluralError) Error() string {
+	if e.Context != "" {
+		return fmt.Sprintf("gettext: msgid %q in context %q has %d plural forms but nplurals=%d", e.ID, e.Context, e.Filled, e.Expected)
+	}
+	return fmt.Sprintf("gettext: msgid %q has %d plural forms but nplurals=%d", e.ID, e.Filled, e.Expected)
+}
+
+// ValidatePlurals returns the plural entries whose number of non-empty forms doesn't match PluralCount,
+// i.e. a Russian entry missing its third form, sorted by context and msgid.
+// Untranslated and fuzzy entries aren't reported, as they aren't used anyway.
+func (do *Domain) ValidatePlurals() []PluralError {
+	expected := do.PluralCount()
+
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	var errs []PluralError
+	check := func(ctx string, trans *Translation) {
+		if trans.ID == "" || trans.PluralID == "" || trans.IsFuzzy() {
+			return
+		}
+
+		trans.load()
+		filled := 0
+		for _, tr := range trans.Trs {
+			if tr != "" {
+				filled++
+			}
+		}
+		if filled != 0 && filled != expected {
+			errs = append(errs, PluralError{Context: ctx, ID: trans.ID, Line: trans.Line, Filled: filled, Expected: expected})
+		}
+	}
+
+	for _, trans := range do.translations {
+		check("", trans)
+	}
+	for ctx, translations := range do.contexts {
+		for _, trans := range translations {
+			check(ctx, trans)
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Context != errs[j].Context {
+			return errs[i].Context < errs[j].Context
+		}
+		return errs[i].ID < errs[j].ID
+	})
+	return errs
+}
+
+// FindDuplicates returns the groups of parsed entries sharing the same context and msgid, which gettext tools reject.
+// Within a group, entries replaced while parsing come first, in parse order, followed by the ones actually used for lookups.
+// When ignoreTrailingSpace is set, msgids that only differ in trailing whitespace are also reported as duplicates.
+func (do *Domain) FindDuplicates(ignoreTrailingSpace bool) [][]*Translation {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	type key struct {
+		context string
+		id      string
+	}
+
+	normalize := func(id string) string {
+		if ignoreTrailingSpace {
+			return strings.TrimRightFunc(id, unicode.IsSpace)
+		}
+		return id
+	}
+
+	all := make([]contextTranslation, 0, len(do.duplicates)+len(do.translations))
+	all = append(all, do.duplicates...)
+	for _, trans := range do.translations {
+		all = append(all, contextTranslation{"", trans})
+	}
+	for name, ctx := range do.contexts {
+		for _, trans := range ctx {
+			all = append(all, contextTranslation{name, trans})
+		}
+	}
+
+	// Consistent order for the active entries
+	active := all[len(do.duplicates):]
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].context != active[j].context {
+			return active[i].context < active[j].context
+		}
+		return active[i].trans.ID < active[j].trans.ID
+	})
+
+	groups := make(map[key][]*Translation)
+	for _, entry := range all {
+		if entry.trans.ID == "" {
+			continue
+		}
+		k := key{entry.context, normalize(entry.trans.ID)}
+		groups[k] = append(groups[k], entry.trans)
+	}
+
+	keys := make([]key, 0)
+	for k, group := range groups {
+		if len(group) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].context != keys[j].context {
+			return keys[i].context < keys[j].context
+		}
+		return keys[i].id < keys[j].id
+	})
+
+	result := make([][]*Translation, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, groups[k])
+	}
+
+	return result
+}
+
+// String implements the fmt.Stringer interface.
+// It returns a short summary of the domain (language and message counts) instead of the full catalog, useful for debugging.
+func (do *Domain) String() string {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	// Don't count the header entry as a message
+	count := len(do.translations)
+	if _, ok := do.translations[""]; ok {
+		count--
+	}
+
+	ctxCount := 0
+	for _, ctx := range do.contexts {
+		for id := range ctx {
+			if id != "" {
+				ctxCount++
+			}
+		}
+	}
+
+	return fmt.Sprintf("Domain{Language: %q, Translations: %d, Contexts: %d}", do.Language, count, ctxCount)
+}
+
 //GetTranslations returns a copy of every translation in the domain. It does not support contexts.
 func (do *Domain) GetTranslations() map[string]*Translation {
 	all := make(map[string]*Translation, len(do.translations))
@@ -409,50 +1329,213 @@ func (do *Domain) GetTranslations() map[string]*Translation {
 	defer do.trMutex.RUnlock()
 
 	for msgID, trans := range do.translations {
-		newTrans := NewTranslation()
-		newTrans.ID = trans.ID
-		newTrans.PluralID = trans.PluralID
-		newTrans.dirty = trans.dirty
-		if len(trans.Refs) > 0 {
-			newTrans.Refs = make([]string, len(trans.Refs))
-			copy(newTrans.Refs, trans.Refs)
+		all[msgID] = trans.clone()
+	}
+
+	return all
+}
+
+// ForEach calls fn for every translation in the domain, in no particular order, until fn returns false.
+// Like GetTranslations, it does not support contexts, but it doesn't copy anything.
+// The domain is read locked during the iteration, so fn must not modify it,
+// and the *Translation passed to fn must not be mutated or retained after fn returns.
+func (do *Domain) ForEach(fn func(id string, t *Translation) bool) {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	for msgID, trans := range do.translations {
+		trans.load()
+		if !fn(msgID, trans) {
+			return
+		}
+	}
+}
+
+// ForEachC works like ForEach, but it also iterates over the translations in every context.
+// Entries without context are passed with an empty ctx.
+func (do *Domain) ForEachC(fn func(ctx, id string, t *Translation) bool) {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	for msgID, trans := range do.translations {
+		trans.load()
+		if !fn("", msgID, trans) {
+			return
 		}
-		for k, v := range trans.Trs {
-			newTrans.Trs[k] = v
+	}
+	for ctx, translations := range do.contexts {
+		for msgID, trans := range translations {
+			trans.load()
+			if !fn(ctx, msgID, trans) {
+				return
+			}
 		}
-		all[msgID] = newTrans
 	}
+}
 
-	return all
+// ContextsFor returns the contexts where the given msgid has an entry, sorted, with an empty string first
+// if it also has one without context. It returns nil if the msgid is unknown.
+func (do *Domain) ContextsFor(id string) []string {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	var contexts []string
+	if _, ok := do.translations[id]; ok {
+		contexts = append(contexts, "")
+	}
+
+	found := len(contexts)
+	for ctx, translations := range do.contexts {
+		if _, ok := translations[id]; ok {
+			contexts = append(contexts, ctx)
+		}
+	}
+	sort.Strings(contexts[found:])
+
+	return contexts
 }
 
 type SourceReference struct {
 	path    string
 	line    int
+	col     int
 	context string
 	trans   *Translation
 }
 
-func extractPathAndLine(ref string) (string, int) {
+// extractReference splits a source reference, "file:line" or "file:line:col" as written by newer xgettext versions,
+// into its parts. The line and column are 0 when missing.
+func extractReference(ref string) (string, int, int) {
 	var path string
-	var line int
+	var line, col int
 	colonIdx := strings.IndexRune(ref, ':')
 	if colonIdx >= 0 {
 		path = ref[:colonIdx]
-		line, _ = strconv.Atoi(ref[colonIdx+1:])
+		pos := ref[colonIdx+1:]
+		if colIdx := strings.IndexRune(pos, ':'); colIdx >= 0 {
+			col, _ = strconv.Atoi(pos[colIdx+1:])
+			pos = pos[:colIdx]
+		}
+		line, _ = strconv.Atoi(pos)
 	} else {
 		path = ref
 		line = 0
 	}
-	return path, line
+	return path, line, col
+}
+
+// SetMarshalOptions sets the options used by MarshalText, i.e. to omit volatile headers for diffable output
+func (do *Domain) SetMarshalOptions(opts MarshalOptions) {
+	do.trMutex.Lock()
+	do.marshalOptions = opts
+	do.trMutex.Unlock()
+}
+
+// SetPluralComments sets whether MarshalText writes, among the translator comments of each plural entry, a comment
+// per plural form listing the first numbers it covers according to the plural rule, i.e. "# form[1]: 2,3,4,...",
+// to help translators review the forms. Po skips them when parsing, so they don't pile up on round-trips.
+func (do *Domain) SetPluralComments(enabled bool) {
+	do.trMutex.Lock()
+	do.pluralComments = enabled
+	do.trMutex.Unlock()
+}
+
+const (
+	// pluralCommentSamples is the number of integers listed for each plural form by SetPluralComments
+	pluralCommentSamples = 3
+
+	// pluralCommentLimit is the largest integer checked for the comments of SetPluralComments
+	pluralCommentLimit = 1000
+)
+
+// pluralFormComments returns the comment written by MarshalText for each plural form, with up to
+// pluralCommentSamples of the numbers it covers. Forms covering no number up to pluralCommentLimit get an empty comment.
+func (do *Domain) pluralFormComments() []string {
+	samples := make([][]string, 0, 2)
+	more := make([]bool, 0, 2)
+	for n := 0; n <= pluralCommentLimit; n++ {
+		form := do.pluralForm(n)
+		if form < 0 {
+			continue
+		}
+		for len(samples) <= form {
+			samples = append(samples, nil)
+			more = append(more, false)
+		}
+		if len(samples[form]) < pluralCommentSamples {
+			samples[form] = append(samples[form], strconv.Itoa(n))
+		} else {
+			more[form] = true
+		}
+	}
+
+	comments := make([]string, len(samples))
+	for form, numbers := range samples {
+		if len(numbers) == 0 {
+			continue
+		}
+		if more[form] {
+			numbers = append(numbers, "...")
+		}
+		comments[form] = fmt.Sprintf("form[%d]: %s", form, strings.Join(numbers, ","))
+	}
+	return comments
+}
+
+// isPluralComment reports whether the comment line l was written by MarshalText with SetPluralComments
+func isPluralComment(l string) bool {
+	rest := strings.TrimPrefix(l, "# form[")
+	if len(rest) == len(l) {
+		return false
+	}
+	idx := strings.Index(rest, "]: ")
+	if idx <= 0 || strings.Trim(rest[:idx], "0123456789") != "" {
+		return false
+	}
+	rest = strings.TrimSuffix(rest[idx+3:], ",...")
+	for _, n := range strings.Split(rest, ",") {
+		if n == "" || strings.Trim(n, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// languagePluralForms returns the Plural-Forms header value of the built-in plural rule for the language of the domain,
+// taken from Language or the Language header. It returns an empty string if the domain already has a Plural-Forms formula
+// or if the language has no built-in rule.
+func (do *Domain) languagePluralForms() string {
+	if do.PluralForms != "" {
+		return ""
+	}
+
+	lang := do.Language
+	for k, v := range do.Headers {
+		if len(v) == 0 || strings.TrimSpace(v[0]) == "" {
+			continue
+		}
+		if strings.EqualFold(k, "Plural-Forms") {
+			return ""
+		}
+		if lang == "" && strings.EqualFold(k, "Language") {
+			lang = v[0]
+		}
+	}
+
+	rule := findPluralRule(lang)
+	if rule == nil {
+		return ""
+	}
+	return fmt.Sprintf("nplurals=%d; plural=%s;", rule.nplurals, rule.plural)
 }
 
 // MarshalText implements encoding.TextMarshaler interface
 // Assists round-trip of POT/PO content
+// When the domain has no Plural-Forms header, the one of the built-in rule for its language is written, if any.
 func (do *Domain) MarshalText() ([]byte, error) {
 	var buf bytes.Buffer
-	if len(do.headerComments) > 0 {
-		buf.WriteString(strings.Join(do.headerComments, "\n"))
+	if do.HeaderComment != "" {
+		buf.WriteString(do.HeaderComment)
 		buf.WriteByte(byte('\n'))
 	}
 	buf.WriteString("msgid \"\"\nmsgstr \"\"")
@@ -472,10 +1555,21 @@ func (do *Domain) MarshalText() ([]byte, error) {
 		"plural-forms":              11,
 	}
 
-	headerKeys := make([]string, 0, len(do.Headers))
+	headerKeys := make([]string, 0, len(do.Headers)+1)
+
+	// Plural-Forms header derived from the language when the domain has none
+	pluralForms := do.languagePluralForms()
 
 	for k, _ := range do.Headers {
-		headerKeys = append(headerKeys, k)
+		if pluralForms != "" && strings.EqualFold(k, "Plural-Forms") {
+			continue
+		}
+		if !do.marshalOptions.omitHeader(k) {
+			headerKeys = append(headerKeys, k)
+		}
+	}
+	if pluralForms != "" && !do.marshalOptions.omitHeader("Plural-Forms") {
+		headerKeys = append(headerKeys, "Plural-Forms")
 	}
 
 	sort.Slice(headerKeys, func(i, j int) bool {
@@ -502,13 +1596,17 @@ func (do *Domain) MarshalText() ([]byte, error) {
 	for _, k := range headerKeys {
 		// Access Headers map directly so as not to canonicalise
 		v := do.Headers[k]
+		if pluralForms != "" && k == "Plural-Forms" {
+			v = []string{pluralForms}
+		}
 
 		for _, value := range v {
 			buf.WriteString("\n\"" + k + ": " + value + "\\n\"")
 		}
 	}
 
-	// Just as with headers, output translations in consistent order (to minimise diffs between round-trips), with (first) source reference taking priority, followed by context and finally ID
+	// Just as with headers, output translations in consistent order (to minimise diffs between round-trips), in parse order by default,
+	// else with (first) source reference taking priority, followed by context and finally ID
 	references := make([]SourceReference, 0)
 	for name, ctx := range do.contexts {
 		for id, trans := range ctx {
@@ -516,10 +1614,11 @@ func (do *Domain) MarshalText() ([]byte, error) {
 				continue
 			}
 			if len(trans.Refs) > 0 {
-				path, line := extractPathAndLine(trans.Refs[0])
+				path, line, col := extractReference(trans.Refs[0])
 				references = append(references, SourceReference{
 					path,
 					line,
+					col,
 					name,
 					trans,
 				})
@@ -527,6 +1626,7 @@ func (do *Domain) MarshalText() ([]byte, error) {
 				references = append(references, SourceReference{
 					"",
 					0,
+					0,
 					name,
 					trans,
 				})
@@ -540,10 +1640,11 @@ func (do *Domain) MarshalText() ([]byte, error) {
 		}
 
 		if len(trans.Refs) > 0 {
-			path, line := extractPathAndLine(trans.Refs[0])
+			path, line, col := extractReference(trans.Refs[0])
 			references = append(references, SourceReference{
 				path,
 				line,
+				col,
 				"",
 				trans,
 			})
@@ -551,13 +1652,40 @@ func (do *Domain) MarshalText() ([]byte, error) {
 			references = append(references, SourceReference{
 				"",
 				0,
+				0,
 				"",
 				trans,
 			})
 		}
 	}
 
+	// Position of the parsed entries, only needed to sort by appearance
+	var positions map[string]int
+	if do.marshalOptions.Sort == SortByAppearance {
+		positions = make(map[string]int, len(do.order))
+		for i, key := range do.order {
+			positions[key] = i
+		}
+	}
+
 	sort.Slice(references, func(i, j int) bool {
+		switch do.marshalOptions.Sort {
+		case SortByAppearance:
+			iPos, iOk := positions[orderKey(references[i].context, references[i].trans.ID)]
+			jPos, jOk := positions[orderKey(references[j].context, references[j].trans.ID)]
+			if iOk && jOk {
+				return iPos < jPos
+			}
+			if iOk != jOk {
+				return iOk
+			}
+		case SortByID:
+			if references[i].context != references[j].context {
+				return references[i].context < references[j].context
+			}
+			return references[i].trans.ID < references[j].trans.ID
+		}
+
 		if references[i].path < references[j].path {
 			return true
 		}
@@ -570,6 +1698,9 @@ func (do *Domain) MarshalText() ([]byte, error) {
 		if references[i].line > references[j].line {
 			return false
 		}
+		if references[i].col != references[j].col {
+			return references[i].col < references[j].col
+		}
 
 		if references[i].context < references[j].context {
 			return true
@@ -580,31 +1711,138 @@ func (do *Domain) MarshalText() ([]byte, error) {
 		return references[i].trans.ID < references[j].trans.ID
 	})
 
+	// Comments on the numbers covered by each plural form
+	var formComments []string
+	if do.pluralComments {
+		formComments = do.pluralFormComments()
+	}
+
+	// Languages with a single form only get msgstr[0]
+	singleForm := do.PluralCount() == 1
+
 	for _, ref := range references {
-		trans := ref.trans
-		if len(trans.Refs) > 0 {
-			buf.WriteString("\n\n#: " + strings.Join(trans.Refs, " "))
-		} else {
-			buf.WriteByte(byte('\n'))
+		writeEntry(&buf, ref.context, ref.trans, false, singleForm, formComments)
+	}
+
+	// Obsolete entries go last, ordered by context and ID
+	obsContexts := make([]string, 0, len(do.obsoleteTranslations))
+	for name := range do.obsoleteTranslations {
+		obsContexts = append(obsContexts, name)
+	}
+	sort.Strings(obsContexts)
+
+	for _, name := range obsContexts {
+		ids := make([]string, 0, len(do.obsoleteTranslations[name]))
+		for id := range do.obsoleteTranslations[name] {
+			ids = append(ids, id)
 		}
+		sort.Strings(ids)
 
-		if ref.context == "" {
-			buf.WriteString("\nmsgid \"" + trans.ID + "\"")
-		} else {
-			buf.WriteString("\nmsgctxt \"" + ref.context + "\"\nmsgid \"" + trans.ID + "\"")
+		for _, id := range ids {
+			writeEntry(&buf, name, do.obsoleteTranslations[name][id], true, singleForm, nil)
 		}
+	}
 
-		if trans.PluralID == "" {
-			buf.WriteString("\nmsgstr \"" + trans.Trs[0] + "\"")
-		} else {
-			buf.WriteString("\nmsgid_plural \"" + trans.PluralID + "\"")
-			for i, tr := range trans.Trs {
-				buf.WriteString("\nmsgstr[" + strconv.Itoa(i) + "] \"" + tr + "\"")
+	buf.WriteByte(byte('\n'))
+
+	return buf.Bytes(), nil
+}
+
+// writeEntry writes a translation preceded by its comments in the order used by GNU gettext tools.
+// Obsolete entries get their msg* lines commented out with "#~". Plural entries only get their first form
+// when singleForm is set.
+func writeEntry(buf *bytes.Buffer, context string, trans *Translation, obsolete, singleForm bool, formComments []string) {
+	prefix := ""
+	prevPrefix := "#|"
+	if obsolete {
+		prefix = "#~ "
+		prevPrefix = "#~|"
+	}
+
+	trans.load()
+	buf.WriteByte(byte('\n'))
+	for _, c := range trans.Comments {
+		writeComment(buf, "#", c)
+	}
+	if trans.PluralID != "" {
+		for i, comment := range formComments {
+			if _, ok := trans.Trs[i]; ok && comment != "" {
+				buf.WriteString("\n# " + comment)
 			}
 		}
 	}
+	for _, c := range trans.ExtractedComments {
+		writeComment(buf, "#.", c)
+	}
+	if len(trans.Refs) > 0 {
+		buf.WriteString("\n#: " + strings.Join(trans.Refs, " "))
+	}
+	if len(trans.Flags) > 0 {
+		buf.WriteString("\n#, " + strings.Join(trans.Flags, ", "))
+	}
+	for _, c := range trans.Previous {
+		writeComment(buf, prevPrefix, c)
+	}
 
-	return buf.Bytes(), nil
+	if context != "" {
+		buf.WriteString("\n" + prefix + "msgctxt " + poQuote(context))
+	}
+	buf.WriteString("\n" + prefix + "msgid " + poQuote(trans.ID))
+
+	if trans.PluralID == "" {
+		buf.WriteString("\n" + prefix + "msgstr " + poQuote(trans.Trs[0]))
+	} else {
+		buf.WriteString("\n" + prefix + "msgid_plural " + poQuote(trans.PluralID))
+
+		// Plural forms in index order
+		idxs := make([]int, 0, len(trans.Trs))
+		for i := range trans.Trs {
+			idxs = append(idxs, i)
+		}
+		sort.Ints(idxs)
+		if singleForm {
+			idxs = []int{0}
+		}
+
+		for _, i := range idxs {
+			buf.WriteString("\n" + prefix + "msgstr[" + strconv.Itoa(i) + "] " + poQuote(trans.Trs[i]))
+		}
+	}
+}
+
+// poQuote quotes str as a PO string: only backslashes, double quotes, newlines, tabs and carriage returns are escaped,
+// other characters (i.e. non-breaking spaces or soft hyphens) are written as is, since GNU gettext tools reject \u escapes.
+func poQuote(str string) string {
+	var b strings.Builder
+	b.Grow(len(str) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeComment writes a comment line, skipping the separator space for empty comments
+func writeComment(buf *bytes.Buffer, prefix, comment string) {
+	if comment == "" {
+		buf.WriteString("\n" + prefix)
+	} else {
+		buf.WriteString("\n" + prefix + " " + comment)
+	}
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler interface
@@ -618,6 +1856,16 @@ func (do *Domain) MarshalBinary() ([]byte, error) {
 	obj.Translations = do.translations
 	obj.Contexts = do.contexts
 
+	// Translations parsed by a lazy Mo are encoded with their msgstr
+	for _, trans := range do.translations {
+		trans.load()
+	}
+	for _, translations := range do.contexts {
+		for _, trans := range translations {
+			trans.load()
+		}
+	}
+
 	var buff bytes.Buffer
 	encoder := gob.NewEncoder(&buff)
 	err := encoder.Encode(obj)
@@ -2,6 +2,11 @@ package gotext
 
 import (
 	"embed"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -10,7 +15,32 @@ var (
 	enUSFixture embed.FS
 )
 
-//since both Po and Mo just pass-through to Domain for MarshalBinary and UnmarshalBinary, test it here
+func TestBinaryEncodingWithoutRegistration(t *testing.T) {
+	// The package doesn't register its types, so applications are free to use their names
+	gob.RegisterName("gotext.TranslatorEncoding", &struct{ Name string }{})
+
+	po, err := NewPoFromString(`msgid "My text"
+msgstr "Translated text"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buff, err := po.GetDomain().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	po2 := NewPo()
+	if err = po2.GetDomain().UnmarshalBinary(buff); err != nil {
+		t.Fatal(err)
+	}
+	if tr := po2.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+}
+
+// Po just passes through to Domain for MarshalBinary and UnmarshalBinary, so test it here.
+// Mo uses the .mo format instead, see TestMoBinaryEncoding.
 func TestBinaryEncoding(t *testing.T) {
 	// Create po objects
 	po := NewPo()
@@ -65,20 +95,1561 @@ func TestDomain_GetTranslations(t *testing.T) {
 		if all[k] == v {
 			t.Error("GetTranslations should be returning a copy, but pointers are equal")
 		}
-		if all[k].ID != v.ID {
-			t.Error("IDs should match")
-		}
-		if all[k].PluralID != v.PluralID {
-			t.Error("PluralIDs should match")
+		if !all[k].Equal(v) {
+			t.Errorf("Translation '%s' should be equal to its copy", k)
 		}
 		if all[k].dirty != v.dirty {
 			t.Error("dirty flag should match")
 		}
-		if len(all[k].Trs) != len(v.Trs) {
-			t.Errorf("Trs length does not match: %d != %d", len(all[k].Trs), len(v.Trs))
+	}
+}
+
+func TestDomain_ForEach(t *testing.T) {
+	po := NewPo()
+
+	f, err := enUSFixture.Open("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po.ParseFile(f)
+
+	domain := po.GetDomain()
+
+	seen := make(map[string]bool)
+	domain.ForEach(func(id string, trans *Translation) bool {
+		if domain.translations[id] != trans {
+			t.Errorf("ForEach should pass the stored Translation for '%s'", id)
+		}
+		seen[id] = true
+		return true
+	})
+	if len(seen) != len(domain.translations) {
+		t.Errorf("Expected %d translations but got %d", len(domain.translations), len(seen))
+	}
+
+	calls := 0
+	domain.ForEach(func(id string, trans *Translation) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Expected ForEach to stop after 1 call but got %d", calls)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		domain.ForEach(func(id string, trans *Translation) bool {
+			return true
+		})
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations but got %v", allocs)
+	}
+}
+
+func TestDomain_DropFuzzyTranslations(t *testing.T) {
+	po, err := NewPoFromString(`#, fuzzy
+msgid ""
+msgstr "Language: fr\n"
+
+msgid "One"
+msgstr "Un"
+
+#, fuzzy
+msgid "Two"
+msgstr "Deux"
+
+#, fuzzy, go-format
+msgctxt "menu"
+msgid "File %d"
+msgstr "Fichier %d"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := po.GetDomain()
+	if n := domain.DropFuzzyTranslations(); n != 2 {
+		t.Errorf("Expected 2 fuzzy entries but got %d", n)
+	}
+	if domain.IsTranslated("Two") || domain.IsTranslatedC("File %d", "menu") {
+		t.Error("Expected fuzzy entries to be untranslated")
+	}
+	if !domain.IsTranslated("One") || domain.Language != "fr" {
+		t.Error("Expected other entries and the header to be kept")
+	}
+
+	entries := make(map[string]bool)
+	domain.ForEachC(func(ctx, id string, trans *Translation) bool {
+		entries[ctx+"|"+id] = true
+		return true
+	})
+	for _, key := range []string{"|", "|One", "|Two", "menu|File %d"} {
+		if !entries[key] {
+			t.Errorf("Expected ForEachC to pass '%s'", key)
+		}
+	}
+}
+
+func TestDomain_ContextsFor(t *testing.T) {
+	po, err := NewPoFromString(`msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "toolbar"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir..."
+
+msgctxt "menu"
+msgid "Close"
+msgstr "Fermer"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := po.GetDomain()
+	tests := map[string][]string{
+		"Open":    {"", "menu", "toolbar"},
+		"Close":   {"menu"},
+		"Missing": nil,
+	}
+	for id, expected := range tests {
+		if contexts := domain.ContextsFor(id); strings.Join(contexts, ",") != strings.Join(expected, ",") || len(contexts) != len(expected) {
+			t.Errorf("%s: expected '%v' but got '%v'", id, expected, contexts)
+		}
+	}
+}
+
+func TestDomain_SplitByContext(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+#. Shown on the cart page
+#, go-format
+msgctxt "cart"
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d article"
+msgstr[1] "%d articles"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "default"
+msgid "Hello"
+msgstr "Salut"
+
+#~ msgctxt "menu"
+#~ msgid "Close"
+#~ msgstr "Fermer"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domains := po.GetDomain().SplitByContext()
+	if len(domains) != 3 {
+		t.Fatalf("Expected 3 domains but got %d", len(domains))
+	}
+
+	if tr := domains["default"].Get("Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if tr := domains["default"].GetC("Hello", "default"); tr != "Salut" {
+		t.Errorf("Expected 'Salut' but got '%s'", tr)
+	}
+	if tr := domains["menu"].Get("Open"); tr != "Ouvrir" {
+		t.Errorf("Expected 'Ouvrir' but got '%s'", tr)
+	}
+	if tr := domains["menu"].Get("Hello"); tr != "Hello" {
+		t.Errorf("Expected 'Hello' but got '%s'", tr)
+	}
+
+	cart := domains["cart"]
+	if tr := cart.GetN("%d item", "%d items", 0, 0); tr != "0 article" {
+		t.Errorf("Expected '0 article' but got '%s'", tr)
+	}
+	if tr := cart.GetN("%d item", "%d items", 2, 2); tr != "2 articles" {
+		t.Errorf("Expected '2 articles' but got '%s'", tr)
+	}
+	if cart.Language != "fr" || cart.GetHeader("Plural-Forms") == "" {
+		t.Errorf("Expected headers to be carried over, got '%s'", cart.Language)
+	}
+	trans := cart.translations["%d item"]
+	if len(trans.ExtractedComments) != 1 || len(trans.Flags) != 1 || trans.Flags[0] != "go-format" {
+		t.Errorf("Expected comments to be carried over, got %v %v", trans.ExtractedComments, trans.Flags)
+	}
+	if trans == po.GetDomain().contexts["cart"]["%d item"] {
+		t.Error("Expected entries to be copied")
+	}
+
+	text, err := domains["menu"].MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "#~ msgid \"Close\"") || strings.Contains(string(text), "msgctxt") {
+		t.Errorf("Unexpected output:\n%s", text)
+	}
+}
+
+func TestDomain_SubsetByPrefix(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "checkout.title"
+msgstr "Paiement"
+
+msgid "account.title"
+msgstr "Compte"
+
+msgid "checkout.items"
+msgid_plural "checkout.items.plural"
+msgstr[0] "%d article"
+msgstr[1] "%d articles"
+
+msgctxt "checkout.summary"
+msgid "Total"
+msgstr "Total TTC"
+
+msgctxt "menu"
+msgid "checkout.open"
+msgstr "Payer"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#~ msgid "checkout.old"
+#~ msgstr "Ancien"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subset := po.GetDomain().SubsetByPrefix("checkout.")
+	if tr := subset.Get("checkout.title"); tr != "Paiement" {
+		t.Errorf("Expected 'Paiement' but got '%s'", tr)
+	}
+	if tr := subset.Get("account.title"); tr != "account.title" {
+		t.Errorf("Expected 'account.title' but got '%s'", tr)
+	}
+	if tr := subset.GetN("checkout.items", "checkout.items.plural", 2, 2); tr != "2 articles" {
+		t.Errorf("Expected '2 articles' but got '%s'", tr)
+	}
+	if tr := subset.GetC("Total", "checkout.summary"); tr != "Total TTC" {
+		t.Errorf("Expected 'Total TTC' but got '%s'", tr)
+	}
+	if tr := subset.GetC("checkout.open", "menu"); tr != "Payer" {
+		t.Errorf("Expected 'Payer' but got '%s'", tr)
+	}
+	if tr := subset.GetC("Open", "menu"); tr != "Open" {
+		t.Errorf("Expected 'Open' but got '%s'", tr)
+	}
+	if subset.Language != "fr" || subset.GetHeader("Plural-Forms") == "" {
+		t.Errorf("Expected headers to be carried over, got '%s'", subset.Language)
+	}
+	if subset.translations["checkout.title"] == po.GetDomain().translations["checkout.title"] {
+		t.Error("Expected entries to be copied")
+	}
+
+	text, err := subset.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`msgid "checkout.title"`, `msgid "checkout.items"`, `msgctxt "checkout.summary"`, `msgid "checkout.open"`}
+	last := -1
+	for _, str := range expected {
+		idx := strings.Index(string(text), str)
+		if idx <= last {
+			t.Errorf("Expected '%s' in parse order in:\n%s", str, text)
+		}
+		last = idx
+	}
+	if strings.Contains(string(text), "account") || strings.Contains(string(text), "checkout.old") {
+		t.Errorf("Unexpected output:\n%s", text)
+	}
+}
+
+func TestDomain_SetRefs(t *testing.T) {
+	domain := NewDomain()
+	domain.Set("Hello", "Bonjour")
+	domain.SetRefs("Hello", []string{"main.go:10", "cms:home", "main.go:10"})
+	domain.AddRef("Hello", "cms:footer")
+	domain.AddRef("Hello", "cms:home")
+	domain.AddRef("Bye", "main.go:20")
+
+	expected := []string{"main.go:10", "cms:home", "cms:footer"}
+	if refs := domain.GetRefs("Hello"); strings.Join(refs, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected '%v' but got '%v'", expected, refs)
+	}
+
+	trans := NewTranslation()
+	trans.AddRef("a.go:1")
+	trans.AddRef("a.go:1")
+	if trans.IsStale() || len(trans.Refs) != 1 {
+		t.Errorf("Expected one ref on a dirty Translation but got '%v'", trans.Refs)
+	}
+
+	text, err := domain.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"#: main.go:10 cms:home cms:footer\nmsgid \"Hello\"", "#: main.go:20\nmsgid \"Bye\""} {
+		if !strings.Contains(string(text), line) {
+			t.Errorf("Expected '%s' in:\n%s", line, text)
+		}
+	}
+}
+
+func TestDomain_SetAliases(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "new.title"
+msgstr "Title"
+
+msgid "new.items"
+msgid_plural "new.items"
+msgstr[0] "One item"
+msgstr[1] "%d items"
+
+msgctxt "menu"
+msgid "new.open"
+msgstr "Open"
+
+msgid "old.direct"
+msgstr "Direct"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := po.GetDomain()
+	domain.SetAliases(map[string]string{
+		"old.title":  "new.title",
+		"old.items":  "new.items",
+		"old.open":   "new.open",
+		"old.direct": "new.title",
+		"old.chain":  "old.title",
+	})
+
+	tests := map[string][2]string{
+		"alias":       {domain.Get("old.title"), "Title"},
+		"plural":      {domain.GetN("old.items", "old.items", 3, 3), "3 items"},
+		"context":     {domain.GetC("old.open", "menu"), "Open"},
+		"direct hit":  {domain.Get("old.direct"), "Direct"},
+		"no context":  {domain.GetC("old.title", "menu"), "old.title"},
+		"not chained": {domain.Get("old.chain"), "old.chain"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+	if !domain.IsTranslated("old.title") || !domain.IsTranslatedNC("old.open", 1, "menu") {
+		t.Error("Expected aliases to be translated")
+	}
+
+	domain.SetAliases(nil)
+	if tr := domain.Get("old.title"); tr != "old.title" {
+		t.Errorf("Expected 'old.title' but got '%s'", tr)
+	}
+}
+
+func TestTranslation_Equal(t *testing.T) {
+	a := NewTranslationWithRefs([]string{"main.go:1"})
+	a.ID = "id"
+	a.PluralID = "ids"
+	a.SetN(0, "one")
+	a.SetN(1, "many")
+	a.Flags = []string{"go-format"}
+
+	b := NewTranslationWithRefs([]string{"main.go:1"})
+	b.ID = "id"
+	b.PluralID = "ids"
+	b.Trs[0] = "one"
+	b.Trs[1] = "many"
+	b.Flags = []string{"go-format"}
+
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("Translations should be equal")
+	}
+
+	b.Trs[1] = "other"
+	if a.Equal(b) {
+		t.Error("Translations with different forms should not be equal")
+	}
+	b.Trs[1] = "many"
+
+	b.Comments = []string{"translator note"}
+	if a.Equal(b) {
+		t.Error("Translations with different comments should not be equal")
+	}
+
+	if a.Equal(nil) {
+		t.Error("Translation should not be equal to nil")
+	}
+}
+
+func TestDomain_GetRange(t *testing.T) {
+	po := NewPo()
+
+	f, err := enUSFixture.Open("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po.ParseFile(f)
+
+	domain := po.GetDomain()
+
+	tr := domain.GetRange("One with var: %s", "Several with vars: %s", 1, 5, "Test")
+	if tr != "This one is the plural: Test" {
+		t.Errorf("Expected 'This one is the plural: Test' but got '%s'", tr)
+	}
+
+	// Falls back to the end value's form
+	tr = domain.GetRange("One with var: %s", "Several with vars: %s", 0, 1, "Test")
+	if tr != "This one is the singular: Test" {
+		t.Errorf("Expected 'This one is the singular: Test' but got '%s'", tr)
+	}
+}
+
+func TestDomain_PluralCategory(t *testing.T) {
+	for lang, rule := range defaultPluralRules {
+		if len(rule.categories) != rule.nplurals {
+			t.Errorf("Expected %d categories for '%s' but got %d", rule.nplurals, lang, len(rule.categories))
+		}
+	}
+
+	domain := NewDomain()
+	for _, test := range []struct {
+		lang       string
+		categories map[int]string
+	}{
+		{"", map[int]string{0: "other", 1: "one", 2: "other"}},
+		{"en_US", map[int]string{0: "other", 1: "one", 2: "other"}},
+		{"fr", map[int]string{0: "one", 1: "one", 2: "other"}},
+		{"ja", map[int]string{1: "other", 5: "other"}},
+		{"ru", map[int]string{1: "one", 2: "few", 5: "many", 11: "many", 21: "one", 22: "few"}},
+		{"cs", map[int]string{1: "one", 3: "few", 5: "other"}},
+		{"ar", map[int]string{0: "zero", 1: "one", 2: "two", 3: "few", 11: "many", 100: "other"}},
+		{"ga", map[int]string{0: "other", 1: "one", 2: "two", 3: "few", 6: "few", 7: "many", 10: "many", 11: "other"}},
+	} {
+		domain.Language = test.lang
+		for n, expected := range test.categories {
+			if category := domain.PluralCategory(n); category != expected {
+				t.Errorf("Expected '%s' for n=%d in '%s' but got '%s'", expected, n, test.lang, category)
+			}
+		}
+	}
+
+	// The Plural-Forms header doesn't change the categories
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if category := po.GetDomain().PluralCategory(5); category != "many" {
+		t.Errorf("Expected 'many' but got '%s'", category)
+	}
+}
+
+func TestDomain_PluralFormula(t *testing.T) {
+	po := NewPo()
+	po.Parse([]byte(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+`))
+
+	expected := "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)"
+	if formula := po.GetDomain().PluralFormula(); formula != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, formula)
+	}
+
+	if formula := NewDomain().PluralFormula(); formula != "" {
+		t.Errorf("Expected empty formula but got '%s'", formula)
+	}
+}
+
+func TestDomain_PluralIndex(t *testing.T) {
+	po := NewPo()
+	f, err := enUSFixture.Open("fixtures/ar/categories.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po.ParseFile(f)
+
+	domain := po.GetDomain()
+	tests := map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 10: 3, 11: 4, 99: 4, 100: 5, 102: 5, 103: 3}
+	for n, expected := range tests {
+		if idx := domain.PluralIndex(n); idx != expected {
+			t.Errorf("Expected index %d for n=%d but got %d", expected, n, idx)
+		}
+	}
+
+	// Germanic fallback without Plural-Forms header
+	domain = NewDomain()
+	if idx := domain.PluralIndex(1); idx != 0 {
+		t.Errorf("Expected index 0 for n=1 but got %d", idx)
+	}
+	if idx := domain.PluralIndex(5); idx != 1 {
+		t.Errorf("Expected index 1 for n=5 but got %d", idx)
+	}
+}
+
+func TestDomain_XLIFF(t *testing.T) {
+	po := NewPo()
+	f, err := enUSFixture.Open("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po.ParseFile(f)
+
+	data, err := po.GetDomain().MarshalXLIFF()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{
+		`<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">`,
+		`<file original="messages" source-language="en" target-language="en-US" datatype="po">`,
+		`<source>My text</source>`,
+		`<target>Translated text</target>`,
+		`<group id="`,
+		`<context context-type="x-gettext-msgctxt">Ctx</context>`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected '%s' in output:\n%s", expected, data)
+		}
+	}
+
+	domain := NewDomain()
+	if err = domain.UnmarshalXLIFF(data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if tr := domain.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+	if tr := domain.GetN("One with var: %s", "Several with vars: %s", 2, "v"); tr != "This one is the plural: v" {
+		t.Errorf("Expected 'This one is the plural: v' but got '%s'", tr)
+	}
+	if tr := domain.GetC("Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+		t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+	}
+	if domain.IsTranslated("Empty translation") {
+		t.Error("Expected 'Empty translation' to stay untranslated")
+	}
+
+	// Vendor return updating an existing domain
+	err = po.GetDomain().UnmarshalXLIFF([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file original="messages" source-language="en" target-language="en-US" datatype="po">
+    <body>
+      <trans-unit id="a">
+        <source>My text</source>
+        <target>Vendor text</target>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := po.Get("My text"); tr != "Vendor text" {
+		t.Errorf("Expected 'Vendor text' but got '%s'", tr)
+	}
+
+	if err = domain.UnmarshalXLIFF([]byte("<xliff>")); !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+}
+
+func TestDomain_CSV(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: pl\n"
+"Plural-Forms: nplurals=3; plural=(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+# First line
+# "quoted", with comma
+msgid "Hello, world"
+msgstr "Witaj, \"świecie\""
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d plik"
+msgstr[1] "%d pliki"
+msgstr[2] "%d plików"
+
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+
+msgid "Multi"
+msgstr "Line one\nLine two"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := po.GetDomain().MarshalCSV()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Line breaks in cells are written as CRLF too, and read back as "\n"
+	expected := "context,msgid,msgid_plural,msgstr,msgstr[1],msgstr[2],comments\r\n" +
+		",%d file,%d files,%d plik,%d pliki,%d plików,\r\n" +
+		",\"Hello, world\",,\"Witaj, \"\"świecie\"\"\",,,\"First line\r\n\"\"quoted\"\", with comma\"\r\n" +
+		",Multi,,\"Line one\r\nLine two\",,,\r\n" +
+		"menu,Open,,,,,\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, data)
+	}
+
+	domain := NewDomain()
+	if err = domain.UnmarshalCSV(data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for id, trans := range po.GetDomain().GetTranslations() {
+		if id == "" {
+			continue
 		}
-		if len(all[k].Refs) != len(v.Refs) {
-			t.Errorf("Refs length does not match: %d != %d", len(all[k].Refs), len(v.Refs))
+		if other := domain.GetTranslations()[id]; other == nil || !equalStrings(trans.Comments, other.Comments) ||
+			trans.PluralID != other.PluralID || len(trans.Trs) != len(other.Trs) || trans.Get() != other.Get() {
+			t.Errorf("Expected the same Translation for '%s' but got %+v", id, other)
 		}
 	}
+	if tr := domain.GetNC("%d file", "%d files", 5, "", 5); tr == "" {
+		t.Error("Expected a plural Translation")
+	}
+	if domain.IsTranslatedC("Open", "menu") || domain.GetC("Open", "menu") != "Open" {
+		t.Error("Expected 'Open' to be loaded untranslated in its context")
+	}
+
+	// Spreadsheet edits with reordered columns and no comments column
+	err = po.GetDomain().UnmarshalCSV([]byte("msgstr,msgid,context\nOtwórz,Open,menu\nNowy,New,\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := po.GetC("Open", "menu"); tr != "Otwórz" {
+		t.Errorf("Expected 'Otwórz' but got '%s'", tr)
+	}
+	if tr := po.Get("New"); tr != "Nowy" {
+		t.Errorf("Expected 'Nowy' but got '%s'", tr)
+	}
+	if comments := po.GetDomain().GetTranslations()["Hello, world"].Comments; len(comments) != 2 {
+		t.Errorf("Expected comments to be kept but got '%v'", comments)
+	}
+
+	for _, data := range []string{"", "msgid,comments\nA,B\n", "msgid,msgstr\n\"A,B\n"} {
+		if err = domain.UnmarshalCSV([]byte(data)); !errors.Is(err, ErrParse) {
+			t.Errorf("Expected ErrParse for '%s' but got '%v'", data, err)
+		}
+	}
+}
+
+func TestDefaultPluralRule(t *testing.T) {
+	for lang := range defaultPluralRules {
+		if _, _, ok := DefaultPluralRule(lang); !ok {
+			t.Errorf("Expected a valid built-in rule for '%s'", lang)
+		}
+	}
+
+	tests := []struct {
+		lang     string
+		nplurals int
+		forms    map[int]int
+	}{
+		{"en_US", 2, map[int]int{0: 1, 1: 0, 2: 1}},
+		{"fr", 2, map[int]int{0: 0, 1: 0, 2: 1}},
+		{"pt_BR", 2, map[int]int{0: 0, 1: 0, 2: 1}},
+		{"pt-PT", 2, map[int]int{0: 1, 1: 0, 2: 1}},
+		{"ru", 3, map[int]int{1: 0, 2: 1, 5: 2, 11: 2, 21: 0, 22: 1, 111: 2}},
+		{"pl", 3, map[int]int{1: 0, 2: 1, 5: 2, 21: 2, 22: 1}},
+		{"ja.UTF-8", 1, map[int]int{1: 0, 5: 0}},
+		{"ar", 6, map[int]int{0: 0, 1: 1, 2: 2, 3: 3, 11: 4, 100: 5}},
+	}
+	for _, test := range tests {
+		nplurals, fn, ok := DefaultPluralRule(test.lang)
+		if !ok {
+			t.Errorf("Expected a built-in rule for '%s'", test.lang)
+			continue
+		}
+		if nplurals != test.nplurals {
+			t.Errorf("Expected %d plural forms for '%s' but got %d", test.nplurals, test.lang, nplurals)
+		}
+		for n, expected := range test.forms {
+			if idx := fn(n); idx != expected {
+				t.Errorf("Expected index %d for n=%d in '%s' but got %d", expected, n, test.lang, idx)
+			}
+		}
+	}
+
+	if _, _, ok := DefaultPluralRule("xx"); ok {
+		t.Error("Expected no built-in rule for 'xx'")
+	}
+
+	// Header wins over the built-in rule, which is used when it's missing
+	po, err := NewPoFromString(`
+msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if idx := po.GetDomain().PluralIndex(5); idx != 1 {
+		t.Errorf("Expected index 1 but got %d", idx)
+	}
+
+	po, err = NewPoFromString(`
+msgid ""
+msgstr ""
+"Language: ru\n"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if idx := po.GetDomain().PluralIndex(5); idx != 2 {
+		t.Errorf("Expected index 2 but got %d", idx)
+	}
+}
+
+func TestDomain_Pseudolocalize(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid ""
+msgstr ""
+"Language: en\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello"
+msgstr "Hello"
+
+msgid "Hello %s, you have {count} messages"
+msgstr ""
+
+msgid "One file"
+msgid_plural "%[1]d files"
+msgstr[0] ""
+msgstr[1] ""
+
+msgctxt "Ctx"
+msgid "Hello"
+msgstr "Hi %(name)s"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	pseudo := po.GetDomain().Pseudolocalize(PseudoOptions{Accents: true, Expansion: 0.8, Brackets: true})
+
+	if tr := pseudo.Get("Hello"); tr != "[Ħëłłö !!!]" {
+		t.Errorf("Expected '[Ħëłłö !!!]' but got '%s'", tr)
+	}
+	if tr := pseudo.GetN("One file", "%[1]d files", 3); tr != "[%[1]d ƒïłëš !!!!!!!!]" {
+		t.Errorf("Expected '[%%[1]d ƒïłëš !!!!!!!!]' but got '%s'", tr)
+	}
+	if tr := pseudo.GetC("Hello", "Ctx"); tr != "[Ħï %(name)s !!!!!!!!]" {
+		t.Errorf("Expected '[Ħï %%(name)s !!!!!!!!]' but got '%s'", tr)
+	}
+
+	pseudo = po.GetDomain().Pseudolocalize(PseudoOptions{Accents: true})
+	if tr := pseudo.Get("Hello %s, you have {count} messages", "Ann"); tr != "Ħëłłö Ann, ýöü ħåṽë {count} ṁëššåĝëš" {
+		t.Errorf("Expected 'Ħëłłö Ann, ýöü ħåṽë {count} ṁëššåĝëš' but got '%s'", tr)
+	}
+
+	// Source domain is untouched
+	if tr := po.Get("Hello"); tr != "Hello" {
+		t.Errorf("Expected 'Hello' but got '%s'", tr)
+	}
+}
+
+func TestDomain_UpdateMetadata(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Language: fr\n"
+
+#. Greeting
+#: old.go:1
+#, fuzzy
+msgid "Hello"
+msgstr "Bonjour"
+
+#: old.go:2
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#: old.go:3
+msgid "Removed"
+msgstr "Supprimé"
+
+#~ msgid "Back"
+#~ msgstr "Retour"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pot, err := NewPoFromString(`msgid ""
+msgstr ""
+
+#. Shown on the home page
+#: home.go:10 home.go:20
+msgid "Hello"
+msgstr ""
+
+#: menu.go:5
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+
+#: back.go:1
+msgid "Back"
+msgstr ""
+
+#: new.go:1
+msgid "New"
+msgstr ""
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := po.GetDomain()
+	domain.UpdateMetadata(pot.GetDomain())
+
+	if tr := domain.Get("Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+	if domain.IsTranslated("Removed") {
+		t.Error("Expected 'Removed' to be obsolete")
+	}
+	if tr := domain.Get("Back"); tr != "Retour" {
+		t.Errorf("Expected 'Retour' but got '%s'", tr)
+	}
+	if domain.IsTranslated("New") {
+		t.Error("Expected 'New' not to be added")
+	}
+
+	text, err := domain.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{
+		"#. Shown on the home page\n#: home.go:10 home.go:20\n#, fuzzy\nmsgid \"Hello\"\nmsgstr \"Bonjour\"",
+		"#: menu.go:5\nmsgctxt \"menu\"\nmsgid \"Open\"\nmsgstr \"Ouvrir\"",
+		"#: back.go:1\nmsgid \"Back\"\nmsgstr \"Retour\"",
+		"#~ msgid \"Removed\"\n#~ msgstr \"Supprimé\"",
+	} {
+		if !strings.Contains(string(text), part) {
+			t.Errorf("Expected '%s' in:\n%s", part, text)
+		}
+	}
+	if strings.Contains(string(text), "old.go:1") || strings.Contains(string(text), "old.go:2") {
+		t.Errorf("Expected old references to be replaced in:\n%s", text)
+	}
+}
+
+func TestDomain_UpdateMetadataConcurrent(t *testing.T) {
+	newDomain := func(ref string) *Domain {
+		do := NewDomain()
+		do.Set("Hello", "Bonjour")
+		do.SetC("Open", "menu", "Ouvrir")
+		do.SetRefs("Hello", []string{ref})
+		return do
+	}
+	a, b := newDomain("a.go:1"), newDomain("b.go:1")
+
+	// Updating two domains from each other at once must not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.UpdateMetadata(b)
+		}()
+		go func() {
+			defer wg.Done()
+			b.UpdateMetadata(a)
+		}()
+	}
+	wg.Wait()
+
+	if refs := a.GetRefs("Hello"); len(refs) != 1 || (refs[0] != "a.go:1" && refs[0] != "b.go:1") {
+		t.Errorf("Expected a single reference but got %v", refs)
+	}
+	if !a.IsTranslatedC("Open", "menu") || !b.IsTranslatedC("Open", "menu") {
+		t.Error("Expected the context entries to be kept")
+	}
+}
+
+func TestDomain_Revive(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+
+msgid "Hello"
+msgstr "Bonjour"
+
+#~ msgid "Removed"
+#~ msgstr "Supprimé"
+
+#~ msgctxt "menu"
+#~ msgid "Close"
+#~ msgstr "Fermer"
+
+#~ msgid "Hello"
+#~ msgstr "Salut"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	if domain.Revive("Missing") {
+		t.Error("Expected no entry to revive")
+	}
+	if domain.Revive("Hello") {
+		t.Error("Expected the active entry to be kept")
+	}
+	if tr := domain.Get("Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+
+	if !domain.Revive("Removed") {
+		t.Error("Expected 'Removed' to be revived")
+	}
+	if tr := domain.Get("Removed"); tr != "Supprimé" {
+		t.Errorf("Expected 'Supprimé' but got '%s'", tr)
+	}
+	if domain.Revive("Removed") {
+		t.Error("Expected 'Removed' to be revived once")
+	}
+
+	if domain.Revive("Close") {
+		t.Error("Expected 'Close' to be revived in its context only")
+	}
+	if !domain.ReviveC("Close", "menu") {
+		t.Error("Expected 'Close' to be revived")
+	}
+	if tr := domain.GetC("Close", "menu"); tr != "Fermer" {
+		t.Errorf("Expected 'Fermer' but got '%s'", tr)
+	}
+
+	text, err := domain.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "#~ msgid \"Removed\"") || strings.Contains(string(text), "#~ msgid \"Close\"") {
+		t.Errorf("Expected revived entries not to be obsolete in:\n%s", text)
+	}
+	if !strings.Contains(string(text), "\nmsgid \"Removed\"\nmsgstr \"Supprimé\"") {
+		t.Errorf("Expected 'Removed' to be active in:\n%s", text)
+	}
+}
+
+func TestDomain_MarshalTextSortOrder(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+
+#: b.go:2
+msgid "Zebra"
+msgstr "Zèbre"
+
+#: a.go:1
+msgctxt "animal"
+msgid "Bee"
+msgstr "Abeille"
+
+#: c.go:3
+msgid "Ant"
+msgstr "Fourmi"
+
+#: a.go:9
+msgid "Zebra"
+msgstr "Zèbre rayé"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+	domain.Set("Added", "Ajouté")
+
+	for _, tc := range []struct {
+		sort     SortOrder
+		expected []string
+	}{
+		{SortByAppearance, []string{`msgid "Zebra"`, `msgid "Bee"`, `msgid "Ant"`, `msgid "Added"`}},
+		{SortByReference, []string{`msgid "Added"`, `msgid "Bee"`, `msgid "Zebra"`, `msgid "Ant"`}},
+		{SortByID, []string{`msgid "Added"`, `msgid "Ant"`, `msgid "Zebra"`, `msgid "Bee"`}},
+	} {
+		domain.SetMarshalOptions(MarshalOptions{Sort: tc.sort})
+		text, err := domain.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for _, line := range strings.Split(string(text), "\n") {
+			if strings.HasPrefix(line, `msgid "`) && line != `msgid ""` {
+				ids = append(ids, line)
+			}
+		}
+		if strings.Join(ids, ", ") != strings.Join(tc.expected, ", ") {
+			t.Errorf("Expected '%s' but got '%s'", strings.Join(tc.expected, ", "), strings.Join(ids, ", "))
+		}
+	}
+}
+
+func TestDomain_MarshalTextColumnReferences(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+
+#: main.go:10:20
+msgid "Second"
+msgstr "Deuxième"
+
+#: main.go:10:5 util.go:3
+msgid "First"
+msgstr "Premier"
+
+#: main.go:2
+msgid "Zeroth"
+msgstr "Zéroième"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+	domain.SetMarshalOptions(MarshalOptions{Sort: SortByReference})
+
+	text, _ := domain.MarshalText()
+	expected := `
+#: main.go:2
+msgid "Zeroth"
+msgstr "Zéroième"
+
+#: main.go:10:5 util.go:3
+msgid "First"
+msgstr "Premier"
+
+#: main.go:10:20
+msgid "Second"
+msgstr "Deuxième"
+`
+	if !strings.HasSuffix(string(text), expected) {
+		t.Errorf("Expected the entries ordered by line and column in:\n%s", text)
+	}
+
+	for ref, want := range map[string][3]interface{}{
+		"main.go:10:5": {"main.go", 10, 5},
+		"main.go:10":   {"main.go", 10, 0},
+		"main.go":      {"main.go", 0, 0},
+	} {
+		path, line, col := extractReference(ref)
+		if path != want[0] || line != want[1] || col != want[2] {
+			t.Errorf("Expected %v for '%s' but got [%s %d %d]", want, ref, path, line, col)
+		}
+	}
+}
+
+func TestDomain_MarshalTextEscapes(t *testing.T) {
+	domain := NewDomain()
+	domain.Set("Price: %s", "Prix\u00a0: %s\u202f€")
+	domain.Set("Password", "Mot de pas\u00adse\u200b")
+	domain.Set("Quote \"%s\"", "Ligne 1\n\t\"%s\" \\ \r")
+
+	text, err := domain.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"msgstr \"Prix\u00a0: %s\u202f€\"",
+		"msgstr \"Mot de pas\u00adse\u200b\"",
+		`msgid "Quote \"%s\""`,
+		`msgstr "Ligne 1\n\t\"%s\" \\ \r"`,
+	} {
+		if !strings.Contains(string(text), expected) {
+			t.Errorf("Expected '%s' in:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(string(text), `\u`) {
+		t.Errorf("Expected no \\u escape in:\n%s", text)
+	}
+
+	po := NewPo()
+	if err = po.ParseBytes(text); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for id, expected := range map[string]string{
+		"Price: %s":    "Prix\u00a0: %s\u202f€",
+		"Password":     "Mot de pas\u00adse\u200b",
+		"Quote \"%s\"": "Ligne 1\n\t\"%s\" \\ \r",
+	} {
+		if tr := po.GetDomain().translations[id].Get(); tr != expected {
+			t.Errorf("Expected %q but got %q", expected, tr)
+		}
+	}
+}
+
+func TestDomain_MarshalTextPluralForms(t *testing.T) {
+	domain := NewDomain()
+	domain.Language = "ru"
+	domain.SetN("file", "files", 1, "файлов")
+
+	text, err := domain.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"`
+	if !strings.Contains(string(text), expected) {
+		t.Errorf("Expected '%s' in:\n%s", expected, text)
+	}
+
+	// The synthesized header is usable as is
+	po := NewPo()
+	po.Parse(text)
+	if nplurals := po.GetDomain().nplurals; nplurals != 3 {
+		t.Errorf("Expected 3 plural forms but got %d", nplurals)
+	}
+
+	// Language from the headers
+	domain = NewDomain()
+	domain.Headers.Set("Language", "ja")
+	if text, _ = domain.MarshalText(); !strings.Contains(string(text), `"Plural-Forms: nplurals=1; plural=0;\n"`) {
+		t.Errorf("Expected the Japanese plural forms in:\n%s", text)
+	}
+
+	// An explicit formula takes precedence
+	domain = NewDomain()
+	domain.Language = "ru"
+	domain.Headers.Set("Plural-Forms", "nplurals=2; plural=(n != 1);")
+	if text, _ = domain.MarshalText(); strings.Count(string(text), "Plural-Forms") != 1 || !strings.Contains(string(text), "plural=(n != 1);") {
+		t.Errorf("Expected the explicit plural forms in:\n%s", text)
+	}
+
+	// Unknown languages get no header
+	domain = NewDomain()
+	domain.Language = "xx"
+	if text, _ = domain.MarshalText(); strings.Contains(string(text), "Plural-Forms") {
+		t.Errorf("Expected no plural forms in:\n%s", text)
+	}
+}
+
+func TestDomain_SingleFormPlurals(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/zh/plurals.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po := NewPo()
+	po.Parse(data)
+
+	plural := "%d files"
+	for _, n := range []int{0, 1, 2, 5, 100} {
+		if expected := strconv.Itoa(n) + " 个文件"; po.GetN("One file", plural, n, n) != expected {
+			t.Errorf("Expected '%s' but got '%s'", expected, po.GetN("One file", plural, n, n))
+		}
+	}
+	if one, five := po.GetN("One folder", "%d folders", 1), po.GetN("One folder", "%d folders", 5); one != five {
+		t.Errorf("Expected the same form for 1 and 5 but got '%s' and '%s'", one, five)
+	}
+	if tr := po.GetNC("One item", "%d items", 5, "cart", 5); tr != "5 件商品" {
+		t.Errorf("Expected '5 件商品' but got '%s'", tr)
+	}
+
+	// Only msgstr[0] is written
+	text, err := po.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(text), "msgstr[1]") || strings.Count(string(text), "msgstr[0]") != 3 {
+		t.Errorf("Expected a single form per entry in:\n%s", text)
+	}
+
+	// The built-in rule of the language works the same without Plural-Forms header
+	domain := NewDomain()
+	domain.Language = "ja"
+	domain.SetN("One file", "%d files", 5, "%d ファイル")
+	domain.translations["One file"].SetN(1, "unused")
+	if one, five := domain.GetN("One file", "%d files", 1), domain.GetN("One file", "%d files", 5); one != "%d ファイル" || one != five {
+		t.Errorf("Expected '%%d ファイル' for 1 and 5 but got '%s' and '%s'", one, five)
+	}
+	if text, _ = domain.MarshalText(); strings.Contains(string(text), "msgstr[1]") {
+		t.Errorf("Expected a single form in:\n%s", text)
+	}
+}
+
+func TestDomain_SetPluralComments(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+
+msgid "Folder"
+msgstr "Папка"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	text, _ := domain.MarshalText()
+	if strings.Contains(string(text), "# form[") {
+		t.Errorf("Expected no plural comments by default in:\n%s", text)
+	}
+
+	domain.SetPluralComments(true)
+	text, _ = domain.MarshalText()
+	expected := `
+# form[0]: 1,21,31,...
+# form[1]: 2,3,4,...
+# form[2]: 0,5,6,...
+msgid "One file"
+`
+	if !strings.Contains(string(text), expected) {
+		t.Errorf("Expected '%s' in:\n%s", expected, text)
+	}
+	if strings.Count(string(text), "# form[") != 3 {
+		t.Errorf("Expected comments for the plural entry only in:\n%s", text)
+	}
+
+	// Skipped when parsed back
+	other := NewPo()
+	other.Parse(text)
+	for _, id := range []string{"One file", "Folder"} {
+		if trans := other.GetDomain().translations[id]; trans == nil || len(trans.Comments) != 0 {
+			t.Errorf("Expected '%s' without comments", id)
+		}
+	}
+	if again, _ := other.GetDomain().MarshalText(); strings.Contains(string(again), "# form[") {
+		t.Errorf("Expected no plural comments in:\n%s", again)
+	}
+
+	// A single form covers every number
+	po, _ = NewPoFromString(`msgid ""
+msgstr "Language: ja\n"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "%d ファイル"
+`)
+	domain = po.GetDomain()
+	domain.SetPluralComments(true)
+	if text, _ = domain.MarshalText(); !strings.Contains(string(text), "\n# form[0]: 0,1,2,...") {
+		t.Errorf("Expected the Japanese plural comment in:\n%s", text)
+	}
+}
+
+func TestDomain_SetEmptyStringPolicy(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Placeholder"
+msgstr ""
+
+msgid "One file"
+msgid_plural "Several files"
+msgstr[0] "Un fichier"
+msgstr[1] ""
+
+msgctxt "menu"
+msgid "Open"
+msgstr ""
+
+msgid "Done"
+msgstr "Fait"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	tests := func() map[string][2]string {
+		return map[string][2]string{
+			"Get":     {domain.Get("Placeholder"), "Placeholder"},
+			"GetN":    {domain.GetN("One file", "Several files", 2), "Several files"},
+			"GetN 1":  {domain.GetN("One file", "Several files", 1), "Un fichier"},
+			"GetC":    {domain.GetC("Open", "menu"), "Open"},
+			"GetNC":   {domain.GetNC("Open", "Open", 1, "menu"), "Open"},
+			"done":    {domain.Get("Done"), "Fait"},
+			"missing": {domain.Get("Missing"), "Missing"},
+		}
+	}
+	for name, tc := range tests() {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	domain.SetEmptyStringPolicy(ReturnEmpty)
+	expected := map[string]string{"Get": "", "GetN": "", "GetC": "", "GetNC": ""}
+	for name, tc := range tests() {
+		want := tc[1]
+		if empty, ok := expected[name]; ok {
+			want = empty
+		}
+		if tc[0] != want {
+			t.Errorf("%s: expected '%s' but got '%s'", name, want, tc[0])
+		}
+	}
+	if domain.IsTranslated("Placeholder") {
+		t.Error("Expected 'Placeholder' to be untranslated")
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.Domains["default"] = po
+	l.SetDomain("default")
+	if tr := l.Freeze().Get("Placeholder"); tr != "" {
+		t.Errorf("Expected '' but got '%s'", tr)
+	}
+}
+
+func TestDiffDomains(t *testing.T) {
+	old, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "Removed"
+msgstr "Supprimé"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgid "Same"
+msgstr "Pareil"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\nLanguage: fr\n"
+
+#, fuzzy
+msgid "Hello"
+msgstr "Salut"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d documents"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgctxt "toolbar"
+msgid "Open"
+msgstr "Ouvrir…"
+
+msgid "Same"
+msgstr "Pareil"
+
+#, fuzzy
+msgid "New"
+msgstr "Nouveau"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := DiffDomains(old.GetDomain(), old.GetDomain()); !diff.Empty() {
+		t.Errorf("Expected no change but got '%s'", diff)
+	}
+
+	diff := DiffDomains(old.GetDomain(), updated.GetDomain())
+	if summary := diff.String(); summary != "2 new strings, 2 translations changed, 1 became fuzzy, 1 removed" {
+		t.Errorf("Expected '2 new strings, 2 translations changed, 1 became fuzzy, 1 removed' but got '%s'", summary)
+	}
+
+	ids := func(list []EntryDiff) string {
+		var s []string
+		for _, entry := range list {
+			s = append(s, entry.Context+"|"+entry.ID)
+		}
+		return strings.Join(s, " ")
+	}
+	tests := map[string][2]string{
+		"added":   {ids(diff.Added), "|New toolbar|Open"},
+		"removed": {ids(diff.Removed), "|Removed"},
+		"changed": {ids(diff.Changed), "|Hello |One file"},
+		"fuzzy":   {ids(diff.BecameFuzzy), "|Hello"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	plural := diff.Changed[1]
+	if plural.Old[1] != "%d fichiers" || plural.New[1] != "%d documents" || plural.New[0] != "Un fichier" {
+		t.Errorf("Expected the old and new plural forms but got '%v' and '%v'", plural.Old, plural.New)
+	}
+	if diff.Added[0].Old != nil || diff.Removed[0].New != nil || diff.Removed[0].Old[0] != "Supprimé" {
+		t.Errorf("Expected no old translation for added entries and no new one for removed entries")
+	}
+}
+
+func TestDomain_ValidatePlurals(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Language: ru\n"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d файл"
+msgstr[1] "%d файла"
+msgstr[2] "%d файлов"
+
+msgid "%d day"
+msgid_plural "%d days"
+msgstr[0] "%d день"
+msgstr[1] "%d дня"
+
+msgctxt "mail"
+msgid "%d message"
+msgid_plural "%d messages"
+msgstr[0] "%d сообщение"
+msgstr[1] ""
+msgstr[2] "%d сообщений"
+
+msgid "%d user"
+msgid_plural "%d users"
+msgstr[0] ""
+msgstr[1] ""
+
+#, fuzzy
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d предмет"
+
+msgid "Hello"
+msgstr "Привет"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	// No Plural-Forms header, the built-in Russian rule is used
+	if n := domain.PluralCount(); n != 3 {
+		t.Errorf("Expected 3 plural forms but got %d", n)
+	}
+
+	errs := domain.ValidatePlurals()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors but got %v", errs)
+	}
+	if e := errs[0]; e.ID != "%d day" || e.Filled != 2 || e.Expected != 3 || e.Line != 10 {
+		t.Errorf("Expected '%%d day' with 2 of 3 forms at line 10 but got %+v", e)
+	}
+	expected := `gettext: msgid "%d message" in context "mail" has 2 plural forms but nplurals=3`
+	if msg := errs[1].Error(); msg != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, msg)
+	}
+
+	// The header takes precedence
+	po, err = NewPoFromString(`msgid ""
+msgstr ""
+"Language: ru\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "%d day"
+msgid_plural "%d days"
+msgstr[0] "%d день"
+msgstr[1] "%d дня"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, errs := po.GetDomain().PluralCount(), po.GetDomain().ValidatePlurals(); n != 2 || len(errs) != 0 {
+		t.Errorf("Expected 2 plural forms and no error but got %d and %v", n, errs)
+	}
+
+	if n := NewDomain().PluralCount(); n != 2 {
+		t.Errorf("Expected 2 plural forms but got %d", n)
+	}
+}
+
+func TestDomain_SetContextFallback(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Pay"
+msgstr "Payer"
+
+msgctxt "checkout"
+msgid "Pay"
+msgstr "Régler"
+
+msgctxt "checkout.button"
+msgid "Cancel"
+msgstr "Annuler l'achat"
+
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d article"
+msgstr[1] "%d articles"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	// Disabled by default
+	if tr := domain.GetC("Pay", "checkout.button"); tr != "Pay" {
+		t.Errorf("Expected 'Pay' but got '%s'", tr)
+	}
+
+	domain.SetContextFallback(".")
+	tests := map[string][2]string{
+		"exact":   {domain.GetC("Cancel", "checkout.button"), "Annuler l'achat"},
+		"parent":  {domain.GetC("Pay", "checkout.button.primary"), "Régler"},
+		"none":    {domain.GetC("Pay", "cart.button"), "Payer"},
+		"plural":  {domain.GetNC("%d item", "%d items", 3, "checkout.summary", 3), "3 articles"},
+		"missing": {domain.GetC("Back", "checkout.button"), "Back"},
+		"no sub":  {domain.GetC("Cancel", "checkout"), "Cancel"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+	if !domain.IsTranslatedC("Pay", "checkout.button") {
+		t.Error("Expected 'Pay' to be translated in 'checkout.button'")
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.Domains["default"] = po
+	l.SetDomain("default")
+	if tr := l.Freeze().GetC("Pay", "checkout.button"); tr != "Régler" {
+		t.Errorf("Expected 'Régler' but got '%s'", tr)
+	}
 }
@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the error-returning functions of the package. Use errors.Is to check for them.
+var (
+	// ErrNoConfig is returned when a lookup is made without a domain configured
+	ErrNoConfig = errors.New("gettext: no domain configured")
+
+	// ErrDomainNotFound is returned when the requested domain isn't loaded
+	ErrDomainNotFound = errors.New("gettext: domain not found")
+
+	// ErrMsgIDNotFound is returned when the requested message has no Translation
+	ErrMsgIDNotFound = errors.New("gettext: msgid not found")
+
+	// ErrParse is returned when the content of a .po or .mo file can't be parsed
+	ErrParse = errors.New("gettext: parse error")
+
+	// ErrBadPluralForm is returned when the Plural-Forms header can't be parsed
+	ErrBadPluralForm = errors.New("gettext: bad plural form")
+
+	// ErrFormat is returned when a Translation can't be formatted with the given vars
+	ErrFormat = errors.New("gettext: format error")
+
+	// ErrArgMismatch is returned in strict vars mode when the number of vars doesn't match the verbs of a Translation
+	ErrArgMismatch = errors.New("gettext: argument count mismatch")
+
+	// ErrInvalidLocale is returned by CanonicalLocale when a locale string isn't well formed
+	ErrInvalidLocale = errors.New("gettext: invalid locale")
+
+	// ErrDomainConflict is returned when a domain already loaded is added again with the ErrorOnConflict policy
+	ErrDomainConflict = errors.New("gettext: domain already loaded")
+)
+
+// parseError matches ErrParse while keeping the underlying error (i.e. an io.ErrUnexpectedEOF) inspectable.
+type parseError struct {
+	err error
+}
+
+func newParseError(format string, a ...interface{}) error {
+	return &parseError{fmt.Errorf(format, a...)}
+}
+
+func (e *parseError) Error() string {
+	return "gettext: " + e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+func (e *parseError) Is(target error) bool {
+	return target == ErrParse
+}
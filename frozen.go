@@ -0,0 +1,380 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"os"
+	"strings"
+
+	"github.com/tanyinloo/gotext/plurals"
+)
+
+// FrozenLocale is a read-only snapshot of a Locale, returned by Locale.Freeze.
+// It has no method to add domains or change translations, so it can't be mutated by accident,
+// and as nothing can change its catalogs its Get* methods don't take any lock.
+// It's meant for servers, which load their catalogs at startup and only read them afterwards.
+type FrozenLocale struct {
+	lang             string
+	defaultDomain    string
+	noSourceFallback bool
+
+	formatErrorHandler func(id, result string) string
+	placeholderStyle   PlaceholderStyle
+	templates          *templateCache
+
+	// Debugging options, see Locale.EnableEnvOverrides and Locale.AnnotateWithRefs
+	envOverrides bool
+	annotateRefs bool
+
+	// Counters of the Locale it was frozen from, nil when metrics are disabled
+	metrics *localeMetrics
+
+	// Key mode, base is nil when it's the language of this Locale
+	keyMode bool
+	base    *FrozenLocale
+
+	domains map[string]*frozenDomain
+}
+
+// frozenDomain holds the translations of a Domain at the time it was frozen
+type frozenDomain struct {
+	translations map[string]*Translation
+	contexts     map[string]map[string]*Translation
+	aliases      map[string]string
+	emptyPolicy  EmptyStringPolicy
+
+	// Separator of the hierarchical contexts, see Domain.SetContextFallback
+	contextSeparator string
+
+	// Plural-Forms expression, or the built-in rule for the language. nil for the Germanic rule
+	pluralforms plurals.Expression
+}
+
+// Freeze returns a read-only snapshot of the Locale, holding a copy of the translations of every loaded domain
+// and the current options. Later changes to the Locale or its domains don't affect the snapshot.
+// In key mode, the domains of the base language are loaded and frozen as well.
+// When metrics are enabled, the lookups of the snapshot are counted in the Metrics of the Locale.
+func (l *Locale) Freeze() *FrozenLocale {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.freeze()
+}
+
+// freeze builds the snapshot. The Locale must be read-locked.
+func (l *Locale) freeze() *FrozenLocale {
+	fl := &FrozenLocale{
+		lang:               l.lang,
+		defaultDomain:      l.defaultDomain,
+		noSourceFallback:   l.noSourceFallback,
+		formatErrorHandler: l.formatErrorHandler,
+		placeholderStyle:   l.placeholderStyle,
+		templates:          l.templates,
+		envOverrides:       l.envOverrides,
+		annotateRefs:       l.annotateRefs,
+		metrics:            l.metrics,
+		keyMode:            l.keyMode,
+		domains:            make(map[string]*frozenDomain, len(l.Domains)),
+	}
+
+	for dom, tr := range l.Domains {
+		if tr != nil {
+			fl.domains[dom] = freezeDomain(tr.GetDomain())
+		}
+	}
+
+	if l.keyMode && l.base != nil {
+		for dom := range l.Domains {
+			l.base.loadDomain(dom)
+		}
+		l.base.RLock()
+		fl.base = l.base.freeze()
+		l.base.RUnlock()
+	}
+
+	return fl
+}
+
+// freezeDomain copies the translations and the plural rule of do
+func freezeDomain(do *Domain) *frozenDomain {
+	fd := &frozenDomain{
+		translations: make(map[string]*Translation),
+		contexts:     make(map[string]map[string]*Translation),
+		aliases:      make(map[string]string),
+	}
+
+	do.pluralMutex.RLock()
+	fd.pluralforms = do.pluralforms
+	if fd.pluralforms == nil {
+		if rule := findPluralRule(do.Language); rule != nil {
+			fd.pluralforms = rule.expr
+		}
+	}
+	do.pluralMutex.RUnlock()
+
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	for id, trans := range do.translations {
+		fd.translations[id] = trans.clone()
+	}
+	fd.emptyPolicy = do.emptyPolicy
+	fd.contextSeparator = do.contextSeparator
+	for from, to := range do.aliases {
+		fd.aliases[from] = to
+	}
+	for ctx, translations := range do.contexts {
+		fd.contexts[ctx] = make(map[string]*Translation, len(translations))
+		for id, trans := range translations {
+			fd.contexts[ctx][id] = trans.clone()
+		}
+	}
+
+	return fd
+}
+
+// pluralForm is the lock-free version of Domain.pluralForm
+func (fd *frozenDomain) pluralForm(n int) int {
+	if fd.pluralforms == nil {
+		/* Use the Germanic plural rule.  */
+		if n == 1 {
+			return 0
+		}
+		return 1
+	}
+	return fd.pluralforms.Eval(uint32(n))
+}
+
+// lookup returns the Translation of str in ctx, or in no context when ctx is nil, like Domain.lookup
+func (fd *frozenDomain) lookup(str string, ctx *string) (*Translation, bool) {
+	find := func(entries map[string]*Translation) (*Translation, bool) {
+		if trans, ok := entries[str]; ok {
+			return trans, true
+		}
+		if alias, ok := fd.aliases[str]; ok {
+			trans, ok := entries[alias]
+			return trans, ok
+		}
+		return nil, false
+	}
+
+	if ctx == nil {
+		return find(fd.translations)
+	}
+	if trans, ok := find(fd.contexts[*ctx]); ok || fd.contextSeparator == "" {
+		return trans, ok
+	}
+
+	for parent := *ctx; ; {
+		idx := strings.LastIndex(parent, fd.contextSeparator)
+		if idx == -1 {
+			return find(fd.translations)
+		}
+		parent = parent[:idx]
+		if trans, ok := find(fd.contexts[parent]); ok {
+			return trans, true
+		}
+	}
+}
+
+// GetLanguage returns the language of the Locale it was frozen from
+func (fl *FrozenLocale) GetLanguage() string {
+	return fl.lang
+}
+
+// GetDomain returns the default domain of the Locale it was frozen from
+func (fl *FrozenLocale) GetDomain() string {
+	return fl.defaultDomain
+}
+
+// Get uses the default domain to return the corresponding Translation of a given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) Get(str string, vars ...interface{}) string {
+	return fl.GetD(fl.defaultDomain, str, vars...)
+}
+
+// GetN retrieves the (N)th plural form of Translation for the given string in the default domain.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetN(str, plural string, n int, vars ...interface{}) string {
+	return fl.GetND(fl.defaultDomain, str, plural, n, vars...)
+}
+
+// GetD returns the corresponding Translation in the given domain for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetD(dom, str string, vars ...interface{}) string {
+	return fl.get(dom, str, "", 0, nil, false, vars)
+}
+
+// GetND retrieves the (N)th plural form of Translation in the given domain for the given string.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetND(dom, str, plural string, n int, vars ...interface{}) string {
+	return fl.get(dom, str, plural, n, nil, true, vars)
+}
+
+// GetC uses the default domain to return the corresponding Translation of the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetC(str, ctx string, vars ...interface{}) string {
+	return fl.GetDC(fl.defaultDomain, str, ctx, vars...)
+}
+
+// GetNC retrieves the (N)th plural form of Translation for the given string in the given context in the default domain.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
+	return fl.GetNDC(fl.defaultDomain, str, plural, n, ctx, vars...)
+}
+
+// GetDC returns the corresponding Translation in the given domain for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetDC(dom, str, ctx string, vars ...interface{}) string {
+	return fl.get(dom, str, "", 0, &ctx, false, vars)
+}
+
+// GetNDC retrieves the (N)th plural form of Translation in the given domain for the given string in the given context.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (fl *FrozenLocale) GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string {
+	return fl.get(dom, str, plural, n, &ctx, true, vars)
+}
+
+// get implements the Get* methods, with the same fallbacks as the matching Locale methods
+func (fl *FrozenLocale) get(dom, str, plural string, n int, ctx *string, isPlural bool, vars []interface{}) (result string) {
+	if fl.annotateRefs {
+		defer func() { result = fl.annotate(dom, str, ctx, result) }()
+	}
+	args := fl.placeholderStyle.printfArgs(vars)
+
+	if tr, ok := fl.envOverride(dom, str, ctx); ok {
+		fl.recordLookup(dom, TranslationResult{Translated: true})
+		return fl.checkFormat(str, Printf(tr, args...), vars)
+	}
+
+	if fl.keyMode {
+		res := fl.getKey(dom, str, plural, n, ctx, isPlural, args)
+		fl.recordLookup(dom, res)
+		return fl.checkFormat(str, res.Text, vars)
+	}
+
+	if fd, ok := fl.domains[dom]; ok {
+		res, ok := fl.translate(fd, str, n, ctx, isPlural, args)
+		fl.recordLookup(dom, res)
+		if ok {
+			return fl.checkFormat(str, res.Text, vars)
+		}
+		if fl.noSourceFallback {
+			return ""
+		}
+		// Like Domain.GetN and Domain.GetNC
+		if isPlural && ((ctx == nil && fd.pluralForm(n) != 0) || (ctx != nil && n != 1)) {
+			return fl.checkFormat(str, Printf(plural, args...), vars)
+		}
+		return fl.checkFormat(str, Printf(str, args...), vars)
+	}
+
+	fl.recordLookup(dom, TranslationResult{UsedFallback: true})
+	if fl.noSourceFallback {
+		return ""
+	}
+
+	// Use western default rule (plural > 1) to handle missing domain default result.
+	if isPlural && n != 1 {
+		return fl.checkFormat(str, Printf(plural, args...), vars)
+	}
+	return fl.checkFormat(str, Printf(str, args...), vars)
+}
+
+// translate returns the formatted Translation of str from fd, and whether it has one
+func (fl *FrozenLocale) translate(fd *frozenDomain, str string, n int, ctx *string, isPlural bool, vars []interface{}) (TranslationResult, bool) {
+	var res TranslationResult
+	trans, ok := fd.lookup(str, ctx)
+	if !ok {
+		return res, false
+	}
+
+	form := 0
+	if isPlural {
+		form = fd.pluralForm(n)
+	}
+
+	// Entries without translation fall back to their source, as Domain does, unless the source fallback is disabled
+	res.Translated = trans.IsTranslatedN(form)
+	if !res.Translated && (fl.noSourceFallback || fl.keyMode) {
+		return res, false
+	}
+	res.Fuzzy = res.Translated && trans.IsFuzzy()
+	if fd.emptyPolicy == ReturnEmpty {
+		res.Text = trans.format(trans.Trs[form], vars)
+	} else {
+		res.Text = trans.format(trans.GetN(form), vars)
+	}
+	return res, true
+}
+
+// getKey resolves a message in key mode, from this FrozenLocale and then from the base language, like Locale.getKey
+func (fl *FrozenLocale) getKey(dom, str, plural string, n int, ctx *string, isPlural bool, vars []interface{}) TranslationResult {
+	if fd, ok := fl.domains[dom]; ok {
+		if res, ok := fl.translate(fd, str, n, ctx, isPlural, vars); ok {
+			res.Language = fl.lang
+			return res
+		}
+	}
+
+	res := TranslationResult{UsedFallback: true}
+	if fl.base == nil {
+		return res
+	}
+	if fd, ok := fl.base.domains[dom]; ok {
+		if base, ok := fl.translate(fd, str, n, ctx, isPlural, vars); ok {
+			base.Language, base.UsedFallback = fl.base.lang, true
+			return base
+		}
+	}
+	return res
+}
+
+// envOverride returns the Translation of str set in the environment, and whether there's one, like Locale.envOverride
+func (fl *FrozenLocale) envOverride(dom, str string, ctx *string) (string, bool) {
+	if !fl.envOverrides {
+		return "", false
+	}
+	if ctx == nil {
+		return os.LookupEnv(EnvOverrideName(dom, "", str))
+	}
+	return os.LookupEnv(EnvOverrideName(dom, *ctx, str))
+}
+
+// recordLookup counts a lookup in dom in the metrics of the Locale, like Locale.recordLookup
+func (fl *FrozenLocale) recordLookup(dom string, res TranslationResult) {
+	if fl.metrics != nil {
+		fl.metrics.record(dom, res)
+	}
+}
+
+// annotate prefixes text with the first source reference of the entry of str, like Locale.annotate
+func (fl *FrozenLocale) annotate(dom, str string, ctx *string, text string) string {
+	fd, ok := fl.domains[dom]
+	if !ok || text == "" {
+		return text
+	}
+	if trans, ok := fd.lookup(str, ctx); ok && len(trans.Refs) > 0 {
+		return "[" + trans.Refs[0] + "] " + text
+	}
+	return text
+}
+
+// checkFormat inserts vars in result like Locale.checkFormat
+func (fl *FrozenLocale) checkFormat(id, result string, vars []interface{}) string {
+	if fl.placeholderStyle != PrintfStyle {
+		interpolated, err := fl.placeholderStyle.interpolate(result, vars, fl.templates)
+		if err != nil && fl.formatErrorHandler != nil {
+			return fl.formatErrorHandler(id, interpolated)
+		}
+		return interpolated
+	}
+
+	if fl.formatErrorHandler != nil && hasFormatError(result, vars) {
+		return fl.formatErrorHandler(id, result)
+	}
+	return result
+}
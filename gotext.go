@@ -24,144 +24,304 @@ package gotext
 
 import (
 	"embed"
-	"encoding/gob"
+	"io/fs"
+	"path"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
-// Global environment variables
-type config struct {
-	sync.RWMutex
+//go:embed fixtures
+var fixture embed.FS
 
-	// Default domain to look at when no domain is specified. Used by package level functions.
-	domain string
+// globalConfig holds the *Config of the package level functions. A Config isn't changed once in use: the setters
+// publish a new one, so lookups load it without any lock.
+var globalConfig atomic.Value
 
-	// Language set.
-	language string
+// configMutex serializes the setters, so none of their changes is lost. Lookups don't use it.
+var configMutex sync.Mutex
 
-	// Path to library directory where all locale directories and Translation files are.
-	path string
+// maxLoadedDomains is the limit set with SetMaxLoadedDomains, applied to every Config put in use
+var maxLoadedDomains int64
 
-	// embedded resource
-	library embed.FS
+func init() {
+	// Init default configuration, its domain is loaded on first use
+	globalConfig.Store(newConfig(fixture, "", "en_US", "default"))
+}
 
-	// Storage for package level methods
-	storage *Locale
+// currentConfig returns the Config in use by the package level functions
+func currentConfig() *Config {
+	return globalConfig.Load().(*Config)
 }
 
-//go:embed fixtures
-var fixture embed.FS
+// setConfig puts in use a new Config with the settings of the current one changed by set, and its default domain loaded
+func setConfig(set func(c *Config)) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
 
-var globalConfig *config
+	current := currentConfig()
+	settings := Config{library: current.library, path: current.path, language: current.language, domain: current.domain}
+	set(&settings)
 
-func init() {
-	// Init default configuration
-	globalConfig = &config{
-		domain:   "default",
-		language: "en_US",
-		storage:  nil,
-		library:  fixture,
+	publishConfig(NewConfig(settings.library, settings.path, settings.language, settings.domain))
+}
+
+// publishConfig puts c in use, with the current limit of loaded domains
+func publishConfig(c *Config) {
+	n := atomic.LoadInt64(&maxLoadedDomains)
+	c.setMaxLoadedDomains(int(n))
+	globalConfig.Store(c)
+
+	// SetMaxLoadedDomains may have applied a new limit to the previous Config meanwhile
+	if m := atomic.LoadInt64(&maxLoadedDomains); m != n {
+		c.setMaxLoadedDomains(int(m))
 	}
+}
 
-	// Register Translator types for gob encoding
-	gob.Register(TranslatorEncoding{})
+// loadLocale returns the Locale for the given language, based on the Global variables settings, with dom loaded.
+// It's called automatically when using the Get*L functions, which load their domain with Locale.withDomain.
+// Locales are cached until the configuration changes, only once they have a domain: the languages without
+// Translation files, i.e. taken from client requests, don't fill the cache.
+func loadLocale(lang, dom string) *Locale {
+	return currentConfig().locale(SimplifiedLocale(lang), dom)
 }
 
-// loadStorage creates a new Locale object at package level based on the Global variables settings.
-// It's called automatically when trying to use Get or GetD methods.
-func loadStorage(force bool) {
-	globalConfig.Lock()
+// locale returns the Locale of the Config for the given language, with dom loaded, see loadLocale
+func (c *Config) locale(lang, dom string) *Locale {
+	if l, ok := c.locales[lang]; ok {
+		return l
+	}
+	if l, ok := c.loaded.Load(lang); ok {
+		return l.(*Locale)
+	}
+
+	l := NewLocale(c.library, c.path, lang)
+	l.SetDomain(c.domain)
+	l.setMaxLoadedDomains(int(atomic.LoadInt64(&maxLoadedDomains)))
+	l.loadDomain(dom)
+	if len(l.DomainNames()) == 0 {
+		return l
+	}
+	return c.cacheLocale(lang, l)
+}
 
-	if globalConfig.storage == nil || force {
-		globalConfig.storage = NewLocale(globalConfig.library, globalConfig.path, globalConfig.language)
+// cacheLocale caches the Locale of a language of the Get*L functions, and returns the cached one,
+// which is another Locale if one was cached meanwhile
+func (c *Config) cacheLocale(lang string, l *Locale) *Locale {
+	if preloaded, ok := c.locales[lang]; ok {
+		return preloaded
 	}
 
-	if _, ok := globalConfig.storage.Domains[globalConfig.domain]; !ok || force {
-		globalConfig.storage.AddDomain(globalConfig.domain)
+	if loaded, ok := c.loaded.LoadOrStore(lang, l); ok {
+		return loaded.(*Locale)
 	}
-	globalConfig.storage.SetDomain(globalConfig.domain)
 
-	globalConfig.Unlock()
+	// SetMaxLoadedDomains may have run before the Locale was cached
+	l.setMaxLoadedDomains(int(atomic.LoadInt64(&maxLoadedDomains)))
+	return l
+}
+
+// Reset discards the package configuration and every loaded Translation file.
+// The package level functions keep working afterwards, returning the source strings, until a new library is set.
+func Reset() {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	atomic.StoreInt64(&maxLoadedDomains, 0)
+	publishConfig(newConfig(embed.FS{}, "", "en_US", "default"))
+}
+
+// SetMaxLoadedDomains limits the number of domains loaded on demand by the package level functions kept in memory,
+// for the package language and for each language of the Get*L functions. When the limit is exceeded,
+// the least recently used ones are unloaded, and loaded again on their next use.
+// It bounds the memory of servers using many languages or domains with skewed access patterns.
+// The default domain loaded by Configure and the other setters isn't counted nor evicted, and a domain
+// is never evicted while a lookup is using it. The default, 0, means no limit.
+func SetMaxLoadedDomains(n int) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	atomic.StoreInt64(&maxLoadedDomains, int64(n))
+	currentConfig().setMaxLoadedDomains(n)
 }
 
 // GetDomain is the domain getter for the package configuration
 func GetDomain() string {
-	var dom string
-	globalConfig.RLock()
-	if globalConfig.storage != nil {
-		dom = globalConfig.storage.GetDomain()
+	c := currentConfig()
+	if dom := c.storage.GetDomain(); dom != "" {
+		return dom
 	}
-	if dom == "" {
-		dom = globalConfig.domain
-	}
-	globalConfig.RUnlock()
-
-	return dom
+	return c.domain
 }
 
 // SetDomain sets the name for the domain to be used at package level.
 // It reloads the corresponding Translation file.
 func SetDomain(dom string) {
-	globalConfig.Lock()
-	globalConfig.domain = dom
-	if globalConfig.storage != nil {
-		globalConfig.storage.SetDomain(dom)
-	}
-	globalConfig.Unlock()
-
-	loadStorage(true)
+	setConfig(func(c *Config) {
+		c.domain = dom
+	})
 }
 
 // GetLanguage is the language getter for the package configuration
 func GetLanguage() string {
-	globalConfig.RLock()
-	lang := globalConfig.language
-	globalConfig.RUnlock()
-
-	return lang
+	return currentConfig().language
 }
 
 // SetLanguage sets the language code to be used at package level.
 // It reloads the corresponding Translation file.
 func SetLanguage(lang string) {
-	globalConfig.Lock()
-	globalConfig.language = SimplifiedLocale(lang)
-	globalConfig.Unlock()
-
-	loadStorage(true)
+	setConfig(func(c *Config) {
+		c.language = lang
+	})
 }
 
 // GetLibrary is the library getter for the package configuration
-func GetLibrary() embed.FS {
-	globalConfig.RLock()
-	lib := globalConfig.library
-	globalConfig.RUnlock()
-
-	return lib
+func GetLibrary() fs.FS {
+	return currentConfig().library
 }
 
 // SetLibrary sets the root path for the loale directories and files to be used at package level.
 // It reloads the corresponding Translation file.
-func SetLibrary(lib embed.FS) {
-	globalConfig.Lock()
-	globalConfig.library = lib
-	globalConfig.Unlock()
-
-	loadStorage(true)
+func SetLibrary(lib fs.FS) {
+	setConfig(func(c *Config) {
+		c.library = lib
+	})
 }
 
 // Configure sets all configuration variables to be used at package level and reloads the corresponding Translation file.
-// It receives the library path, language code and domain name.
+// It receives the library, i.e. an embed.FS or an os.DirFS, the library path, language code and domain name.
 // This function is recommended to be used when changing more than one setting,
 // as using each setter will introduce a I/O overhead because the Translation file will be loaded after each set.
-func Configure(lib embed.FS, path, lang, dom string) {
-	globalConfig.Lock()
-	globalConfig.library = lib
-	globalConfig.path = path
-	globalConfig.language = SimplifiedLocale(lang)
-	globalConfig.domain = dom
-	globalConfig.Unlock()
+func Configure(lib fs.FS, path, lang, dom string) {
+	setConfig(func(c *Config) {
+		c.library = lib
+		c.path = path
+		c.language = lang
+		c.domain = dom
+	})
+}
+
+// Config is a complete package configuration, built off to the side and put in use at once with SwapConfig,
+// i.e. to reload the translations without downtime. Its Translation files are loaded while building it,
+// so lookups made meanwhile keep using the current configuration.
+// A Config shouldn't be changed once swapped in.
+type Config struct {
+	library  fs.FS
+	path     string
+	language string
+	domain   string
+
+	// Locale of the package level functions, and of the Get*L functions by language code, loaded before the swap
+	storage *Locale
+	locales map[string]*Locale
+
+	// Locales of the other languages of the Get*L functions, by language code, created on first use
+	loaded *sync.Map
+
+	// Files of the library found by NegotiateLanguage, by language and domain. Only files that exist are cached,
+	// so it's bounded by the library whatever the languages asked for.
+	domainFiles *sync.Map
+}
+
+// newConfig creates a Config with the given settings, whose default domain isn't loaded yet
+func newConfig(lib fs.FS, path, lang, dom string) *Config {
+	c := &Config{
+		library:     lib,
+		path:        path,
+		language:    SimplifiedLocale(lang),
+		domain:      dom,
+		loaded:      new(sync.Map),
+		domainFiles: new(sync.Map),
+	}
+	c.storage = NewLocale(lib, path, c.language)
+	c.storage.SetDomain(dom)
+
+	return c
+}
+
+// NewConfig creates a Config with the same settings as Configure: the library path, language code and
+// default domain name. The default domain is loaded right away.
+func NewConfig(lib fs.FS, path, lang, dom string) *Config {
+	c := newConfig(lib, path, lang, dom)
+	c.load()
+
+	return c
+}
+
+// load loads the default domain of the Config
+func (c *Config) load() {
+	c.storage.AddDomain(c.domain)
+	c.storage.SetDomain(c.domain)
+}
+
+// setMaxLoadedDomains applies the limit of SetMaxLoadedDomains to every Locale of the Config
+func (c *Config) setMaxLoadedDomains(n int) {
+	c.storage.setMaxLoadedDomains(n)
+	for _, l := range c.locales {
+		l.setMaxLoadedDomains(n)
+	}
+	c.loaded.Range(func(_, l interface{}) bool {
+		l.(*Locale).setMaxLoadedDomains(n)
+		return true
+	})
+}
+
+// AddDomain loads another domain for the language of the Config, so it's ready when the Config is swapped in.
+// Domains that aren't loaded are still loaded on first use.
+func (c *Config) AddDomain(dom string) {
+	c.storage.AddDomain(dom)
+}
+
+// AddLanguage loads the given domains, or the default domain if none is given, for another language
+// used with the Get*L functions, i.e. GetL or GetForClient.
+func (c *Config) AddLanguage(lang string, domains ...string) {
+	lang = SimplifiedLocale(lang)
+	if len(domains) == 0 {
+		domains = []string{c.domain}
+	}
+
+	if c.locales == nil {
+		c.locales = make(map[string]*Locale)
+	}
+	l, ok := c.locales[lang]
+	if !ok {
+		l = NewLocale(c.library, c.path, lang)
+		l.SetDomain(c.domain)
+		c.locales[lang] = l
+	}
+	for _, dom := range domains {
+		l.AddDomain(dom)
+	}
+}
+
+// SwapConfig replaces the whole package configuration with c, along with every loaded Translation file,
+// in a single atomic store: concurrent lookups see either the previous configuration or c, never a mix of both,
+// and never wait for the swap. Lookups in progress finish with the previous one.
+// Configure(lib, path, lang, dom) can be migrated to SwapConfig(NewConfig(lib, path, lang, dom)), calling
+// Config.AddDomain and Config.AddLanguage before the swap to preload the domains and languages in use.
+func SwapConfig(c *Config) {
+	publishConfig(c)
+}
+
+// AddTranslation sets the Translation of str in the given language, domain and context, so the package level
+// functions return it, i.e. to test code depending on translations without fixture files.
+// It's used by the Get*L functions for that language, and by Get and the other package level functions
+// if it's the package language. The domain is loaded first, or created empty if it has no file.
+// Translations added this way are discarded when the configuration changes, i.e. with Configure or SetLanguage.
+// It's safe to use concurrently with lookups.
+func AddTranslation(lang, dom, str, ctx, translation string) {
+	lang = SimplifiedLocale(lang)
+	c := currentConfig()
+	if lang == c.language {
+		c.storage.AddTranslation(dom, str, ctx, translation)
+	}
 
-	loadStorage(true)
+	// The Locale isn't cached yet when the language has no file of the domain, it is once it has the Translation
+	l := c.locale(lang, dom)
+	l.AddTranslation(dom, str, ctx, translation)
+	if cached := c.cacheLocale(lang, l); cached != l {
+		cached.AddTranslation(dom, str, ctx, translation)
+	}
 }
 
 // Get uses the default domain globally set to return the corresponding Translation of a given string.
@@ -176,42 +336,61 @@ func GetN(str, plural string, n int, vars ...interface{}) string {
 	return GetND(GetDomain(), str, plural, n, vars...)
 }
 
+// GetCount works like GetN, inserting n into the Translation when the singular and plural strings
+// both hold a single %d or %v verb. See Locale.GetCount.
+func GetCount(str, plural string, n int, vars ...interface{}) string {
+	return GetN(str, plural, n, countVars(str, plural, n, vars)...)
+}
+
 // GetD returns the corresponding Translation in the given domain for a given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func GetD(dom, str string, vars ...interface{}) string {
-	// Try to load default package Locale storage
-	loadStorage(false)
+	l := currentConfig().storage
+	return l.withDomain(dom, func() string {
+		return l.GetD(dom, str, vars...)
+	})
+}
 
-	// Return Translation
-	globalConfig.RLock()
+// GetNamedN retrieves the (N)th plural form of Translation for the given string in the default domain,
+// and replaces its {name} style placeholders with the values of data, i.e. "You have {count} new messages".
+// n is available as "count", and its CLDR plural category for the package language as "category",
+// unless data sets them. Placeholders without value are left as is.
+func GetNamedN(str, plural string, n int, data map[string]interface{}) string {
+	return formatNamed(GetN(str, plural, n), namedCount(n, GetLanguage(), data))
+}
 
-	if _, ok := globalConfig.storage.Domains[dom]; !ok {
-		globalConfig.storage.AddDomain(dom)
-	}
+// GetOr uses the default domain globally set to return the corresponding Translation of a given string,
+// or fallback when it has no Translation, i.e. when the string is a key like "err.not_found".
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetOr(str, fallback string, vars ...interface{}) string {
+	dom := GetDomain()
 
-	tr := globalConfig.storage.GetD(dom, str, vars...)
-	globalConfig.RUnlock()
+	l := currentConfig().storage
+	return l.withDomain(dom, func() string {
+		return l.GetDOr(dom, str, fallback, vars...)
+	})
+}
 
-	return tr
+// GetResult works like Get, returning the Translation along with its metadata, see Locale.GetResult.
+func GetResult(str string, vars ...interface{}) TranslationResult {
+	dom := GetDomain()
+
+	var res TranslationResult
+	l := currentConfig().storage
+	l.withDomain(dom, func() string {
+		res = l.GetNDCResult(dom, str, "", 0, "", vars...)
+		return res.Text
+	})
+	return res
 }
 
 // GetND retrieves the (N)th plural form of Translation in the given domain for a given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func GetND(dom, str, plural string, n int, vars ...interface{}) string {
-	// Try to load default package Locale storage
-	loadStorage(false)
-
-	// Return Translation
-	globalConfig.RLock()
-
-	if _, ok := globalConfig.storage.Domains[dom]; !ok {
-		globalConfig.storage.AddDomain(dom)
-	}
-
-	tr := globalConfig.storage.GetND(dom, str, plural, n, vars...)
-	globalConfig.RUnlock()
-
-	return tr
+	l := currentConfig().storage
+	return l.withDomain(dom, func() string {
+		return l.GetND(dom, str, plural, n, vars...)
+	})
 }
 
 // GetC uses the default domain globally set to return the corresponding Translation of the given string in the given context.
@@ -229,27 +408,155 @@ func GetNC(str, plural string, n int, ctx string, vars ...interface{}) string {
 // GetDC returns the corresponding Translation in the given domain for the given string in the given context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func GetDC(dom, str, ctx string, vars ...interface{}) string {
-	// Try to load default package Locale storage
-	loadStorage(false)
-
-	// Return Translation
-	globalConfig.RLock()
-	tr := globalConfig.storage.GetDC(dom, str, ctx, vars...)
-	globalConfig.RUnlock()
-
-	return tr
+	l := currentConfig().storage
+	return l.withDomain(dom, func() string {
+		return l.GetDC(dom, str, ctx, vars...)
+	})
 }
 
 // GetNDC retrieves the (N)th plural form of Translation in the given domain for a given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string {
-	// Try to load default package Locale storage
-	loadStorage(false)
+	l := currentConfig().storage
+	return l.withDomain(dom, func() string {
+		return l.GetNDC(dom, str, plural, n, ctx, vars...)
+	})
+}
+
+// GetL returns the corresponding Translation of a given string in the default domain for the given language,
+// without changing the language set at package level. It's safe to use concurrently with different languages.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetL(lang, str string, vars ...interface{}) string {
+	return GetDL(lang, GetDomain(), str, vars...)
+}
+
+// GetNL retrieves the (N)th plural form of Translation for the given string in the default domain for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetNL(lang, str, plural string, n int, vars ...interface{}) string {
+	return GetNDL(lang, GetDomain(), str, plural, n, vars...)
+}
+
+// GetDL returns the corresponding Translation in the given domain for a given string for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetDL(lang, dom, str string, vars ...interface{}) string {
+	l := loadLocale(lang, dom)
+	return l.withDomain(dom, func() string {
+		return l.GetD(dom, str, vars...)
+	})
+}
 
-	// Return Translation
-	globalConfig.RLock()
-	tr := globalConfig.storage.GetNDC(dom, str, plural, n, ctx, vars...)
-	globalConfig.RUnlock()
+// GetNDL retrieves the (N)th plural form of Translation in the given domain for a given string for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetNDL(lang, dom, str, plural string, n int, vars ...interface{}) string {
+	l := loadLocale(lang, dom)
+	return l.withDomain(dom, func() string {
+		return l.GetND(dom, str, plural, n, vars...)
+	})
+}
+
+// GetCL returns the corresponding Translation of the given string in the given context in the default domain for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetCL(lang, str, ctx string, vars ...interface{}) string {
+	return GetDCL(lang, GetDomain(), str, ctx, vars...)
+}
+
+// GetNCL retrieves the (N)th plural form of Translation for the given string in the given context in the default domain for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetNCL(lang, str, plural string, n int, ctx string, vars ...interface{}) string {
+	return GetNDCL(lang, GetDomain(), str, plural, n, ctx, vars...)
+}
+
+// GetDCL returns the corresponding Translation in the given domain for the given string in the given context for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetDCL(lang, dom, str, ctx string, vars ...interface{}) string {
+	l := loadLocale(lang, dom)
+	return l.withDomain(dom, func() string {
+		return l.GetDC(dom, str, ctx, vars...)
+	})
+}
+
+// GetNDCL retrieves the (N)th plural form of Translation in the given domain for a given string in the given context for the given language.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetNDCL(lang, dom, str, plural string, n int, ctx string, vars ...interface{}) string {
+	l := loadLocale(lang, dom)
+	return l.withDomain(dom, func() string {
+		return l.GetNDC(dom, str, plural, n, ctx, vars...)
+	})
+}
+
+// NegotiateLanguage returns the language to use for a client, from the value of its Accept-Language HTTP header:
+// the first language, by quality, for which the library set at package level has a file of the given domain.
+// Each language range is tried as is, i.e. "fr_CH" for "fr-CH", then as its primary language, "fr".
+// It returns an empty string if the library has no file of the domain for any of the client languages.
+func NegotiateLanguage(acceptLanguage, dom string) string {
+	c := currentConfig()
+	lib, libPath, files := c.library, c.path, c.domainFiles
+
+	for _, tag := range ParseAcceptLanguage(acceptLanguage) {
+		if tag.Tag == "*" {
+			continue
+		}
+
+		subtags := strings.Split(tag.Tag, "-")
+		subtags[0] = strings.ToLower(subtags[0])
+		for i := 1; i < len(subtags); i++ {
+			if len(subtags[i]) == 2 {
+				subtags[i] = strings.ToUpper(subtags[i])
+			}
+		}
+
+		for _, lang := range []string{strings.Join(subtags, "_"), subtags[0]} {
+			if hasDomainFile(files, lib, libPath, lang, dom) {
+				return lang
+			}
+		}
+	}
+
+	return ""
+}
+
+// hasDomainFile reports whether the library has a file of the domain for exactly the given language,
+// in the directories searched by Locale.AddDomain. Files found are cached in files, by language and domain,
+// so negotiating the language of each request doesn't open them again.
+func hasDomainFile(files *sync.Map, lib fs.FS, libPath, lang, dom string) bool {
+	key := lang + "\x00" + dom
+	if _, ok := files.Load(key); ok {
+		return true
+	}
+
+	if !lookupDomainFile(lib, libPath, lang, dom) {
+		return false
+	}
+	files.Store(key, struct{}{})
+	return true
+}
+
+// lookupDomainFile looks for a file of the domain for the given language on each library root
+func lookupDomainFile(lib fs.FS, libPath, lang, dom string) bool {
+	for _, root := range NewLocale(lib, libPath, lang).libraryPaths() {
+		for _, dir := range []string{path.Join(lang, "LC_MESSAGES"), lang} {
+			for _, ext := range []string{"po", "mo"} {
+				if file, err := lib.Open(path.Join(root, dir, dom+"."+ext)); err == nil {
+					file.Close()
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// GetForClient translates a string in the given domain for an HTTP client, in the language negotiated
+// by NegotiateLanguage from the value of its Accept-Language header, like GetDL.
+// If no client language has a file of the domain, the language set at package level is used, and if it has
+// no Translation either, the string itself. It doesn't change the package configuration, so it's safe to use
+// concurrently, i.e. from HTTP handlers.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func GetForClient(acceptLanguage, dom, str string, vars ...interface{}) string {
+	lang := NegotiateLanguage(acceptLanguage, dom)
+	if lang == "" {
+		lang = GetLanguage()
+	}
 
-	return tr
+	return GetDL(lang, dom, str, vars...)
 }
@@ -2,6 +2,7 @@ package gotext
 
 import (
 	"embed"
+	"io/fs"
 	"os"
 	"path"
 	"sync"
@@ -22,7 +23,7 @@ func TestGettersSetters(t *testing.T) {
 	SetLibrary(res)
 	lib := GetLibrary()
 
-	if ar, err := lib.ReadDir("fixtures/ar"); err != nil || len(ar) != 2 {
+	if ar, err := fs.ReadDir(lib, "fixtures/ar"); err != nil || len(ar) != 2 {
 		t.Errorf("Expected GetLibrary to return '/tmp/test', but got '%s'", "lib")
 	}
 
@@ -509,3 +510,317 @@ func TestPackageArabicMissingPluralForm(t *testing.T) {
 		t.Errorf("Expected to get 'الكحول والتبغ', but got '%s'", tr)
 	}
 }
+
+func TestPackageLanguageFunctions(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	var wg sync.WaitGroup
+	for _, lang := range []string{"en_US", "de_DE", "fr_FR", "en_AU"} {
+		wg.Add(1)
+		go func(lang string) {
+			defer wg.Done()
+
+			if tr := GetL(lang, "language"); tr != lang && tr != lang[:2] {
+				t.Errorf("Expected '%s' but got '%s'", lang, tr)
+			}
+			if tr := GetNDL(lang, "default", "One with var: %s", "Several with vars: %s", 2, "v"); tr != "This one is the plural: v" {
+				t.Errorf("Expected 'This one is the plural: v' but got '%s'", tr)
+			}
+			if tr := GetCL(lang, "Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+				t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+			}
+		}(lang)
+	}
+	wg.Wait()
+
+	// Package language is untouched
+	if lang := GetLanguage(); lang != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", lang)
+	}
+
+	// Only the languages with a file are cached
+	for _, lang := range []string{"xx_YY", "not a language"} {
+		if tr := GetL(lang, "My text"); tr != "My text" {
+			t.Errorf("Expected 'My text' but got '%s'", tr)
+		}
+		if _, ok := currentConfig().loaded.Load(SimplifiedLocale(lang)); ok {
+			t.Errorf("Expected the Locale of '%s' not to be cached", lang)
+		}
+	}
+	if _, ok := currentConfig().loaded.Load("de_DE"); !ok {
+		t.Error("Expected the Locale of 'de_DE' to be cached")
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	for _, tc := range []struct {
+		header, dom, expected string
+	}{
+		{"", "default", ""},
+		{"fr-CH, de;q=0.8", "default", "fr"},
+		{"de-de", "default", "de_DE"},
+		{"de-AT", "default", "de"},
+		{"es, de;q=0", "default", ""},
+		{"es, *;q=0.5", "default", ""},
+		{"es;q=0.9, en-GB;q=0.1, en-AU;q=0.5", "default", "en_AU"},
+		{"fr, ar;q=0.5", "categories", "ar"},
+	} {
+		if lang := NegotiateLanguage(tc.header, tc.dom); lang != tc.expected {
+			t.Errorf("Expected '%s' for %q but got '%s'", tc.expected, tc.header, lang)
+		}
+	}
+
+	// Results are cached until the configuration changes
+	files := currentConfig().domainFiles
+	if _, ok := files.Load("de_DE\x00default"); !ok {
+		t.Error("Expected the 'de_DE' file of 'default' to be cached")
+	}
+	if _, ok := files.Load("es\x00default"); ok {
+		t.Error("Expected the missing 'es' file of 'default' not to be cached")
+	}
+
+	Configure(enUSFixture, "missing", "en_US", "default")
+	defer Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	if lang := NegotiateLanguage("de-DE", "default"); lang != "" {
+		t.Errorf("Expected '' after changing the library path but got '%s'", lang)
+	}
+}
+
+func TestGetForClient(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	var wg sync.WaitGroup
+	for header, expected := range map[string]string{
+		"fr-CH, de;q=0.8": "fr",
+		"de-DE":           "de_DE",
+		"es":              "en_US",
+		"":                "en_US",
+	} {
+		wg.Add(1)
+		go func(header, expected string) {
+			defer wg.Done()
+
+			if tr := GetForClient(header, "default", "language"); tr != expected {
+				t.Errorf("Expected '%s' for %q but got '%s'", expected, header, tr)
+			}
+			if tr := GetForClient(header, "default", "One with var: %s", "v"); tr != "This one is the singular: v" {
+				t.Errorf("Expected 'This one is the singular: v' for %q but got '%s'", header, tr)
+			}
+		}(header, expected)
+	}
+	wg.Wait()
+
+	// Package language is untouched
+	if lang := GetLanguage(); lang != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", lang)
+	}
+}
+
+func TestSwapConfig(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+	defer Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	c := NewConfig(enUSFixture, "fixtures", "fr_FR", "default")
+	c.AddDomain("keys")
+	c.AddLanguage("de_DE")
+
+	// Lookups see either configuration
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if tr := Get("language"); tr != "en_US" && tr != "fr" {
+					t.Errorf("Expected 'en_US' or 'fr' but got '%s'", tr)
+					return
+				}
+			}
+		}()
+	}
+	SwapConfig(c)
+	close(stop)
+	wg.Wait()
+
+	if lang := GetLanguage(); lang != "fr_FR" {
+		t.Errorf("Expected 'fr_FR' but got '%s'", lang)
+	}
+	if tr := Get("language"); tr != "fr" {
+		t.Errorf("Expected 'fr' but got '%s'", tr)
+	}
+	if tr := GetD("keys", "checkout.title"); tr != "Paiement" {
+		t.Errorf("Expected 'Paiement' but got '%s'", tr)
+	}
+
+	// Preloaded languages are used by the Get*L functions
+	if currentConfig() != c || loadLocale("de_DE", "default") != c.locales["de_DE"] {
+		t.Error("Expected the preloaded de_DE Locale to be used")
+	}
+	if tr := GetL("de_DE", "language"); tr != "de_DE" {
+		t.Errorf("Expected 'de_DE' but got '%s'", tr)
+	}
+
+	// Lookups don't take the lock of the setters
+	configMutex.Lock()
+	if tr := GetL("de_DE", "language"); tr != "de_DE" {
+		t.Errorf("Expected 'de_DE' but got '%s'", tr)
+	}
+	if lang := GetLanguage(); lang != "fr_FR" {
+		t.Errorf("Expected 'fr_FR' but got '%s'", lang)
+	}
+	configMutex.Unlock()
+}
+
+func TestAddTranslation(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+	defer Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	// Concurrent with lookups
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Get("My text")
+				GetL("fr", "Injected")
+			}
+		}()
+	}
+	AddTranslation("en_US", "default", "Injected", "", "Injected text")
+	AddTranslation("en_US", "default", "Injected", "Ctx", "Injected in a context")
+	AddTranslation("en_US", "runtime", "Dynamic %s", "", "Dynamic translation %s")
+	AddTranslation("fr", "default", "Injected", "", "Texte injecté")
+	wg.Wait()
+
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{Get("Injected"), "Injected text"},
+		{Get("My text"), "Translated text"},
+		{GetC("Injected", "Ctx"), "Injected in a context"},
+		{GetD("runtime", "Dynamic %s", "v"), "Dynamic translation v"},
+		{GetL("en_US", "Injected"), "Injected text"},
+		{GetL("fr", "Injected"), "Texte injecté"},
+		{GetL("fr", "My text"), "Translated text"},
+		{GetL("de_DE", "Injected"), "Injected"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+
+	// Discarded by a new configuration
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+	if tr := Get("Injected"); tr != "Injected" {
+		t.Errorf("Expected 'Injected' but got '%s'", tr)
+	}
+}
+
+func TestGetResult(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	if res := GetResult("One with var: %s", "v"); res != (TranslationResult{"This one is the singular: v", true, false, "en_US", false}) {
+		t.Errorf("Expected the en_US Translation but got %+v", res)
+	}
+	if res := GetResult("Untranslated"); res != (TranslationResult{"Untranslated", false, false, "", true}) {
+		t.Errorf("Expected the source string but got %+v", res)
+	}
+}
+
+func TestPackageFunctionsWithoutConfig(t *testing.T) {
+	Reset()
+	defer Configure(res, "fixtures", "en_US", "default")
+
+	for name, tc := range map[string]struct {
+		got, want string
+	}{
+		"Get":       {Get("My text %s", "v"), "My text v"},
+		"GetN":      {GetN("One %d", "Several %d", 2, 2), "Several 2"},
+		"GetD":      {GetD("dom", "My text"), "My text"},
+		"GetND":     {GetND("dom", "One", "Several", 1), "One"},
+		"GetC":      {GetC("My text", "Ctx"), "My text"},
+		"GetNC":     {GetNC("One", "Several", 3, "Ctx"), "Several"},
+		"GetDC":     {GetDC("dom", "My text", "Ctx"), "My text"},
+		"GetNDC":    {GetNDC("dom", "One", "Several", 1, "Ctx"), "One"},
+		"GetL":      {GetL("fr", "My text"), "My text"},
+		"GetOr":     {GetOr("err.key", "Fallback %d", 1), "Fallback 1"},
+		"GetNamedN": {GetNamedN("{count} file", "{count} files", 2, nil), "2 files"},
+		"GetNDL":    {GetNDL("fr", "dom", "One", "Several", 2), "Several"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc.want, tc.got)
+		}
+	}
+
+	Reset()
+	if dom := GetDomain(); dom != "default" {
+		t.Errorf("Expected 'default' but got '%s'", dom)
+	}
+	if entries, err := fs.ReadDir(GetLibrary(), "."); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an empty library but got %d entries (%v)", len(entries), err)
+	}
+}
+
+func TestSetMaxLoadedDomains(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "fr_FR", "keys")
+	SetMaxLoadedDomains(1)
+	defer Reset()
+
+	// Context lookups load their domain on demand too
+	if tr := GetDC("default", "Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+		t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+	}
+	if tr := GetNC("One with var: %s", "Several with vars: %s", 2, "Ctx", "v"); tr != "Several with vars: v" {
+		t.Errorf("Expected 'Several with vars: v' but got '%s'", tr)
+	}
+	if tr := GetNDC("default", "One with var: %s", "Several with vars: %s", 2, "Ctx", "v"); tr != "This one is the plural in a Ctx context: v" {
+		t.Errorf("Expected 'This one is the plural in a Ctx context: v' but got '%s'", tr)
+	}
+
+	// The limit applies to the Locales of the Get*L functions, created before or after the call
+	if tr := GetDL("fr", "default", "My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if tr := GetDCL("fr", "keys", "checkout.title", ""); tr != "checkout.title" {
+		t.Errorf("Expected 'checkout.title' but got '%s'", tr)
+	}
+	if names := loadLocale("fr", "keys").DomainNames(); len(names) != 1 || names[0] != "keys" {
+		t.Errorf("Expected '[keys]' but got '%v'", names)
+	}
+
+	SetMaxLoadedDomains(2)
+	GetDL("fr", "default", "My text")
+	if names := loadLocale("fr", "keys").DomainNames(); len(names) != 2 {
+		t.Errorf("Expected '[default keys]' but got '%v'", names)
+	}
+
+	// Concurrent lookups in evicted domains
+	SetMaxLoadedDomains(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if i%2 == 0 {
+					if tr := GetDC("default", "Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+						t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+					}
+				} else if tr := GetDL("fr", "keys", "checkout.title"); tr != "Paiement" {
+					t.Errorf("Expected 'Paiement' but got '%s'", tr)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"strings"
+	"time"
+)
+
+// Date layouts of the POT-Creation-Date and PO-Revision-Date headers, as written by xgettext and PO editors
+var headerDateLayouts = []string{
+	"2006-01-02 15:04-0700",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 15:04:05 -0700",
+}
+
+// ProjectIDVersion returns the Project-Id-Version header, i.e. "gotext 1.5.0"
+func (do *Domain) ProjectIDVersion() string {
+	return do.GetHeader("Project-Id-Version")
+}
+
+// ReportMsgidBugsTo returns the Report-Msgid-Bugs-To header, the address to report issues with the msgids
+func (do *Domain) ReportMsgidBugsTo() string {
+	return do.GetHeader("Report-Msgid-Bugs-To")
+}
+
+// LastTranslator returns the Last-Translator header, i.e. "John Doe <john@example.com>"
+func (do *Domain) LastTranslator() string {
+	return do.GetHeader("Last-Translator")
+}
+
+// LanguageTeam returns the Language-Team header, i.e. "French <fr@example.com>"
+func (do *Domain) LanguageTeam() string {
+	return do.GetHeader("Language-Team")
+}
+
+// CreationDate returns the POT-Creation-Date header, when the template was generated.
+// It returns the zero time without error if the header is missing or still holds the template placeholder.
+func (do *Domain) CreationDate() (time.Time, error) {
+	return parseHeaderDate(do.GetHeader("POT-Creation-Date"))
+}
+
+// RevisionDate returns the PO-Revision-Date header, when the Translation file was last edited.
+// It returns the zero time without error if the header is missing or still holds the template placeholder,
+// and an error matching ErrParse if the date can't be parsed.
+func (do *Domain) RevisionDate() (time.Time, error) {
+	return parseHeaderDate(do.GetHeader("PO-Revision-Date"))
+}
+
+// parseHeaderDate parses a date in the gettext format, "YEAR-MO-DA HO:MI+ZONE"
+func parseHeaderDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.HasPrefix(value, "YEAR-") {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range headerDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, newParseError("invalid header date %q", value)
+}
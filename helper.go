@@ -7,7 +7,10 @@ package gotext
 
 import (
 	"fmt"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -28,7 +31,204 @@ func SimplifiedLocale(lang string) string {
 	return strings.TrimSpace(lang)
 }
 
+// CanonicalLocale validates a locale string and returns it in the canonical form of the gettext locale directories:
+// language[_Script][_REGION][@modifier], with a 2 or 3 letter lowercase language, a 4 letter titlecase script,
+// a 2 letter uppercase or 3 digit region, and the modifier as is. Subtags can be separated by "_" or "-",
+// and the codeset is dropped, so " en-us.UTF-8" gives "en_US" and "SR_latn_rs@latin" gives "sr_Latn_RS@latin".
+// Unlike SimplifiedLocale, it returns an error matching ErrInvalidLocale for anything else, i.e. "english" or "12_34".
+func CanonicalLocale(s string) (string, error) {
+	lang := strings.TrimSpace(s)
+
+	modifier := ""
+	if idx := strings.Index(lang, "@"); idx != -1 {
+		lang, modifier = lang[:idx], lang[idx+1:]
+		if modifier == "" || !isAlnum(modifier) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+		}
+	}
+	if idx := strings.Index(lang, "."); idx != -1 {
+		lang = lang[:idx]
+	}
+
+	subtags := strings.FieldsFunc(lang, func(r rune) bool { return r == '_' || r == '-' })
+	if len(subtags) == 0 || len(subtags) > 3 || strings.Count(lang, "_")+strings.Count(lang, "-") != len(subtags)-1 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+	}
+
+	// Language
+	if len(subtags[0]) < 2 || len(subtags[0]) > 3 || !isAlpha(subtags[0]) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+	}
+	canonical := strings.ToLower(subtags[0])
+	rest := subtags[1:]
+
+	// Script
+	if len(rest) > 0 && len(rest[0]) == 4 && isAlpha(rest[0]) {
+		canonical += "_" + strings.ToUpper(rest[0][:1]) + strings.ToLower(rest[0][1:])
+		rest = rest[1:]
+	}
+
+	// Region
+	if len(rest) > 0 {
+		switch region := rest[0]; {
+		case len(region) == 2 && isAlpha(region):
+			canonical += "_" + strings.ToUpper(region)
+		case len(region) == 3 && isDigits(region):
+			canonical += "_" + region
+		default:
+			return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, s)
+	}
+
+	if modifier != "" {
+		canonical += "@" + modifier
+	}
+	return canonical, nil
+}
+
+// isAlpha reports whether s only holds ASCII letters
+func isAlpha(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i] | 0x20; c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigits reports whether s only holds ASCII digits
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlnum reports whether s only holds ASCII letters and digits
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isDigits(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SystemLocale returns the language for messages set on the environment, simplified with SimplifiedLocale,
+// following the gettext precedence: the first non-empty variable of LC_ALL, LC_MESSAGES and LANG,
+// overridden by the first language of the LANGUAGE list when set.
+// It returns an empty string, meaning no Translation, for the "C" and "POSIX" locales or if no locale is set.
+func SystemLocale() string {
+	var lang string
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if lang = os.Getenv(env); lang != "" {
+			break
+		}
+	}
+
+	// Without locale, the "C" locale is used
+	lang = SimplifiedLocale(lang)
+	if lang == "" || lang == "C" || lang == "POSIX" {
+		return ""
+	}
+
+	// LANGUAGE is ignored for the "C" locale, like gettext does
+	if language := SimplifiedLocale(os.Getenv("LANGUAGE")); language != "" {
+		return language
+	}
+
+	return lang
+}
+
+// LanguageTag is a language range of an Accept-Language header with its quality value, as returned by ParseAcceptLanguage
+type LanguageTag struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage parses the value of an HTTP Accept-Language header, i.e. "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5",
+// and returns its language ranges sorted by quality, highest first, keeping the header order for equal qualities.
+// Ranges without q parameter have a quality of 1. Ranges with a quality of 0, which the client explicitly refuses,
+// and malformed ranges or q values, i.e. "q=abc" or "q=1.5", are skipped. The "*" wildcard is returned as is.
+func ParseAcceptLanguage(header string) []LanguageTag {
+	var tags []LanguageTag
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if !isLanguageRange(tag) {
+			continue
+		}
+
+		quality, ok := 1.0, true
+		for _, param := range params[1:] {
+			name, value := strings.TrimSpace(param), ""
+			if idx := strings.Index(name, "="); idx != -1 {
+				name, value = strings.TrimSpace(name[:idx]), strings.TrimSpace(name[idx+1:])
+			}
+			if strings.EqualFold(name, "q") {
+				quality, ok = parseQuality(value)
+			}
+		}
+		if !ok || quality == 0 {
+			continue
+		}
+
+		tags = append(tags, LanguageTag{Tag: tag, Quality: quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].Quality > tags[j].Quality
+	})
+
+	return tags
+}
+
+// isLanguageRange reports whether tag is "*" or alphanumeric subtags of 1 to 8 characters separated by hyphens
+func isLanguageRange(tag string) bool {
+	if tag == "*" {
+		return true
+	}
+	if tag == "" {
+		return false
+	}
+	for _, subtag := range strings.Split(tag, "-") {
+		if len(subtag) == 0 || len(subtag) > 8 {
+			return false
+		}
+		for _, c := range subtag {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseQuality parses a q value, which must be between 0 and 1 with at most 3 decimals
+func parseQuality(value string) (float64, bool) {
+	if value == "" || len(value) > 5 || (value[0] != '0' && value[0] != '1') {
+		return 0, false
+	}
+	if len(value) > 1 && value[1] != '.' {
+		return 0, false
+	}
+	quality, err := strconv.ParseFloat(value, 64)
+	if err != nil || quality < 0 || quality > 1 {
+		return 0, false
+	}
+	return quality, true
+}
+
 // Printf applies text formatting only when needed to parse variables.
+// Vars are formatted by the fmt package, so for the %s, %q and %v verbs an error uses its Error() method
+// and a fmt.Stringer its String() method, Error() taking precedence when both are implemented.
+// A nil error is written as "%!s(<nil>)" and a String() method panicking on a nil pointer as "<nil>".
 func Printf(str string, vars ...interface{}) string {
 	if len(vars) > 0 {
 		return fmt.Sprintf(str, vars...)
@@ -37,6 +237,117 @@ func Printf(str string, vars ...interface{}) string {
 	return str
 }
 
+// FormatSource formats the msgid str with vars without looking it up, the way the Get* functions format strings
+// without Translation, i.e. to render a preview of untranslated strings. It's an alias of Printf.
+func FormatSource(str string, vars ...interface{}) string {
+	return Printf(str, vars...)
+}
+
+// formatErrorRe matches the error markers written by fmt: bad verbs or types, missing and extra arguments,
+// bad argument indexes, widths and precisions, and a missing verb
+var formatErrorRe = regexp.MustCompile(`%!(\((NOVERB|BADWIDTH|BADPREC|BADINDEX|EXTRA [^)]*)\)|[^(]\((MISSING|BADINDEX|<nil>|[^=)]+=[^)]*)\))`)
+
+// hasFormatError reports whether a string formatted by Printf with vars holds a fmt error marker (i.e. "%!d(string=foo)").
+// Other uses of "%!", like the "100%!" formatted from "100%%!", aren't errors.
+func hasFormatError(result string, vars []interface{}) bool {
+	return len(vars) > 0 && strings.Contains(result, "%!") && formatErrorRe.MatchString(result)
+}
+
+// formatVerbRe matches fmt verbs, with their optional flags, argument indexes, width and precision, and escaped percents
+var formatVerbRe = regexp.MustCompile(`%%|%(\[\d+\])?[+\-# 0]*(\[\d+\])?(\d+|\*)?(\.(\[\d+\])?(\d+|\*)?)?[a-zA-Z]`)
+
+// formatVerbs returns the sorted verb letters of the fmt verbs in str, ignoring escaped percents.
+// Flags, width, precision and argument indexes are dropped, so "%5.2f" and "%[2]f" both give "f".
+func formatVerbs(str string) []string {
+	var verbs []string
+	for _, match := range formatVerbRe.FindAllString(str, -1) {
+		if match != "%%" {
+			verbs = append(verbs, match[len(match)-1:])
+		}
+	}
+	sort.Strings(verbs)
+
+	return verbs
+}
+
+// formatArgCount returns the number of vars used by the fmt verbs of str, taking argument indexes
+// and * widths and precisions into account, i.e. 2 for "%[2]d %[1]s" and for "%*d".
+func formatArgCount(str string) int {
+	argNum, count := 0, 0
+	for _, match := range formatVerbRe.FindAllString(str, -1) {
+		if match == "%%" {
+			continue
+		}
+		for i := 1; i < len(match); i++ {
+			switch {
+			case match[i] == '[':
+				end := i + strings.IndexByte(match[i:], ']')
+				idx, _ := strconv.Atoi(match[i+1 : end])
+				argNum, i = idx-1, end
+			case match[i] == '*' || i == len(match)-1:
+				argNum++
+				if argNum > count {
+					count = argNum
+				}
+			}
+		}
+	}
+
+	return count
+}
+
+// countVars returns vars preceded by n when str and plural both hold a single %d or %v verb, as used by GetCount
+func countVars(str, plural string, n int, vars []interface{}) []interface{} {
+	for _, s := range []string{str, plural} {
+		if verbs := formatVerbs(s); len(verbs) != 1 || (verbs[0] != "d" && verbs[0] != "v") {
+			return vars
+		}
+	}
+
+	return append([]interface{}{n}, vars...)
+}
+
+// namedTokenRe matches {name} style placeholders
+var namedTokenRe = regexp.MustCompile(`\{([a-zA-Z0-9_.]+)\}`)
+
+// formatNamed replaces the {name} placeholders of str with the values of data, formatted with fmt.Sprint.
+// Placeholders without value are left as is.
+func formatNamed(str string, data map[string]interface{}) string {
+	if !strings.Contains(str, "{") {
+		return str
+	}
+
+	return namedTokenRe.ReplaceAllStringFunc(str, func(token string) string {
+		if v, ok := data[token[1:len(token)-1]]; ok {
+			return fmt.Sprint(v)
+		}
+		return token
+	})
+}
+
+// namedCount returns data with n set as "count" and its CLDR plural category for lang as "category",
+// unless data already has them. data isn't modified.
+func namedCount(n int, lang string, data map[string]interface{}) map[string]interface{} {
+	_, hasCount := data["count"]
+	_, hasCategory := data["category"]
+	if hasCount && hasCategory {
+		return data
+	}
+
+	withCount := make(map[string]interface{}, len(data)+2)
+	for k, v := range data {
+		withCount[k] = v
+	}
+	if !hasCount {
+		withCount["count"] = n
+	}
+	if !hasCategory {
+		withCount["category"] = pluralCategory(lang, n)
+	}
+
+	return withCount
+}
+
 // NPrintf support named format
 // NPrintf("%(name)s is Type %(type)s", map[string]interface{}{"name": "Gotext", "type": "struct"})
 func NPrintf(format string, params map[string]interface{}) {
@@ -6,10 +6,30 @@
 package gotext
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
 
+type testStringer struct {
+	name string
+}
+
+func (s *testStringer) String() string {
+	return "stringer " + s.name
+}
+
+// testErrorStringer implements both error and fmt.Stringer
+type testErrorStringer struct{}
+
+func (testErrorStringer) Error() string {
+	return "from Error"
+}
+
+func (testErrorStringer) String() string {
+	return "from String"
+}
+
 func TestSimplifiedLocale(t *testing.T) {
 	tr := SimplifiedLocale("de_DE@euro")
 	if tr != "de_DE" {
@@ -27,6 +47,80 @@ func TestSimplifiedLocale(t *testing.T) {
 	}
 }
 
+func TestCanonicalLocale(t *testing.T) {
+	for input, expected := range map[string]string{
+		"en":               "en",
+		"en-us":            "en_US",
+		" EN_us ":          "en_US",
+		"de_DE.UTF-8":      "de_DE",
+		"el_gr@euro":       "el_GR@euro",
+		"sr-latn-rs@latin": "sr_Latn_RS@latin",
+		"zh-HANT":          "zh_Hant",
+		"es-419":           "es_419",
+		"fil_ph":           "fil_PH",
+	} {
+		if lang, err := CanonicalLocale(input); err != nil || lang != expected {
+			t.Errorf("Expected '%s' for %q but got '%s', '%v'", expected, input, lang, err)
+		}
+	}
+
+	for _, input := range []string{"", "english", "12_34", "e", "en_", "en__US", "en_USA", "en_U1", "en_Latn_US_x",
+		"en_US_GB", "en@", "en@euro!", "_US", "français"} {
+		if lang, err := CanonicalLocale(input); !errors.Is(err, ErrInvalidLocale) {
+			t.Errorf("Expected ErrInvalidLocale for %q but got '%s', '%v'", input, lang, err)
+		}
+	}
+}
+
+func TestSystemLocale(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"LANG": "fr_FR.UTF-8"}, "fr_FR"},
+		{map[string]string{"LANG": "fr_FR.UTF-8", "LC_MESSAGES": "de_DE"}, "de_DE"},
+		{map[string]string{"LANG": "fr_FR", "LC_MESSAGES": "de_DE", "LC_ALL": "es_ES@euro"}, "es_ES"},
+		{map[string]string{"LANG": "fr_FR", "LANGUAGE": "pt_BR:pt:en"}, "pt_BR"},
+		{map[string]string{"LANG": "C.UTF-8", "LANGUAGE": "pt_BR"}, ""},
+		{map[string]string{"LC_ALL": "POSIX", "LANG": "fr_FR"}, ""},
+		{map[string]string{"LANGUAGE": "pt_BR"}, ""},
+	}
+
+	for _, tc := range tests {
+		for _, env := range []string{"LANGUAGE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+			t.Setenv(env, tc.env[env])
+		}
+
+		if lang := SystemLocale(); lang != tc.expected {
+			t.Errorf("Expected '%s' for %v but got '%s'", tc.expected, tc.env, lang)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		header   string
+		expected []LanguageTag
+	}{
+		{"", nil},
+		{"fr", []LanguageTag{{"fr", 1}}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []LanguageTag{{"fr-CH", 1}, {"fr", 0.9}, {"en", 0.8}, {"de", 0.7}, {"*", 0.5}}},
+		{"en;q=0.5,de,fr;q=0.8, es", []LanguageTag{{"de", 1}, {"es", 1}, {"fr", 0.8}, {"en", 0.5}}},
+		{"en;q=0, fr", []LanguageTag{{"fr", 1}}},
+		{"en;q=0.000, *;q=0", nil},
+		{"en;Q=0.3 , fr ; q = 0.4", []LanguageTag{{"fr", 0.4}, {"en", 0.3}}},
+		{"en;q=abc, fr;q=1.5, de;q=-1, it;q=0.1234, es;q=, pt;q=0.2", []LanguageTag{{"pt", 0.2}}},
+		{"en;level=1;q=0.7", []LanguageTag{{"en", 0.7}}},
+		{"en_US, de-DE, ,toolongsubtag, *", []LanguageTag{{"de-DE", 1}, {"*", 1}}},
+	} {
+		tags := ParseAcceptLanguage(tc.header)
+		if !reflect.DeepEqual(tags, tc.expected) {
+			t.Errorf("Expected %v for %q but got %v", tc.expected, tc.header, tags)
+		}
+	}
+}
+
 func TestReformattingSingleNamedPattern(t *testing.T) {
 	pat := "%(name_me)x"
 
@@ -110,3 +204,92 @@ func TestSprintfFloatsWithPrecision(t *testing.T) {
 		t.Errorf("result should be (%v) but is (%v)", expectedresult, s)
 	}
 }
+
+func TestFormatSource(t *testing.T) {
+	Configure(enUSFixture, "fixtures", "en_US", "default")
+
+	// Same as the fallback of the Get* functions, even for translated strings
+	noVars := "No vars: %d"
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{FormatSource("Untranslated %s", "v"), Get("Untranslated %s", "v")},
+		{FormatSource("One with var: %s", "v"), "One with var: v"},
+		{FormatSource(noVars), "No vars: %d"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+}
+
+func TestPrintfErrorsAndStringers(t *testing.T) {
+	var nilErr error
+	var nilStringer *testStringer
+
+	tests := []struct {
+		format   string
+		vars     []interface{}
+		expected string
+	}{
+		{"Failed: %s", []interface{}{errors.New("disk full")}, "Failed: disk full"},
+		{"Failed: %v", []interface{}{errors.New("disk full")}, "Failed: disk full"},
+		{"Failed: %q", []interface{}{errors.New("disk full")}, `Failed: "disk full"`},
+		{"Name: %s", []interface{}{&testStringer{"foo"}}, "Name: stringer foo"},
+		{"Name: %v", []interface{}{&testStringer{"foo"}}, "Name: stringer foo"},
+		{"Both: %s", []interface{}{testErrorStringer{}}, "Both: from Error"},
+		{"Nil: %s", []interface{}{nilErr}, "Nil: %!s(<nil>)"},
+		{"Nil: %s", []interface{}{nilStringer}, "Nil: <nil>"},
+	}
+
+	for _, tc := range tests {
+		if s := Printf(tc.format, tc.vars...); s != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, s)
+		}
+	}
+}
+
+func TestHasFormatError(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		vars     []interface{}
+		expected bool
+	}{
+		{"Hello %s", []interface{}{"Ann"}, false},
+		{"100%%! done by %s", []interface{}{"Ann"}, false},
+		{"Sale: %s", []interface{}{"50%! (today)"}, false},
+		{"Hello %s", []interface{}{"%!d(string=foo)"}, true},
+		{"Hello %d", []interface{}{"Ann"}, true},
+		{"Hello %s %s", []interface{}{"Ann"}, true},
+		{"Hello", []interface{}{"Ann"}, true},
+		{"Hello %[3]s", []interface{}{"Ann"}, true},
+		{"Hello %*d", []interface{}{"Ann", 1}, true},
+		{"Hello %.*d", []interface{}{"Ann", 1}, true},
+		{"Hello %s %", []interface{}{"Ann"}, true},
+		{"Hello %d", []interface{}{nil}, true},
+		{"Hello %!", nil, false},
+	} {
+		result := Printf(tc.format, tc.vars...)
+		if hasFormatError(result, tc.vars) != tc.expected {
+			t.Errorf("Expected %v for '%s' formatted as '%s'", tc.expected, tc.format, result)
+		}
+	}
+}
+
+func TestFormatArgCount(t *testing.T) {
+	for str, expected := range map[string]int{
+		"Hello":             0,
+		"100%% done":        0,
+		"%s has %d apples":  2,
+		"%[2]d %[1]s":       2,
+		"%[3]s":             3,
+		"%[1]s and %[1]q":   1,
+		"%*d":               2,
+		"%-*.*f %s":         4,
+		"%[2]s %s %[1]d %v": 3,
+	} {
+		if count := formatArgCount(str); count != expected {
+			t.Errorf("Expected %d for '%s' but got %d", expected, str, count)
+		}
+	}
+}
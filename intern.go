@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"sync"
+)
+
+// stringPool holds a single copy of equal strings, so the translations parsed with it share their backing storage
+type stringPool struct {
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+func newStringPool() *stringPool {
+	return &stringPool{strings: make(map[string]string)}
+}
+
+// intern returns the pooled copy of s, adding s to the pool if it's new
+func (p *stringPool) intern(s string) string {
+	if s == "" {
+		return s
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pooled, ok := p.strings[s]; ok {
+		return pooled
+	}
+	p.strings[s] = s
+	return s
+}
+
+// internAll replaces every string of list with its pooled copy
+func (p *stringPool) internAll(list []string) {
+	for i, s := range list {
+		list[i] = p.intern(s)
+	}
+}
+
+// intern returns the pooled copy of s when interning is enabled for the domain, or s
+func (do *Domain) intern(s string) string {
+	if do.pool == nil {
+		return s
+	}
+	return do.pool.intern(s)
+}
+
+// internTranslation replaces the source strings of a parsed Translation with their pooled copies, when interning is enabled
+func (do *Domain) internTranslation(trans *Translation) {
+	if do.pool == nil {
+		return
+	}
+
+	// Translations and translator comments are left out, as they're seldom repeated and would only grow the pool
+	trans.ID = do.pool.intern(trans.ID)
+	trans.PluralID = do.pool.intern(trans.PluralID)
+	do.pool.internAll(trans.Refs)
+	do.pool.internAll(trans.Flags)
+	do.pool.internAll(trans.ExtractedComments)
+	do.pool.internAll(trans.Previous)
+}
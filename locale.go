@@ -7,11 +7,22 @@ package gotext
 
 import (
 	"bytes"
-	"embed"
+	"container/list"
+	"context"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"io/fs"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
 	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 /*
@@ -48,10 +59,14 @@ Example:
 */
 type Locale struct {
 	// Path to locale files.
+	// It can hold a list of library roots separated by ':' (like PATH), searched in order.
 	path string
 
-	// Embeded resource to get files
-	resource embed.FS
+	// Additional library roots added with AddLibrary, searched after path.
+	libraries []string
+
+	// Filesystem to get files from, i.e. an embed.FS or os.DirFS("/usr/share/locale")
+	resource fs.FS
 
 	// Language for this Locale
 	lang string
@@ -62,13 +77,77 @@ type Locale struct {
 	// First AddDomain is default Domain
 	defaultDomain string
 
+	// Return an empty string instead of the source string for untranslated messages
+	noSourceFallback bool
+
+	// Called when a formatted string holds a fmt error marker, like "%!d(string=foo)"
+	formatErrorHandler func(id, result string) string
+
+	// Called with the value recovered from a panic by GetSafe and GetNSafe, nil to log it
+	panicHandler func(id string, recovered interface{})
+
+	// How vars are inserted in translations, and the templates parsed with TemplateStyle
+	placeholderStyle PlaceholderStyle
+	templates        *templateCache
+
+	// Treat msgids as keys, resolved through the base language instead of being returned as is
+	keyMode bool
+
+	// Locale of the base language used in key mode, nil when it's the language of this Locale
+	base *Locale
+
+	// Domains being loaded by loadDomain, so concurrent callers wait for a single parse
+	loading map[string]*domainLoad
+
+	// Maximum number of domains loaded on demand kept loaded, 0 for no limit
+	maxLoaded int
+
+	// Domains loaded on demand, most recently used first, with their element in the list
+	lru      *list.List
+	lruElems map[string]*list.Element
+
+	// Number of lookups in progress by domain, which keep it from being evicted
+	pins map[string]int
+
+	// Cache validators of the domains loaded with AddDomainURL, and the client fetching them, nil for the default one
+	remotes    map[string]remoteDomain
+	httpClient *http.Client
+
+	// Files the domains were loaded from, in merge order, see ReloadDomain
+	sources map[string][]domainSource
+
+	// Pool of the strings of parsed domains, nil unless interning is enabled
+	pool *stringPool
+
+	// Separator of the nested directories of domain names, empty for flat domain files
+	domainSeparator string
+
+	// What AddDomain does when the domain is already loaded
+	domainConflict DomainConflictPolicy
+
+	// Look up translations in the environment before the catalogs, see EnableEnvOverrides
+	envOverrides bool
+
+	// Check the number of vars given to the error-returning methods, see SetStrictVars
+	strictVars bool
+
+	// Prefix translations with the source reference of their entry, see AnnotateWithRefs
+	annotateRefs bool
+
+	// Lookup counters by domain, nil unless metrics are enabled
+	metrics *localeMetrics
+
 	// Sync Mutex
 	sync.RWMutex
 }
 
 // NewLocale creates and initializes a new Locale object for a given language.
-// It receives a path for the i18n .po/.mo files directory (p) and a language code to use (l).
-func NewLocale(res embed.FS, p, l string) *Locale {
+// It receives the filesystem the files are read from (res), i.e. an embed.FS, or an os.DirFS for files that
+// can change, a path for the i18n .po/.mo files directory (p) and a language code to use (l).
+// Several library roots can be given in p separated by ':' (i.e. "/usr/local/share/locale:/usr/share/locale"),
+// each domain is loaded from the first root where it's found. Paths in the embedded filesystem always use '/',
+// so the separator is the same on every OS.
+func NewLocale(res fs.FS, p, l string) *Locale {
 	return &Locale{
 		resource: res,
 		path:     p,
@@ -77,57 +156,190 @@ func NewLocale(res embed.FS, p, l string) *Locale {
 	}
 }
 
-func (l *Locale) findExt(dom, ext string) fs.File {
-	filename := path.Join(l.path, l.lang, "LC_MESSAGES", dom+"."+ext)
+// AddLibrary appends a library root to the list of paths searched, in order, when loading domains.
+func (l *Locale) AddLibrary(p string) {
+	l.Lock()
+	l.libraries = append(l.libraries, p)
+	if l.base != nil {
+		l.base.AddLibrary(p)
+	}
+	l.Unlock()
+}
+
+// librarySeparator separates the library roots of a Locale path.
+const librarySeparator = ":"
+
+// libraryPaths returns all library roots in search order.
+func (l *Locale) libraryPaths() []string {
+	l.RLock()
+	defer l.RUnlock()
+
+	roots := strings.Split(l.path, librarySeparator)
+	return append(roots, l.libraries...)
+}
+
+// findFile looks for the domain file on each library root and returns the first one found along with its name and extension.
+func (l *Locale) findFile(dom string) (fs.File, string, string) {
+	for _, root := range l.libraryPaths() {
+		for _, ext := range []string{"po", "mo"} {
+			if file, filename := l.findExt(root, dom, ext); file != nil {
+				return file, filename, ext
+			}
+		}
+	}
+
+	return nil, "", ""
+}
+
+func (l *Locale) findExt(root, dom, ext string) (fs.File, string) {
+	dom = l.domainPath(dom)
+
+	filename := path.Join(root, l.lang, "LC_MESSAGES", dom+"."+ext)
 	if file, err := l.resource.Open(filename); err == nil {
-		return file
+		return file, filename
 	}
 
 	if len(l.lang) > 2 {
-		filename = path.Join(l.path, l.lang[:2], "LC_MESSAGES", dom+"."+ext)
+		filename = path.Join(root, l.lang[:2], "LC_MESSAGES", dom+"."+ext)
 		if file, err := l.resource.Open(filename); err == nil {
-			return file
+			return file, filename
 		}
 	}
 
-	filename = path.Join(l.path, l.lang, dom+"."+ext)
+	filename = path.Join(root, l.lang, dom+"."+ext)
 	if file, err := l.resource.Open(filename); err == nil {
-		return file
+		return file, filename
 	}
 
 	if len(l.lang) > 2 {
-		filename = path.Join(l.path, l.lang[:2], dom+"."+ext)
+		filename = path.Join(root, l.lang[:2], dom+"."+ext)
 		if file, err := l.resource.Open(filename); err == nil {
-			return file
+			return file, filename
 		}
 	}
 
-	return nil
+	return nil, ""
+}
+
+// domainPath returns the path of the domain file, without extension, relative to the language directory.
+func (l *Locale) domainPath(dom string) string {
+	l.RLock()
+	sep := l.domainSeparator
+	l.RUnlock()
+
+	if sep == "" {
+		return dom
+	}
+	return strings.ReplaceAll(dom, sep, "/")
+}
+
+// SetDomainSeparator sets the separator of hierarchical domain names, which are loaded from nested directories:
+// with SetDomainSeparator("."), the domain "app.checkout" is loaded from "app/checkout.mo" (or .po) under LC_MESSAGES.
+// The domain keeps its dotted name in Domains and the Get* methods. An empty separator, the default, loads flat files.
+// Domains loaded before the call aren't reloaded.
+func (l *Locale) SetDomainSeparator(sep string) {
+	l.Lock()
+	l.domainSeparator = sep
+	if l.base != nil {
+		l.base.SetDomainSeparator(sep)
+	}
+	l.Unlock()
+}
+
+// DomainConflictPolicy selects what AddDomain does when the domain is already loaded, set with Locale.SetDomainConflictPolicy
+type DomainConflictPolicy int
+
+const (
+	// ReplaceDomain reloads the domain from its file, dropping the previous translations. It's the default.
+	ReplaceDomain DomainConflictPolicy = iota
+
+	// MergeDomain adds the entries of the file to the loaded domain, replacing those with the same context and msgid.
+	// The header, and so the plural rules, of the loaded domain are kept.
+	MergeDomain
+
+	// ErrorOnConflict keeps the loaded domain: AddDomainE returns ErrDomainConflict, so a misconfigured startup
+	// can fail loudly, and AddDomain logs the conflict.
+	ErrorOnConflict
+)
+
+// SetDomainConflictPolicy sets what AddDomain, AddDomainE and AddDomainFiltered do when the domain is already loaded.
+// Domains added with AddTranslator always replace the loaded one.
+func (l *Locale) SetDomainConflictPolicy(policy DomainConflictPolicy) {
+	l.Lock()
+	l.domainConflict = policy
+	l.Unlock()
 }
 
 // AddDomain creates a new domain for a given locale object and initializes the Po object.
-// If the domain exists, it gets reloaded.
+// If the domain exists, it gets reloaded, unless another policy is set with SetDomainConflictPolicy.
+// With the ErrorOnConflict policy, it logs the conflict and keeps the loaded domain if it exists,
+// use AddDomainE to get ErrDomainConflict instead.
 func (l *Locale) AddDomain(dom string) {
+	l.addDomainLogged(dom, nil)
+}
+
+// AddDomainE works like AddDomain, but it returns ErrDomainNotFound if no file is found for the domain,
+// and ErrDomainConflict instead of logging it if the domain exists with the ErrorOnConflict policy.
+func (l *Locale) AddDomainE(dom string) error {
+	return l.addDomain(dom, nil)
+}
+
+// AddDomainFiltered works like AddDomain, but it only retains the entries whose msgid is accepted by keep,
+// i.e. to trim a large shared catalog down to the messages used by a binary and save memory.
+// The header, and so the plural rules, are always loaded. Obsolete entries are dropped.
+func (l *Locale) AddDomainFiltered(dom string, keep func(id string) bool) {
+	l.addDomainLogged(dom, keep)
+}
+
+// addDomainLogged loads the domain like addDomain, ignoring missing files and logging conflicts.
+func (l *Locale) addDomainLogged(dom string, keep func(id string) bool) {
+	if err := l.addDomain(dom, keep); errors.Is(err, ErrDomainConflict) {
+		log.Printf("%v, keeping the loaded one", err)
+	}
+}
+
+// addDomain loads the domain file, retaining only the entries accepted by keep if it isn't nil.
+func (l *Locale) addDomain(dom string, keep func(id string) bool) error {
 	var poObj Translator
 
-	file := l.findExt(dom, "po")
-	if file != nil {
+	// Fail before parsing, the conflict is checked again when saving the domain
+	if err := l.checkDomainConflict(dom); err != nil {
+		return err
+	}
+
+	file, filename, ext := l.findFile(dom)
+	switch ext {
+	case "po":
 		poObj = NewPo()
-		// Parse file.
-		poObj.ParseFile(file)
-	} else {
-		file = l.findExt(dom, "mo")
-		if file != nil {
-			poObj = NewMo()
-			// Parse file.
-			poObj.ParseFile(file)
-		} else {
-			// fallback return if no file found with
-			return
-		}
+	case "mo":
+		poObj = NewMo()
+	default:
+		// fallback return if no file found with
+		return fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+	}
+
+	l.RLock()
+	poObj.GetDomain().pool = l.pool
+	l.RUnlock()
+
+	// Parse file.
+	poObj.ParseFile(file)
+	if keep != nil {
+		poObj.GetDomain().retain(keep)
 	}
 
-	// Save new domain
+	return l.saveDomain(dom, poObj, domainSource{name: filename, keep: keep})
+}
+
+// domainSource is a file, or a pattern of files, of the Locale resources a domain was loaded from, see ReloadDomain
+type domainSource struct {
+	name string
+	glob bool
+	keep func(id string) bool
+}
+
+// saveDomain makes a domain parsed from src available, following the domain conflict policy if it's already loaded.
+func (l *Locale) saveDomain(dom string, poObj Translator, src domainSource) error {
 	l.Lock()
 
 	if l.Domains == nil {
@@ -136,10 +348,394 @@ func (l *Locale) AddDomain(dom string) {
 	if l.defaultDomain == "" {
 		l.defaultDomain = dom
 	}
+	if prev := l.Domains[dom]; prev != nil && l.domainConflict != ReplaceDomain {
+		l.Unlock()
+		if l.domainConflict == ErrorOnConflict {
+			return fmt.Errorf("%w: %q", ErrDomainConflict, dom)
+		}
+		prev.GetDomain().merge(poObj.GetDomain())
+
+		l.Lock()
+		if _, ok := l.sources[dom]; ok {
+			l.sources[dom] = append(l.sources[dom], src)
+		}
+		l.Unlock()
+		return nil
+	}
 	l.Domains[dom] = poObj
+	if l.sources == nil {
+		l.sources = make(map[string][]domainSource)
+	}
+	l.sources[dom] = []domainSource{src}
 
-	// Unlock "Save new domain"
 	l.Unlock()
+
+	return nil
+}
+
+// AddDomainGlob loads every file of the Locale resources matching pattern, in the syntax of path.Match
+// (i.e. "locales/en/messages/*.po"), into the single domain dom, for catalogs split into a file per feature.
+// Files are merged in filename order: entries of later files replace those with the same context and msgid,
+// and the replaced ones are reported by Domain.FindDuplicates. The header, and so the plural rules, come from the first file.
+// The domain is then added like with AddDomainE, following the policy set with SetDomainConflictPolicy.
+// Files ending in ".mo" are parsed as .mo files, the others as .po files. The files that fail to parse are listed
+// in the returned error, which matches ErrParse, while their valid entries are still loaded.
+// It returns ErrDomainNotFound if no file matches.
+func (l *Locale) AddDomainGlob(dom, pattern string) error {
+	if err := l.checkDomainConflict(dom); err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(l.resource, pattern)
+	if err != nil {
+		return fmt.Errorf("gettext: %v", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+	}
+	sort.Strings(files)
+
+	merged, failed := l.parseFiles(files)
+	if merged != nil {
+		if err := l.saveDomain(dom, merged, domainSource{name: pattern, glob: true}); err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w in %d of %d files: %s", ErrParse, len(failed), len(files), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// parseFiles parses the given files of the Locale resources, as .mo files if their name ends in ".mo"
+// and as .po files otherwise, and merges them in order. It returns the merged domain, nil if no file could be read,
+// along with the errors of the files that failed.
+func (l *Locale) parseFiles(files []string) (Translator, []string) {
+	l.RLock()
+	pool := l.pool
+	l.RUnlock()
+
+	var merged Translator
+	var failed []string
+	for _, name := range files {
+		data, err := fs.ReadFile(l.resource, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed since it was found, i.e. before ReloadDomain
+			continue
+		}
+		if err == nil {
+			var tr Translator
+			if strings.HasSuffix(name, ".mo") {
+				mo := NewMo()
+				mo.domain.pool = pool
+				err = mo.parse(data)
+				tr = mo
+			} else {
+				po := NewPo()
+				po.domain.pool = pool
+				err = po.parse(data)
+				tr = po
+			}
+
+			if merged == nil {
+				merged = tr
+			} else {
+				merged.GetDomain().merge(tr.GetDomain())
+			}
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	return merged, failed
+}
+
+// ReloadDomain parses again the files a domain was loaded from by AddDomain, AddDomainFiltered or AddDomainGlob,
+// or fetches it again if it was loaded with AddDomainURL, and swaps the loaded domain for the new one,
+// i.e. when a file watcher reports a change. Lookups in progress finish with the previous version, and the other
+// domains aren't affected. Glob patterns are matched again, so new files are picked up, and the files merged
+// with the MergeDomain policy are merged again in the same order.
+// The loaded version is kept on errors: it returns ErrDomainNotFound if the domain isn't loaded from files
+// or they're gone, and an error matching ErrParse if one of them fails to parse.
+// A domain evicted by SetMaxLoadedDomains isn't loaded again, it's read from its files on its next use anyway.
+// Files read from the disk, with an os.DirFS, are parsed again with their changes. As files embedded in the binary
+// don't change, reloading them only drops the changes made at runtime, i.e. with AddTranslation.
+func (l *Locale) ReloadDomain(dom string) error {
+	l.RLock()
+	sources := l.sources[dom]
+	remote, isRemote := l.remotes[dom]
+	l.RUnlock()
+
+	if len(sources) == 0 {
+		if isRemote {
+			return l.AddDomainURL(dom, remote.url)
+		}
+		return fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+	}
+
+	var reloaded Translator
+	for _, src := range sources {
+		files := []string{src.name}
+		if src.glob {
+			var err error
+			if files, err = fs.Glob(l.resource, src.name); err != nil {
+				return fmt.Errorf("gettext: %v", err)
+			}
+			sort.Strings(files)
+		}
+
+		tr, failed := l.parseFiles(files)
+		if len(failed) > 0 {
+			return fmt.Errorf("%w in %d of %d files: %s", ErrParse, len(failed), len(files), strings.Join(failed, "; "))
+		}
+		if tr == nil {
+			return fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+		}
+		if src.keep != nil {
+			tr.GetDomain().retain(src.keep)
+		}
+
+		if reloaded == nil {
+			reloaded = tr
+		} else {
+			reloaded.GetDomain().merge(tr.GetDomain())
+		}
+	}
+
+	l.Lock()
+	if _, ok := l.Domains[dom]; ok {
+		l.Domains[dom] = reloaded
+	}
+	l.Unlock()
+
+	return nil
+}
+
+// checkDomainConflict returns ErrDomainConflict if the domain is loaded and the policy is ErrorOnConflict
+func (l *Locale) checkDomainConflict(dom string) error {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.domainConflict == ErrorOnConflict && l.Domains[dom] != nil {
+		return fmt.Errorf("%w: %q", ErrDomainConflict, dom)
+	}
+	return nil
+}
+
+// domainLoad tracks a domain being loaded by loadDomain. done is closed when loading finishes.
+type domainLoad struct {
+	done chan struct{}
+}
+
+// loadDomain loads the domain with AddDomain if it isn't loaded yet.
+// When called concurrently for the same domain, only the first call parses the file while the others wait for it.
+func (l *Locale) loadDomain(dom string) {
+	l.acquireDomain(dom)
+	l.releaseDomain(dom)
+}
+
+// withDomain loads the domain like loadDomain and returns the result of get,
+// keeping the domain from being evicted until get returns.
+func (l *Locale) withDomain(dom string, get func() string) string {
+	l.acquireDomain(dom)
+	defer l.releaseDomain(dom)
+
+	return get()
+}
+
+// acquireDomain loads the domain like loadDomain and pins it, so it isn't evicted until releaseDomain is called.
+func (l *Locale) acquireDomain(dom string) {
+	l.Lock()
+	if l.pins == nil {
+		l.pins = make(map[string]int)
+	}
+	l.pins[dom]++
+
+	if _, ok := l.Domains[dom]; ok {
+		if elem, ok := l.lruElems[dom]; ok {
+			l.lru.MoveToFront(elem)
+		}
+		l.Unlock()
+		return
+	}
+	if load, ok := l.loading[dom]; ok {
+		l.Unlock()
+		<-load.done
+		return
+	}
+
+	load := &domainLoad{done: make(chan struct{})}
+	if l.loading == nil {
+		l.loading = make(map[string]*domainLoad)
+	}
+	l.loading[dom] = load
+	l.Unlock()
+
+	l.AddDomain(dom)
+
+	l.Lock()
+	delete(l.loading, dom)
+	if _, ok := l.Domains[dom]; ok {
+		if l.lru == nil {
+			l.lru = list.New()
+			l.lruElems = make(map[string]*list.Element)
+		}
+		if _, ok := l.lruElems[dom]; !ok {
+			l.lruElems[dom] = l.lru.PushFront(dom)
+		}
+		l.evictDomains()
+	}
+	l.Unlock()
+	close(load.done)
+}
+
+// releaseDomain unpins a domain pinned by acquireDomain, evicting domains if the limit is exceeded.
+func (l *Locale) releaseDomain(dom string) {
+	l.Lock()
+	if l.pins[dom]--; l.pins[dom] <= 0 {
+		delete(l.pins, dom)
+	}
+	l.evictDomains()
+	l.Unlock()
+}
+
+// setMaxLoadedDomains limits the number of domains loaded on demand kept loaded, see SetMaxLoadedDomains.
+// Only the Locales of the package level functions load domains on demand, with withDomain and loadDomain.
+func (l *Locale) setMaxLoadedDomains(n int) {
+	l.Lock()
+	l.maxLoaded = n
+	l.evictDomains()
+	l.Unlock()
+}
+
+// evictDomains unloads the least recently used domains loaded on demand, except the pinned ones,
+// until their count is within the limit. The Locale must be locked.
+func (l *Locale) evictDomains() {
+	if l.maxLoaded <= 0 || l.lru == nil {
+		return
+	}
+
+	for elem := l.lru.Back(); elem != nil && l.lru.Len() > l.maxLoaded; {
+		prev := elem.Prev()
+		if dom := elem.Value.(string); l.pins[dom] == 0 {
+			l.lru.Remove(elem)
+			delete(l.lruElems, dom)
+			delete(l.Domains, dom)
+		}
+		elem = prev
+	}
+}
+
+// remoteDomain holds the HTTP cache validators of a domain loaded from an URL
+type remoteDomain struct {
+	url          string
+	etag         string
+	lastModified string
+}
+
+// DefaultHTTPTimeout is the timeout of the requests of AddDomainURL, unless another client is set with SetHTTPClient
+const DefaultHTTPTimeout = 30 * time.Second
+
+// defaultHTTPClient is the client of AddDomainURL, unless another one is set with SetHTTPClient
+var defaultHTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
+// SetHTTPClient sets the client used by AddDomainURL, AddDomainURLContext and ReloadDomain to fetch domains,
+// i.e. to change the timeout or use a proxy. nil restores the default client, whose timeout is DefaultHTTPTimeout.
+func (l *Locale) SetHTTPClient(client *http.Client) {
+	l.Lock()
+	l.httpClient = client
+	l.Unlock()
+}
+
+// AddDomainURL fetches a .po or .mo file over HTTP and makes it available as the given domain.
+// The format is detected from the content. Calling it again for the same domain and url makes a conditional request
+// using the ETag and Last-Modified headers of the previous response, and the domain is only reloaded if it changed.
+// On network, HTTP or parse errors, the previously loaded version of the domain is kept.
+// The request times out after DefaultHTTPTimeout, see SetHTTPClient, and AddDomainURLContext to cancel it.
+func (l *Locale) AddDomainURL(dom, url string) error {
+	return l.AddDomainURLContext(context.Background(), dom, url)
+}
+
+// AddDomainURLContext works like AddDomainURL, the request being canceled when ctx is done.
+// The returned error then matches the error of ctx with errors.Is.
+func (l *Locale) AddDomainURLContext(ctx context.Context, dom, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("gettext: %v", err)
+	}
+
+	l.RLock()
+	remote, loaded := l.remotes[dom]
+	_, exists := l.Domains[dom]
+	client := l.httpClient
+	l.RUnlock()
+
+	if client == nil {
+		client = defaultHTTPClient
+	}
+
+	if loaded && exists && remote.url == url {
+		if remote.etag != "" {
+			req.Header.Set("If-None-Match", remote.etag)
+		}
+		if remote.lastModified != "" {
+			req.Header.Set("If-Modified-Since", remote.lastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gettext: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("gettext: fetching %s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("gettext: %v", err)
+	}
+
+	l.RLock()
+	pool := l.pool
+	l.RUnlock()
+
+	var tr Translator
+	if len(data) >= 4 && (binary.LittleEndian.Uint32(data) == MoMagicLittleEndian || binary.LittleEndian.Uint32(data) == MoMagicBigEndian) {
+		mo := NewMo()
+		mo.domain.pool = pool
+		err = mo.parse(data)
+		tr = mo
+	} else {
+		po := NewPo()
+		po.domain.pool = pool
+		err = po.parse(data)
+		tr = po
+	}
+	if err != nil {
+		return err
+	}
+
+	l.AddTranslator(dom, tr)
+
+	l.Lock()
+	if l.remotes == nil {
+		l.remotes = make(map[string]remoteDomain)
+	}
+	l.remotes[dom] = remoteDomain{
+		url:          url,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	l.Unlock()
+
+	return nil
 }
 
 // AddTranslator takes a domain name and a Translator object to make it available in the Locale object.
@@ -153,10 +749,44 @@ func (l *Locale) AddTranslator(dom string, tr Translator) {
 		l.defaultDomain = dom
 	}
 	l.Domains[dom] = tr
+	delete(l.sources, dom)
 
 	l.Unlock()
 }
 
+// AddTranslation sets the Translation of str in the given domain and context, i.e. to inject a few translations
+// in tests without a fixture file. The domain is loaded first if it isn't yet, or created empty if it has no file.
+// Once changed, the domain is never evicted by SetMaxLoadedDomains, so the Translation isn't lost.
+func (l *Locale) AddTranslation(dom, str, ctx, translation string) {
+	l.loadDomain(dom)
+
+	l.Lock()
+	if l.Domains == nil {
+		l.Domains = make(map[string]Translator)
+	}
+	if l.defaultDomain == "" {
+		l.defaultDomain = dom
+	}
+	tr := l.Domains[dom]
+	if tr == nil {
+		po := NewPo()
+		po.domain.Language = l.lang
+		tr = po
+		l.Domains[dom] = tr
+	}
+	if elem, ok := l.lruElems[dom]; ok {
+		l.lru.Remove(elem)
+		delete(l.lruElems, dom)
+	}
+	l.Unlock()
+
+	if ctx == "" {
+		tr.GetDomain().Set(str, translation)
+	} else {
+		tr.GetDomain().SetC(str, ctx, translation)
+	}
+}
+
 // GetDomain is the domain getter for Locale configuration
 func (l *Locale) GetDomain() string {
 	l.RLock()
@@ -172,6 +802,107 @@ func (l *Locale) SetDomain(dom string) {
 	l.Unlock()
 }
 
+// SetFallbackToSource sets whether the Get* methods return the (formatted) source string when no Translation is found.
+// It defaults to true. When disabled, untranslated messages, including plural and context variants and missing domains,
+// return an empty string so they can be detected with == "".
+func (l *Locale) SetFallbackToSource(fallback bool) {
+	l.Lock()
+	l.noSourceFallback = !fallback
+	l.Unlock()
+}
+
+// SetStrictVars sets whether GetE and GetDE check that the number of vars matches the fmt verbs of the Translation,
+// returning the formatted string along with an error matching ErrArgMismatch when it doesn't, instead of
+// the %!(EXTRA ...) or %!s(MISSING) markers left by fmt. It's meant to catch call-site bugs in development,
+// and only applies to the PrintfStyle placeholder style.
+func (l *Locale) SetStrictVars(strict bool) {
+	l.Lock()
+	l.strictVars = strict
+	l.Unlock()
+}
+
+// SetKeyMode sets whether msgids are symbolic keys (i.e. "checkout.button.pay") rather than source text.
+// In key mode, a key without Translation is resolved through the same domain of the base language set with SetBaseLanguage,
+// which holds the source text of every key, and an empty string is returned if it's missing there too:
+// keys are never returned as is, whatever SetFallbackToSource says. The fallback given to GetOr is still used.
+func (l *Locale) SetKeyMode(enabled bool) {
+	l.Lock()
+	l.keyMode = enabled
+	l.Unlock()
+}
+
+// SetBaseLanguage sets the language code of the catalogs holding the source text of every key, used in key mode.
+// Its domains are loaded from the same library as this Locale, on first use.
+func (l *Locale) SetBaseLanguage(lang string) {
+	lang = SimplifiedLocale(lang)
+
+	l.Lock()
+	defer l.Unlock()
+
+	if lang == "" || lang == l.lang {
+		l.base = nil
+		return
+	}
+
+	l.base = NewLocale(l.resource, l.path, lang)
+	l.base.libraries = append([]string(nil), l.libraries...)
+	l.base.pool = l.pool
+	l.base.domainSeparator = l.domainSeparator
+}
+
+// EnableInterning sets whether the domains loaded afterwards share a single copy of equal strings,
+// like msgids found in the catalogs of several languages, repeated context names, flags and source references.
+// Translated strings aren't interned. It trades some parsing time for memory, and doesn't change the translations.
+// The pool belongs to the Locale, and to its base language in key mode, so it's freed along with them.
+// Loading catalogs of the same 2000 msgids in 5 contexts for 4 languages with one pool (see BenchmarkPo_ParseInterning)
+// retains about 9% less memory, pool included, and parsing is about 8% slower. The savings grow with the number of
+// catalogs sharing the pool, and with the length of msgids compared to translations.
+func (l *Locale) EnableInterning(enabled bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if !enabled {
+		l.pool = nil
+	} else if l.pool == nil {
+		l.pool = newStringPool()
+	}
+	if l.base != nil {
+		l.base.Lock()
+		l.base.pool = l.pool
+		l.base.Unlock()
+	}
+}
+
+// getKey resolves a message in key mode, from this Locale and then from the base language.
+// get looks the message up in a Domain, see Domain.getResult. The Text of the result is empty if the message is
+// missing from both, and UsedFallback is set unless it's translated in this Locale. The Locale must be read-locked.
+func (l *Locale) getKey(dom string, get func(do *Domain) TranslationResult) TranslationResult {
+	if tr, ok := l.Domains[dom]; ok && tr != nil {
+		if res := get(tr.GetDomain()); res.Translated {
+			res.Language = l.lang
+			return res
+		}
+	}
+
+	res := TranslationResult{UsedFallback: true}
+	if l.base == nil {
+		return res
+	}
+
+	l.base.loadDomain(dom)
+
+	l.base.RLock()
+	defer l.base.RUnlock()
+
+	if tr, ok := l.base.Domains[dom]; ok && tr != nil {
+		if base := get(tr.GetDomain()); base.Translated {
+			base.Language, base.UsedFallback = l.base.lang, true
+			return base
+		}
+	}
+	return res
+}
+
 // Get uses a domain "default" to return the corresponding Translation of a given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
 func (l *Locale) Get(str string, vars ...interface{}) string {
@@ -184,44 +915,377 @@ func (l *Locale) GetN(str, plural string, n int, vars ...interface{}) string {
 	return l.GetND(l.GetDomain(), str, plural, n, vars...)
 }
 
+// GetCount works like GetN, but it inserts n into the Translation when the singular and plural strings both hold
+// a single fmt verb, %d or %v (with optional flags and width), so GetCount("%d item", "%d items", n) replaces
+// GetN("%d item", "%d items", n, n). Otherwise, like for "One item" or "%d items in %s", vars are used as given.
+func (l *Locale) GetCount(str, plural string, n int, vars ...interface{}) string {
+	return l.GetN(str, plural, n, countVars(str, plural, n, vars)...)
+}
+
 // GetD returns the corresponding Translation in the given domain for the given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
-func (l *Locale) GetD(dom, str string, vars ...interface{}) string {
+func (l *Locale) GetD(dom, str string, vars ...interface{}) (result string) {
 	// Sync read
 	l.RLock()
 	defer l.RUnlock()
 
-	if l.Domains != nil {
-		if _, ok := l.Domains[dom]; ok {
-			if l.Domains[dom] != nil {
-				return l.Domains[dom].Get(str, vars...)
-			}
+	if l.annotateRefs {
+		defer func() { result = l.annotate(dom, str, "", result) }()
+	}
+	args := l.printfArgs(vars)
+
+	if tr, ok := l.envOverride(dom, "", str); ok {
+		l.recordLookup(dom, TranslationResult{Translated: true})
+		return l.checkFormat(str, Printf(tr, args...), vars)
+	}
+
+	if l.keyMode {
+		res := l.getKey(dom, func(do *Domain) TranslationResult {
+			return do.getResult(str, "", 0, nil, false, args)
+		})
+		l.recordLookup(dom, res)
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	if tr := l.Domains[dom]; tr != nil {
+		res := tr.GetDomain().getResult(str, "", 0, nil, false, args)
+		l.recordLookup(dom, res)
+		if l.noSourceFallback && !res.Translated {
+			return ""
+		}
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	l.recordLookup(dom, TranslationResult{UsedFallback: true})
+	if l.noSourceFallback {
+		return ""
+	}
+	return l.checkFormat(str, Printf(str, args...), vars)
+}
+
+// AnnotateWithRefs sets whether the GetD, GetND, GetDC and GetNDC methods, and the methods built on them, prefix
+// the strings they return with the first source reference of their entry, i.e. "[ui/login.go:42] Welcome",
+// to find out which code shows a string on screen. It's a debugging aid that should never be enabled in production.
+// Strings without entry, or whose entry has no reference, and empty strings are returned as is.
+func (l *Locale) AnnotateWithRefs(enabled bool) {
+	l.Lock()
+	l.annotateRefs = enabled
+	l.Unlock()
+}
+
+// annotate prefixes text, returned by a lookup of str in the given domain and context, with the first source reference
+// of its entry, see AnnotateWithRefs. The Locale must be read-locked.
+func (l *Locale) annotate(dom, str, ctx, text string) string {
+	tr := l.Domains[dom]
+	if tr == nil || text == "" {
+		return text
+	}
+	if ref := tr.GetDomain().sourceRef(str, ctx); ref != "" {
+		return "[" + ref + "] " + text
+	}
+	return text
+}
+
+// EnvOverridePrefix is the prefix of the environment variables read when Locale.EnableEnvOverrides is set
+const EnvOverridePrefix = "GOTEXT_OVERRIDE_"
+
+// EnableEnvOverrides sets whether the GetD, GetND, GetDC and GetNDC methods, and the methods built on them, look up
+// translations in the environment before the catalogs. It's a debugging and QA feature, to tweak a single string
+// without rebuilding or editing catalogs, and it's off by default: don't enable it in production.
+// The variables are named as returned by EnvOverrideName, i.e. GOTEXT_OVERRIDE_default_Hello=Bonjour.
+// An override is used for every plural form, and it's formatted with the vars like a Translation.
+func (l *Locale) EnableEnvOverrides(enabled bool) {
+	l.Lock()
+	l.envOverrides = enabled
+	l.Unlock()
+}
+
+// envOverride returns the Translation of str set in the environment, and whether there's one.
+// The Locale must be read-locked.
+func (l *Locale) envOverride(dom, ctx, str string) (string, bool) {
+	if !l.envOverrides {
+		return "", false
+	}
+	return os.LookupEnv(EnvOverrideName(dom, ctx, str))
+}
+
+// EnvOverrideName returns the name of the environment variable overriding the Translation of str in the given domain
+// and context, empty for none, when Locale.EnableEnvOverrides is set. It's EnvOverridePrefix followed by the encoded
+// domain, an underscore and the encoded msgid, prefixed with the context and an EOT byte ("\x04") when there's one.
+// Domains and msgids are encoded byte by byte: ASCII letters and digits are kept and any other byte is written as
+// an underscore followed by its value in two uppercase hexadecimal digits, so "Hello, world!" in the "default" domain
+// gives GOTEXT_OVERRIDE_default_Hello_2C_20world_21.
+func EnvOverrideName(dom, ctx, str string) string {
+	if ctx != "" {
+		str = ctx + EotSeparator + str
+	}
+	return EnvOverridePrefix + encodeEnvName(dom) + "_" + encodeEnvName(str)
+}
+
+// encodeEnvName encodes s to be used in an environment variable name, see EnvOverrideName
+func encodeEnvName(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			buf.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&buf, "_%02X", c)
+	}
+	return buf.String()
+}
+
+// SetFormatErrorHandler sets a function called when the result of a Get* method holds a fmt error marker
+// (like "%!d(string=foo)") because of a bad verb or wrong arguments. It receives the source string (id)
+// and the formatted result, and returns the string to use instead, i.e. after logging the error.
+// The handler is called while the Locale is read-locked, so it must not call methods of the Locale.
+// Set it to nil to return the formatted result as is, which is the default.
+func (l *Locale) SetFormatErrorHandler(handler func(id, result string) string) {
+	l.Lock()
+	l.formatErrorHandler = handler
+	l.Unlock()
+}
+
+// SetPanicHandler sets a function called by GetSafe and GetNSafe when translating a string panics,
+// with the source string (id) and the value recovered from the panic, i.e. to report it.
+// Set it to nil to write the panic with the standard logger, which is the default.
+func (l *Locale) SetPanicHandler(handler func(id string, recovered interface{})) {
+	l.Lock()
+	l.panicHandler = handler
+	l.Unlock()
+}
+
+// GetSafe works like Get, but it never panics: if anything goes wrong while translating the string,
+// i.e. a broken Translator or a panicking format error handler, the panic is recovered and reported
+// to the panic handler, and the source string is returned as is, without inserting vars.
+// It's meant for servers, where a translation bug must not take down a request.
+func (l *Locale) GetSafe(str string, vars ...interface{}) (tr string) {
+	defer l.recoverGet(str, str, &tr)
+	return l.Get(str, vars...)
+}
+
+// GetNSafe works like GetN, but it never panics, returning str or plural as is according to n,
+// with the Germanic plural rule, if translating panics. See GetSafe.
+func (l *Locale) GetNSafe(str, plural string, n int, vars ...interface{}) (tr string) {
+	source := str
+	if n != 1 {
+		source = plural
+	}
+	defer l.recoverGet(str, source, &tr)
+	return l.GetN(str, plural, n, vars...)
+}
+
+// recoverGet recovers from a panic of GetSafe or GetNSafe, reporting it for id to the panic handler and setting tr to source.
+// It must be deferred. A panic of the handler itself is dropped.
+func (l *Locale) recoverGet(id, source string, tr *string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	*tr = source
+
+	l.RLock()
+	handler := l.panicHandler
+	l.RUnlock()
+
+	if handler == nil {
+		log.Printf("gotext: recovered from panic translating %q: %v", id, recovered)
+		return
+	}
+
+	defer func() {
+		_ = recover()
+	}()
+	handler(id, recovered)
+}
+
+// SetPlaceholderStyle sets how the vars given to the Get* methods are inserted in translations, for every domain.
+// With PrintfStyle, the default, all the vars are used with the fmt.Printf syntax. With NamedBraceStyle and TemplateStyle,
+// only the last var is used, as the data holding the placeholder values; see their documentation.
+// The format error handler is also called when the last var can't be used by the style.
+func (l *Locale) SetPlaceholderStyle(style PlaceholderStyle) {
+	l.Lock()
+	l.placeholderStyle = style
+	l.templates = nil
+	if style == TemplateStyle {
+		l.templates = &templateCache{}
+	}
+	l.Unlock()
+}
+
+// printfArgs returns the vars to format with fmt, according to the placeholder style. The Locale must be read-locked.
+func (l *Locale) printfArgs(vars []interface{}) []interface{} {
+	return l.placeholderStyle.printfArgs(vars)
+}
+
+// checkFormat inserts vars in result for the non printf placeholder styles,
+// and passes result through the format error handler when formatting vars failed.
+// The Locale must be read-locked.
+func (l *Locale) checkFormat(id, result string, vars []interface{}) string {
+	if l.placeholderStyle != PrintfStyle {
+		interpolated, err := l.placeholderStyle.interpolate(result, vars, l.templates)
+		if err != nil && l.formatErrorHandler != nil {
+			return l.formatErrorHandler(id, interpolated)
+		}
+		return interpolated
+	}
+
+	if l.formatErrorHandler != nil && hasFormatError(result, vars) {
+		return l.formatErrorHandler(id, result)
+	}
+	return result
+}
+
+// HasTranslation reports whether the given string has a Translation in the given domain and context,
+// with an empty context meaning none: the entry must exist, have a non-empty msgstr (or first plural form)
+// and not be flagged as fuzzy. It doesn't load the domain nor look up the base language in key mode,
+// so it's a cheap check to show UI elements only when they're localized.
+func (l *Locale) HasTranslation(dom, str, ctx string) bool {
+	l.RLock()
+	tr, ok := l.Domains[dom]
+	l.RUnlock()
+
+	if !ok || tr == nil {
+		return false
+	}
+
+	return tr.GetDomain().hasTranslation(str, ctx)
+}
+
+// GetE is like Get, but returns an error instead of falling back to the source string:
+// ErrNoConfig if no default domain is set, ErrDomainNotFound if it isn't loaded
+// and ErrMsgIDNotFound if the string has no Translation.
+func (l *Locale) GetE(str string, vars ...interface{}) (string, error) {
+	dom := l.GetDomain()
+	if dom == "" {
+		return "", ErrNoConfig
+	}
+
+	return l.GetDE(dom, str, vars...)
+}
+
+// GetDE is like GetD, but returns ErrDomainNotFound or ErrMsgIDNotFound instead of falling back to the source string.
+// If formatting vars fails, the formatted string is returned along with ErrFormat, or ErrArgMismatch in strict vars mode.
+func (l *Locale) GetDE(dom, str string, vars ...interface{}) (string, error) {
+	l.RLock()
+	tr, ok := l.Domains[dom]
+	style, templates, strict := l.placeholderStyle, l.templates, l.strictVars
+	l.RUnlock()
+
+	if !ok || tr == nil {
+		return "", fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+	}
+	if !tr.GetDomain().IsTranslated(str) {
+		return "", fmt.Errorf("%w: %q", ErrMsgIDNotFound, str)
+	}
+
+	result := tr.Get(str, style.printfArgs(vars)...)
+	if strict && style == PrintfStyle {
+		if expected := formatArgCount(tr.Get(str)); expected != len(vars) {
+			return result, fmt.Errorf("%w: %q expects %d vars but got %d", ErrArgMismatch, str, expected, len(vars))
+		}
+	}
+	if style != PrintfStyle {
+		result, err := style.interpolate(result, vars, templates)
+		if err != nil {
+			return result, fmt.Errorf("%w: %v", ErrFormat, err)
 		}
+		return result, nil
 	}
+	if hasFormatError(result, vars) {
+		return result, fmt.Errorf("%w: %q", ErrFormat, result)
+	}
+
+	return result, nil
+}
+
+// GetNamedN retrieves the (N)th plural form of Translation for the given string in the default domain,
+// and replaces its {name} style placeholders with the values of data, i.e. "You have {count} new messages".
+// n is available as "count", and its CLDR plural category for the language of the Locale ("one", "few"...)
+// as "category", unless data sets them. Placeholders without value are left as is.
+func (l *Locale) GetNamedN(str, plural string, n int, data map[string]interface{}) string {
+	l.RLock()
+	lang := l.lang
+	l.RUnlock()
 
-	return Printf(str, vars...)
+	return formatNamed(l.GetN(str, plural, n), namedCount(n, lang, data))
+}
+
+// GetOr is like Get, but formats fallback instead of str when str has no Translation in the default domain.
+// It's meant for catalogs using short keys as msgid (i.e. "err.not_found") rather than real text.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (l *Locale) GetOr(str, fallback string, vars ...interface{}) string {
+	return l.GetDOr(l.GetDomain(), str, fallback, vars...)
+}
+
+// GetDOr is like GetD, but formats fallback instead of str when str has no Translation in the given domain,
+// or in the base language in key mode. The fallback is used even if SetFallbackToSource(false) was called.
+func (l *Locale) GetDOr(dom, str, fallback string, vars ...interface{}) string {
+	l.RLock()
+	defer l.RUnlock()
+
+	args := l.printfArgs(vars)
+
+	if tr, ok := l.Domains[dom]; ok && tr != nil && tr.GetDomain().IsTranslated(str) {
+		return l.checkFormat(str, tr.Get(str, args...), vars)
+	}
+
+	if l.keyMode {
+		if res := l.getKey(dom, func(do *Domain) TranslationResult {
+			return do.getResult(str, "", 0, nil, false, args)
+		}); res.Text != "" {
+			return l.checkFormat(str, res.Text, vars)
+		}
+	}
+
+	return l.checkFormat(str, Printf(fallback, args...), vars)
 }
 
 // GetND retrieves the (N)th plural form of Translation in the given domain for the given string.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
-func (l *Locale) GetND(dom, str, plural string, n int, vars ...interface{}) string {
+func (l *Locale) GetND(dom, str, plural string, n int, vars ...interface{}) (result string) {
 	// Sync read
 	l.RLock()
 	defer l.RUnlock()
 
-	if l.Domains != nil {
-		if _, ok := l.Domains[dom]; ok {
-			if l.Domains[dom] != nil {
-				return l.Domains[dom].GetN(str, plural, n, vars...)
-			}
+	if l.annotateRefs {
+		defer func() { result = l.annotate(dom, str, "", result) }()
+	}
+	args := l.printfArgs(vars)
+
+	if tr, ok := l.envOverride(dom, "", str); ok {
+		l.recordLookup(dom, TranslationResult{Translated: true})
+		return l.checkFormat(str, Printf(tr, args...), vars)
+	}
+
+	if l.keyMode {
+		res := l.getKey(dom, func(do *Domain) TranslationResult {
+			return do.getResult(str, plural, n, nil, true, args)
+		})
+		l.recordLookup(dom, res)
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	if tr := l.Domains[dom]; tr != nil {
+		res := tr.GetDomain().getResult(str, plural, n, nil, true, args)
+		l.recordLookup(dom, res)
+		if l.noSourceFallback && !res.Translated {
+			return ""
 		}
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	l.recordLookup(dom, TranslationResult{UsedFallback: true})
+	if l.noSourceFallback {
+		return ""
 	}
 
 	// Use western default rule (plural > 1) to handle missing domain default result.
 	if n == 1 {
-		return Printf(str, vars...)
+		return l.checkFormat(str, Printf(str, args...), vars)
 	}
-	return Printf(plural, vars...)
+	return l.checkFormat(str, Printf(plural, args...), vars)
 }
 
 // GetC uses a domain "default" to return the corresponding Translation of the given string in the given context.
@@ -238,42 +1302,182 @@ func (l *Locale) GetNC(str, plural string, n int, ctx string, vars ...interface{
 
 // GetDC returns the corresponding Translation in the given domain for the given string in the given context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
-func (l *Locale) GetDC(dom, str, ctx string, vars ...interface{}) string {
+func (l *Locale) GetDC(dom, str, ctx string, vars ...interface{}) (result string) {
 	// Sync read
 	l.RLock()
 	defer l.RUnlock()
 
-	if l.Domains != nil {
-		if _, ok := l.Domains[dom]; ok {
-			if l.Domains[dom] != nil {
-				return l.Domains[dom].GetC(str, ctx, vars...)
-			}
+	if l.annotateRefs {
+		defer func() { result = l.annotate(dom, str, ctx, result) }()
+	}
+	args := l.printfArgs(vars)
+
+	if tr, ok := l.envOverride(dom, ctx, str); ok {
+		l.recordLookup(dom, TranslationResult{Translated: true})
+		return l.checkFormat(str, Printf(tr, args...), vars)
+	}
+
+	if l.keyMode {
+		res := l.getKey(dom, func(do *Domain) TranslationResult {
+			return do.getResult(str, "", 0, &ctx, false, args)
+		})
+		l.recordLookup(dom, res)
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	if tr := l.Domains[dom]; tr != nil {
+		res := tr.GetDomain().getResult(str, "", 0, &ctx, false, args)
+		l.recordLookup(dom, res)
+		if l.noSourceFallback && !res.Translated {
+			return ""
 		}
+		return l.checkFormat(str, res.Text, vars)
 	}
 
-	return Printf(str, vars...)
+	l.recordLookup(dom, TranslationResult{UsedFallback: true})
+	if l.noSourceFallback {
+		return ""
+	}
+	return l.checkFormat(str, Printf(str, args...), vars)
 }
 
 // GetNDC retrieves the (N)th plural form of Translation in the given domain for the given string in the given context.
 // Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
-func (l *Locale) GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string {
+func (l *Locale) GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) (result string) {
 	// Sync read
 	l.RLock()
 	defer l.RUnlock()
 
-	if l.Domains != nil {
-		if _, ok := l.Domains[dom]; ok {
-			if l.Domains[dom] != nil {
-				return l.Domains[dom].GetNC(str, plural, n, ctx, vars...)
-			}
+	if l.annotateRefs {
+		defer func() { result = l.annotate(dom, str, ctx, result) }()
+	}
+	args := l.printfArgs(vars)
+
+	if tr, ok := l.envOverride(dom, ctx, str); ok {
+		l.recordLookup(dom, TranslationResult{Translated: true})
+		return l.checkFormat(str, Printf(tr, args...), vars)
+	}
+
+	if l.keyMode {
+		res := l.getKey(dom, func(do *Domain) TranslationResult {
+			return do.getResult(str, plural, n, &ctx, true, args)
+		})
+		l.recordLookup(dom, res)
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	if tr := l.Domains[dom]; tr != nil {
+		res := tr.GetDomain().getResult(str, plural, n, &ctx, true, args)
+		l.recordLookup(dom, res)
+		if l.noSourceFallback && !res.Translated {
+			return ""
+		}
+		return l.checkFormat(str, res.Text, vars)
+	}
+
+	l.recordLookup(dom, TranslationResult{UsedFallback: true})
+	if l.noSourceFallback {
+		return ""
+	}
+
+	// Use western default rule (plural > 1) to handle missing domain default result.
+	if n == 1 {
+		return l.checkFormat(str, Printf(str, args...), vars)
+	}
+	return l.checkFormat(str, Printf(plural, args...), vars)
+}
+
+// GetPreferred returns the Translation of str from the first of the given domains which has it translated,
+// i.e. an app-specific override domain before a shared base domain.
+// If none has it, the source string is returned, unless SetFallbackToSource(false) was set.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (l *Locale) GetPreferred(domains []string, str string, vars ...interface{}) string {
+	l.RLock()
+	defer l.RUnlock()
+
+	args := l.printfArgs(vars)
+
+	for _, dom := range domains {
+		if tr, ok := l.Domains[dom]; ok && tr != nil && tr.GetDomain().IsTranslated(str) {
+			return l.checkFormat(str, tr.Get(str, args...), vars)
+		}
+	}
+
+	if l.noSourceFallback {
+		return ""
+	}
+	return l.checkFormat(str, Printf(str, args...), vars)
+}
+
+// GetPreferredN retrieves the (N)th plural form of Translation of str from the first of the given domains which has it translated.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (l *Locale) GetPreferredN(domains []string, str, plural string, n int, vars ...interface{}) string {
+	l.RLock()
+	defer l.RUnlock()
+
+	args := l.printfArgs(vars)
+
+	for _, dom := range domains {
+		if tr, ok := l.Domains[dom]; ok && tr != nil && tr.GetDomain().IsTranslatedN(str, n) {
+			return l.checkFormat(str, tr.GetN(str, plural, n, args...), vars)
+		}
+	}
+
+	if l.noSourceFallback {
+		return ""
+	}
+
+	// Use western default rule (plural > 1) to handle missing domain default result.
+	if n == 1 {
+		return l.checkFormat(str, Printf(str, args...), vars)
+	}
+	return l.checkFormat(str, Printf(plural, args...), vars)
+}
+
+// GetPreferredC returns the Translation of str in the given context from the first of the given domains which has it translated.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (l *Locale) GetPreferredC(domains []string, str, ctx string, vars ...interface{}) string {
+	l.RLock()
+	defer l.RUnlock()
+
+	args := l.printfArgs(vars)
+
+	for _, dom := range domains {
+		if tr, ok := l.Domains[dom]; ok && tr != nil && tr.GetDomain().IsTranslatedC(str, ctx) {
+			return l.checkFormat(str, tr.GetC(str, ctx, args...), vars)
+		}
+	}
+
+	if l.noSourceFallback {
+		return ""
+	}
+	return l.checkFormat(str, Printf(str, args...), vars)
+}
+
+// GetPreferredNC retrieves the (N)th plural form of Translation of str in the given context
+// from the first of the given domains which has it translated.
+// Supports optional parameters (vars... interface{}) to be inserted on the formatted string using the fmt.Printf syntax.
+func (l *Locale) GetPreferredNC(domains []string, str, plural string, n int, ctx string, vars ...interface{}) string {
+	l.RLock()
+	defer l.RUnlock()
+
+	args := l.printfArgs(vars)
+
+	for _, dom := range domains {
+		if tr, ok := l.Domains[dom]; ok && tr != nil && tr.GetDomain().IsTranslatedNC(str, n, ctx) {
+			return l.checkFormat(str, tr.GetNC(str, plural, n, ctx, args...), vars)
 		}
 	}
 
+	if l.noSourceFallback {
+		return ""
+	}
+
 	// Use western default rule (plural > 1) to handle missing domain default result.
 	if n == 1 {
-		return Printf(str, vars...)
+		return l.checkFormat(str, Printf(str, args...), vars)
 	}
-	return Printf(plural, vars...)
+	return l.checkFormat(str, Printf(plural, args...), vars)
 }
 
 //GetTranslations returns a copy of all translations in all domains of this locale. It does not support contexts.
@@ -291,6 +1495,44 @@ func (l *Locale) GetTranslations() map[string]*Translation {
 	return all
 }
 
+// DomainNames returns the sorted names of the domains loaded in the Locale.
+// Unlike ranging over Domains, it's safe to call while domains are being loaded by other goroutines.
+func (l *Locale) DomainNames() []string {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.domainNames()
+}
+
+// domainNames returns the sorted names of the loaded domains. The Locale must be read-locked.
+func (l *Locale) domainNames() []string {
+	names := make([]string, 0, len(l.Domains))
+	for name := range l.Domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String implements the fmt.Stringer interface.
+// It returns a short summary of the locale: the language, default domain and the loaded domains with their message counts.
+func (l *Locale) String() string {
+	l.RLock()
+	defer l.RUnlock()
+
+	names := l.domainNames()
+	domains := make([]string, 0, len(names))
+	for _, name := range names {
+		if l.Domains[name] == nil {
+			domains = append(domains, name+": <nil>")
+			continue
+		}
+		domains = append(domains, name+": "+l.Domains[name].GetDomain().String())
+	}
+
+	return fmt.Sprintf("Locale{Language: %q, Domain: %q, Domains: [%s]}", l.lang, l.defaultDomain, strings.Join(domains, ", "))
+}
+
 // LocaleEncoding is used as intermediary storage to encode Locale objects to Gob.
 type LocaleEncoding struct {
 	Lang          string
@@ -305,7 +1547,7 @@ func (l *Locale) MarshalBinary() ([]byte, error) {
 	obj.Domains = make(map[string][]byte)
 	for k, v := range l.Domains {
 		var err error
-		obj.Domains[k], err = v.MarshalBinary()
+		obj.Domains[k], err = v.GetDomain().MarshalBinary()
 		if err != nil {
 			return nil, err
 		}
@@ -6,9 +6,22 @@
 package gotext
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestLocale(t *testing.T) {
@@ -626,3 +639,1887 @@ func TestLocale_GetTranslations(t *testing.T) {
 		t.Errorf("translations of msgid %s do not match: \"%s\" != \"%s\"", moreMsgID, more.Get(), l.Get(moreMsgID))
 	}
 }
+
+func TestLocale_String(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "en_US")
+	l.AddDomain("default")
+
+	expected := `Locale{Language: "en_US", Domain: "default", Domains: [default: Domain{Language: "en_US", Translations: 11, Contexts: 2}]}`
+	if s := l.String(); s != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, s)
+	}
+
+	if s := fmt.Sprint(l); s != expected {
+		t.Errorf("Expected fmt to use String(), got '%s'", s)
+	}
+}
+
+func TestLocale_SetFallbackToSource(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "en_US")
+	l.AddDomain("default")
+
+	// Default behaviour returns the source string
+	if tr := l.Get("Untranslated string"); tr != "Untranslated string" {
+		t.Errorf("Expected 'Untranslated string' but got '%s'", tr)
+	}
+
+	l.SetFallbackToSource(false)
+
+	if tr := l.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+	if tr := l.Get("Untranslated string"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+	if tr := l.Get("Empty translation"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+	if tr := l.GetN("Empty plural form singular", "Empty plural form", 1); tr != "Singular translated" {
+		t.Errorf("Expected 'Singular translated' but got '%s'", tr)
+	}
+	if tr := l.GetN("Empty plural form singular", "Empty plural form", 2); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+	if tr := l.GetC("Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+		t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+	}
+	if tr := l.GetC("Some random", "Ctx"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+	if tr := l.GetNC("Untranslated", "Untranslated plural", 3, "Ctx"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+	if tr := l.GetD("missing_domain", "My text"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+
+	l.SetFallbackToSource(true)
+
+	if tr := l.Get("Untranslated string"); tr != "Untranslated string" {
+		t.Errorf("Expected 'Untranslated string' but got '%s'", tr)
+	}
+}
+
+func TestLocaleLibraries(t *testing.T) {
+	// Search path list
+	l := NewLocale(enUSFixture, "missing:fixtures", "en_US")
+	l.AddDomain("default")
+
+	if tr := l.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+
+	// Roots added at runtime
+	l = NewLocale(enUSFixture, "missing", "fr_FR")
+	l.AddDomain("default")
+
+	if _, ok := l.Domains["default"]; ok {
+		t.Error("Expected 'default' domain not to be found")
+	}
+
+	l.AddLibrary("fixtures")
+	l.AddDomain("default")
+
+	if _, ok := l.Domains["default"]; !ok {
+		t.Error("Expected 'default' domain to be loaded from added library")
+	}
+}
+
+func TestLocale_AddDomainURL(t *testing.T) {
+	po, err := enUSFixture.ReadFile("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo, err := enUSFixture.ReadFile("fixtures/de_DE/LC_MESSAGES/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := false
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/default.po":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			downloads++
+			w.Write(po)
+		case "/default.mo":
+			w.Write(mo)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	if err := l.AddDomainURL("remote", server.URL+"/default.po"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := l.GetD("remote", "language"); tr != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", tr)
+	}
+
+	// Not modified
+	if err := l.AddDomainURL("remote", server.URL+"/default.po"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if downloads != 1 {
+		t.Errorf("Expected 1 download but got %d", downloads)
+	}
+
+	// Errors keep the loaded domain
+	fail = true
+	if err := l.AddDomainURL("remote", server.URL+"/default.po"); err == nil {
+		t.Error("Expected an error")
+	}
+	if tr := l.GetD("remote", "language"); tr != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", tr)
+	}
+	fail = false
+
+	if err := l.AddDomainURL("remote", server.URL+"/missing.po"); err == nil {
+		t.Error("Expected an error")
+	}
+
+	// MO content is detected
+	if err := l.AddDomainURL("remote", server.URL+"/default.mo"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := l.GetD("remote", "language"); tr != "de_DE" {
+		t.Errorf("Expected 'de_DE' but got '%s'", tr)
+	}
+}
+
+func TestLocale_AddDomainURLContext(t *testing.T) {
+	po, err := enUSFixture.ReadFile("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.po" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write(po)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+
+	// Canceled requests fail
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = l.AddDomainURLContext(ctx, "remote", server.URL+"/slow.po"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded but got '%v'", err)
+	}
+	if _, ok := l.Domains["remote"]; ok {
+		t.Error("Expected the domain not to be loaded")
+	}
+
+	// So do requests exceeding the timeout of the client
+	l.SetHTTPClient(&http.Client{Timeout: 50 * time.Millisecond})
+	if err = l.AddDomainURL("remote", server.URL+"/slow.po"); err == nil {
+		t.Error("Expected an error")
+	}
+
+	requests := 0
+	l.SetHTTPClient(&http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return http.DefaultTransport.RoundTrip(r)
+	})})
+	if err = l.AddDomainURLContext(context.Background(), "remote", server.URL+"/default.po"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request with the client but got %d", requests)
+	}
+	if tr := l.GetD("remote", "language"); tr != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", tr)
+	}
+}
+
+// roundTripper is an http.RoundTripper calling the function
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestLocale_GetE(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+
+	if _, err := l.GetE("My text"); !errors.Is(err, ErrNoConfig) {
+		t.Errorf("Expected ErrNoConfig but got '%v'", err)
+	}
+
+	l.AddDomain("default")
+
+	tr, err := l.GetE("My text")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+
+	if _, err = l.GetE("Missing string"); !errors.Is(err, ErrMsgIDNotFound) {
+		t.Errorf("Expected ErrMsgIDNotFound but got '%v'", err)
+	}
+	if _, err = l.GetDE("missing", "My text"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%v'", err)
+	}
+}
+
+func TestLocale_GetOr(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+
+	if tr := l.GetOr("My text", "Fallback"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+	if tr := l.GetOr("err.not_found", "Item %d not found", 3); tr != "Item 3 not found" {
+		t.Errorf("Expected 'Item 3 not found' but got '%s'", tr)
+	}
+	if tr := l.GetDOr("missing", "My text", "Fallback"); tr != "Fallback" {
+		t.Errorf("Expected 'Fallback' but got '%s'", tr)
+	}
+
+	l.SetFallbackToSource(false)
+	if tr := l.GetOr("err.not_found", "Fallback"); tr != "Fallback" {
+		t.Errorf("Expected 'Fallback' but got '%s'", tr)
+	}
+}
+
+func TestLocale_SetKeyMode(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddDomain("keys")
+
+	// Keys leak without key mode
+	if tr := l.GetD("keys", "cart.empty"); tr != "cart.empty" {
+		t.Errorf("Expected 'cart.empty' but got '%s'", tr)
+	}
+
+	l.SetKeyMode(true)
+	l.SetBaseLanguage("en_US")
+
+	// Keys have no verbs, don't let vet check them as format strings
+	pay := "checkout.button.pay"
+
+	tests := map[string][2]string{
+		"translated":    {l.GetD("keys", "checkout.title"), "Paiement"},
+		"empty":         {l.GetD("keys", pay, "10 €"), "Pay 10 € now"},
+		"plural":        {l.GetND("keys", "cart.items", "cart.items", 3, 3), "3 items"},
+		"context":       {l.GetDC("keys", "file.open", "menu"), "Open"},
+		"plural ctx":    {l.GetNDC("keys", "cart.items", "cart.items", 1, "menu"), ""},
+		"missing":       {l.GetD("keys", "cart.empty"), ""},
+		"missing dom":   {l.GetD("missing", "checkout.title"), ""},
+		"or translated": {l.GetDOr("keys", "checkout.title", "Fallback"), "Paiement"},
+		"or base":       {l.GetDOr("keys", pay, "Fallback %s", "5 €"), "Pay 5 € now"},
+		"or fallback":   {l.GetDOr("keys", "cart.empty", "Fallback"), "Fallback"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	// Without base language, missing keys are empty
+	l.SetBaseLanguage("fr")
+	if tr := l.GetD("keys", "checkout.button.pay"); tr != "" {
+		t.Errorf("Expected empty string but got '%s'", tr)
+	}
+}
+
+func TestLocale_GetResult(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello %s"
+msgstr "Bonjour %s"
+
+#, fuzzy
+msgid "Draft"
+msgstr "Brouillon"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] ""
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	for name, tc := range map[string]struct {
+		result   TranslationResult
+		expected TranslationResult
+	}{
+		"translated":     {l.GetResult("Hello %s", "Alice"), TranslationResult{"Bonjour Alice", true, false, "fr", false}},
+		"fuzzy":          {l.GetResult("Draft"), TranslationResult{"Brouillon", true, true, "fr", false}},
+		"missing":        {l.GetResult("Missing"), TranslationResult{"Missing", false, false, "", true}},
+		"context":        {l.GetNDCResult("default", "Open", "", 0, "menu"), TranslationResult{"Ouvrir", true, false, "fr", false}},
+		"context miss":   {l.GetNDCResult("default", "Open", "", 0, "file"), TranslationResult{"Open", false, false, "", true}},
+		"plural":         {l.GetNDCResult("default", "One file", "%d files", 1, "", 1), TranslationResult{"Un fichier%!(EXTRA int=1)", true, false, "fr", false}},
+		"plural empty":   {l.GetNDCResult("default", "One file", "%d files", 2, "", 2), TranslationResult{"2 files", false, false, "", true}},
+		"missing domain": {l.GetNDCResult("missing", "Hello %s", "", 0, "", "Bob"), TranslationResult{"Hello Bob", false, false, "", true}},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("%s: expected %+v but got %+v", name, tc.expected, tc.result)
+		}
+	}
+
+	// Base language in key mode
+	l = NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddDomain("keys")
+	l.SetKeyMode(true)
+	l.SetBaseLanguage("en_US")
+
+	pay := "checkout.button.pay"
+	for name, tc := range map[string]struct {
+		result   TranslationResult
+		expected TranslationResult
+	}{
+		"translated": {l.GetNDCResult("keys", "checkout.title", "", 0, ""), TranslationResult{"Paiement", true, false, "fr", false}},
+		"base":       {l.GetNDCResult("keys", pay, "", 0, "", "10 €"), TranslationResult{"Pay 10 € now", true, false, "en_US", true}},
+		"missing":    {l.GetNDCResult("keys", "cart.empty", "", 0, ""), TranslationResult{"", false, false, "", true}},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("%s: expected %+v but got %+v", name, tc.expected, tc.result)
+		}
+	}
+}
+
+func TestLocale_GetErrorVars(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+
+	err := errors.New("disk full")
+	if tr := l.Get("One with var: %s", err); tr != "This one is the singular: disk full" {
+		t.Errorf("Expected 'This one is the singular: disk full' but got '%s'", tr)
+	}
+	if tr := l.GetN("One with var: %s", "Several with vars: %s", 2, &testStringer{"foo"}); tr != "This one is the plural: stringer foo" {
+		t.Errorf("Expected 'This one is the plural: stringer foo' but got '%s'", tr)
+	}
+}
+
+func TestLocale_AddDomainFiltered(t *testing.T) {
+	for _, lang := range []string{"en_US", "de"} {
+		l := NewLocale(enUSFixture, "fixtures", lang)
+		l.AddDomainFiltered("default", func(id string) bool {
+			return id == "My text" || id == "One with var: %s"
+		})
+
+		domain := l.GetDomain()
+		if domain != "default" {
+			t.Fatalf("Expected 'default' domain but got '%s'", domain)
+		}
+
+		do := l.Domains["default"].GetDomain()
+		if !do.IsTranslated("My text") {
+			t.Errorf("%s: expected 'My text' to be kept", lang)
+		}
+		if do.IsTranslated("language") || do.IsTranslatedC("Some random in a context", "Ctx") {
+			t.Errorf("%s: expected other entries to be dropped", lang)
+		}
+		// The predicate applies to the msgid of entries with context too
+		if tr := l.GetNC("One with var: %s", "Several with vars: %s", 2, "Ctx", "v"); tr == "Several with vars: v" {
+			t.Errorf("%s: expected a plural Translation in context", lang)
+		}
+		if tr := l.GetN("One with var: %s", "Several with vars: %s", 2, "v"); tr == "Several with vars: v" {
+			t.Errorf("%s: expected a plural Translation", lang)
+		}
+		if do.GetHeader("Language") == "" || do.PluralFormula() == "" {
+			t.Errorf("%s: expected the header to be loaded", lang)
+		}
+	}
+}
+
+func TestLocale_GetNamedN(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "You have {count} new message from {sender}"
+msgid_plural "You have {count} new messages from {sender}"
+msgstr[0] "{sender} vous a envoyé {count} nouveau message"
+msgstr[1] "{sender} vous a envoyé {count} nouveaux messages"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	str, plural := "You have {count} new message from {sender}", "You have {count} new messages from {sender}"
+	data := map[string]interface{}{"sender": "Alice"}
+
+	if tr := l.GetNamedN(str, plural, 1, data); tr != "Alice vous a envoyé 1 nouveau message" {
+		t.Errorf("Expected 'Alice vous a envoyé 1 nouveau message' but got '%s'", tr)
+	}
+	if tr := l.GetNamedN(str, plural, 5, data); tr != "Alice vous a envoyé 5 nouveaux messages" {
+		t.Errorf("Expected 'Alice vous a envoyé 5 nouveaux messages' but got '%s'", tr)
+	}
+	if _, ok := data["count"]; ok {
+		t.Error("Expected data to be left untouched")
+	}
+
+	// Explicit count and unmatched tokens
+	if tr := l.GetNamedN(str, plural, 5, map[string]interface{}{"count": "five"}); tr != "{sender} vous a envoyé five nouveaux messages" {
+		t.Errorf("Expected '{sender} vous a envoyé five nouveaux messages' but got '%s'", tr)
+	}
+
+	// Untranslated strings use the source plural rule
+	if tr := l.GetNamedN("{count} file", "{count} files", 1, nil); tr != "1 file" {
+		t.Errorf("Expected '1 file' but got '%s'", tr)
+	}
+
+	// CLDR category of the language, 0 is singular in French
+	if tr := l.GetNamedN("{count} ({category})", "{count} ({category})", 0, nil); tr != "0 (one)" {
+		t.Errorf("Expected '0 (one)' but got '%s'", tr)
+	}
+	if tr := l.GetNamedN("{category}", "{category}", 2, map[string]interface{}{"category": "custom"}); tr != "custom" {
+		t.Errorf("Expected 'custom' but got '%s'", tr)
+	}
+}
+
+func TestLocale_SetStrictVars(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid "%s has %d apples"
+msgstr "%s a %d pommes"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+msgid "100%% done"
+msgstr "%[1]s: 100%% fait"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	// Too many vars are dropped silently by Get* as the Translation isn't a format string
+	if tr, err := l.GetE("Hello", "Alice"); err != nil || tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' without error but got '%s', '%v'", tr, err)
+	}
+
+	l.SetStrictVars(true)
+	for name, tc := range map[string]struct {
+		id   string
+		vars []interface{}
+		err  error
+	}{
+		"match":      {"%s has %d apples", []interface{}{"Alice", 3}, nil},
+		"too few":    {"%s has %d apples", []interface{}{"Alice"}, ErrArgMismatch},
+		"none":       {"%s has %d apples", nil, ErrArgMismatch},
+		"too many":   {"%s has %d apples", []interface{}{"Alice", 3, 4}, ErrArgMismatch},
+		"no verb":    {"Hello", []interface{}{"Alice"}, ErrArgMismatch},
+		"plain":      {"Hello", nil, nil},
+		"arg index":  {"100%% done", []interface{}{"Upload"}, nil},
+		"index many": {"100%% done", []interface{}{"Upload", 1}, ErrArgMismatch},
+	} {
+		if _, err := l.GetE(tc.id, tc.vars...); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected '%v' but got '%v'", name, tc.err, err)
+		}
+	}
+
+	id := "%s has %d apples"
+	tr, err := l.GetE(id, "Alice")
+	if expected := "%q expects 2 vars but got 1"; err == nil || !strings.Contains(err.Error(), fmt.Sprintf(expected, id)) {
+		t.Errorf("Expected the var counts in the error but got '%v'", err)
+	}
+	if tr != "Alice a %!d(MISSING) pommes" {
+		t.Errorf("Expected 'Alice a %%!d(MISSING) pommes' but got '%s'", tr)
+	}
+}
+
+func TestLocale_SetFormatErrorHandler(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid "%d apples"
+msgstr "%s pommes"
+
+msgid "%d pears"
+msgstr "%d poires"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	if tr := l.Get("%d apples", 3); tr != "%!s(int=3) pommes" {
+		t.Errorf("Expected '%%!s(int=3) pommes' but got '%s'", tr)
+	}
+	if _, err = l.GetE("%d apples", 3); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat but got '%v'", err)
+	}
+
+	var failed []string
+	l.SetFormatErrorHandler(func(id, result string) string {
+		failed = append(failed, result)
+		return id
+	})
+
+	if tr := l.Get("%d apples", 3); tr != "%d apples" {
+		t.Errorf("Expected '%%d apples' but got '%s'", tr)
+	}
+	if tr := l.Get("%d pears", 3); tr != "3 poires" {
+		t.Errorf("Expected '3 poires' but got '%s'", tr)
+	}
+	if tr := l.GetD("missing", "%d kiwis", 3); tr != "3 kiwis" {
+		t.Errorf("Expected '3 kiwis' but got '%s'", tr)
+	}
+	if len(failed) != 1 || failed[0] != "%!s(int=3) pommes" {
+		t.Errorf("Expected one format error but got %v", failed)
+	}
+}
+
+func TestLocaleLoadDomainConcurrent(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+
+	const workers = 50
+	loaded := make([]Translator, workers)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+
+			l.loadDomain("default")
+
+			l.RLock()
+			loaded[i] = l.Domains["default"]
+			l.RUnlock()
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// A single parse happened, so all callers got the same Translator
+	for i, tr := range loaded {
+		if tr == nil || tr != loaded[0] {
+			t.Fatalf("Expected all callers to share the same Translator, caller %d got a different one", i)
+		}
+	}
+	if tr := l.GetD("default", "My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+}
+
+func TestLocale_GetPreferred(t *testing.T) {
+	base, err := NewPoFromString(`
+msgid "Save"
+msgstr "Base save"
+
+msgid "Cancel"
+msgstr "Base cancel"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Base one file"
+msgstr[1] "Base %d files"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Base open"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	override, err := NewPoFromString(`
+msgid "Save"
+msgstr "Override save"
+
+msgid "Cancel"
+msgstr ""
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Override one file"
+msgstr[1] "Override %d files"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddTranslator("base", base)
+	l.AddTranslator("override", override)
+
+	domains := []string{"missing", "override", "base"}
+	if tr := l.GetPreferred(domains, "Save"); tr != "Override save" {
+		t.Errorf("Expected 'Override save' but got '%s'", tr)
+	}
+	if tr := l.GetPreferred(domains, "Cancel"); tr != "Base cancel" {
+		t.Errorf("Expected 'Base cancel' but got '%s'", tr)
+	}
+	if tr := l.GetPreferred(domains, "Unknown %s", "v"); tr != "Unknown v" {
+		t.Errorf("Expected 'Unknown v' but got '%s'", tr)
+	}
+	if tr := l.GetPreferredN(domains, "One file", "%d files", 3, 3); tr != "Override 3 files" {
+		t.Errorf("Expected 'Override 3 files' but got '%s'", tr)
+	}
+	if tr := l.GetPreferredC(domains, "Open", "menu"); tr != "Base open" {
+		t.Errorf("Expected 'Base open' but got '%s'", tr)
+	}
+	if tr := l.GetPreferredNC(domains, "Open", "Opens", 2, "menu"); tr != "Opens" {
+		t.Errorf("Expected 'Opens' but got '%s'", tr)
+	}
+
+	l.SetFallbackToSource(false)
+	if tr := l.GetPreferred(domains, "Unknown"); tr != "" {
+		t.Errorf("Expected '' but got '%s'", tr)
+	}
+}
+
+func TestLocale_Freeze(t *testing.T) {
+	type getter interface {
+		GetD(dom, str string, vars ...interface{}) string
+		GetND(dom, str, plural string, n int, vars ...interface{}) string
+		GetDC(dom, str, ctx string, vars ...interface{}) string
+		GetNDC(dom, str, plural string, n int, ctx string, vars ...interface{}) string
+	}
+	calls := map[string]func(g getter) string{
+		"translated":     func(g getter) string { return g.GetD("default", "My text") },
+		"untranslated":   func(g getter) string { return g.GetD("default", "Untranslated string") },
+		"empty":          func(g getter) string { return g.GetD("default", "Empty translation") },
+		"vars":           func(g getter) string { return g.GetD("default", "Some random %s", "value") },
+		"plural 1":       func(g getter) string { return g.GetND("default", "One with var: %s", "Several with vars: %s", 1, "v") },
+		"plural 3":       func(g getter) string { return g.GetND("default", "One with var: %s", "Several with vars: %s", 3, "v") },
+		"empty plural":   func(g getter) string { return g.GetND("default", "Empty plural form singular", "Empty plural form", 2) },
+		"missing plural": func(g getter) string { return g.GetND("default", "Missing", "Missings", 2) },
+		"context":        func(g getter) string { return g.GetDC("default", "Some random in a context", "Ctx") },
+		"missing ctx":    func(g getter) string { return g.GetDC("default", "Some random", "Ctx") },
+		"ctx plural":     func(g getter) string { return g.GetNDC("default", "Untranslated", "Untranslated plural", 3, "Ctx") },
+		"missing dom":    func(g getter) string { return g.GetND("missing", "One", "Several", 0) },
+		"keys":           func(g getter) string { return g.GetD("keys", "checkout.title") },
+	}
+
+	for _, fallback := range []bool{true, false} {
+		l := NewLocale(enUSFixture, "fixtures", "en_US")
+		l.AddDomain("default")
+		l.AddDomain("keys")
+		l.SetFallbackToSource(fallback)
+
+		fl := l.Freeze()
+		if fl.GetDomain() != "default" || fl.GetLanguage() != "en_US" {
+			t.Errorf("Expected 'default' and 'en_US' but got '%s' and '%s'", fl.GetDomain(), fl.GetLanguage())
+		}
+		for name, call := range calls {
+			if expected, tr := call(l), call(fl); tr != expected {
+				t.Errorf("%s (fallback %v): expected '%s' but got '%s'", name, fallback, expected, tr)
+			}
+		}
+
+		// Later changes don't affect the snapshot
+		l.Domains["default"].GetDomain().Set("My text", "Changed")
+		l.AddTranslator("added", NewPo())
+		if tr := fl.Get("My text"); tr != translatedText {
+			t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+		}
+	}
+
+	// Key mode freezes the base language too
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddDomain("keys")
+	l.SetKeyMode(true)
+	l.SetBaseLanguage("en_US")
+	fl := l.Freeze()
+
+	pay := "checkout.button.pay"
+	tests := map[string][2]string{
+		"translated": {fl.GetD("keys", "checkout.title"), "Paiement"},
+		"base":       {fl.GetD("keys", pay, "10 €"), "Pay 10 € now"},
+		"plural":     {fl.GetND("keys", "cart.items", "cart.items", 3, 3), "3 items"},
+		"context":    {fl.GetDC("keys", "file.open", "menu"), "Open"},
+		"missing":    {fl.GetD("keys", "cart.empty"), ""},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+}
+
+func TestLocale_FreezeDebugOptions(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+#: ui/login.go:42
+msgid "Welcome"
+msgstr "Bienvenue"
+
+#: ui/menu.go:3
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#, fuzzy
+msgid "Draft"
+msgstr "Brouillon"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvOverrideName("default", "", "Draft"), "Brouillon surchargé")
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+	l.EnableEnvOverrides(true)
+	l.EnableMetrics(true)
+	l.AnnotateWithRefs(true)
+	fl := l.Freeze()
+
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{fl.Get("Welcome"), "[ui/login.go:42] Bienvenue"},
+		{fl.GetC("Open", "menu"), "[ui/menu.go:3] Ouvrir"},
+		{fl.Get("Draft"), "Brouillon surchargé"},
+		{fl.Get("Missing"), "Missing"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+
+	expected := DomainMetrics{Language: "fr", Lookups: 4, Hits: 3, Misses: 1}
+	if m := l.Metrics(); m["default"] != expected {
+		t.Errorf("Expected %+v but got %+v", expected, m["default"])
+	}
+}
+
+func TestLocale_EnableInterning(t *testing.T) {
+	plain := NewLocale(enUSFixture, "fixtures", "en_US")
+	plain.AddDomain("default")
+
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.EnableInterning(true)
+	l.AddDomain("default")
+
+	if l.pool == nil || l.Domains["default"].GetDomain().pool != l.pool {
+		t.Error("Expected the domain to be parsed with the pool of the Locale")
+	}
+
+	// Each Locale has its own pool, shared with its base language
+	other := NewLocale(enUSFixture, "fixtures", "fr")
+	other.EnableInterning(true)
+	if other.pool == l.pool {
+		t.Error("Expected another Locale to have its own pool")
+	}
+	other.SetBaseLanguage("en_US")
+	if other.base.pool != other.pool {
+		t.Error("Expected the base language to share the pool of the Locale")
+	}
+
+	expected := plain.Domains["default"].GetDomain().GetTranslations()
+	translations := l.Domains["default"].GetDomain().GetTranslations()
+	if len(translations) != len(expected) {
+		t.Errorf("Expected %d translations but got %d", len(expected), len(translations))
+	}
+	for id, trans := range expected {
+		if !trans.Equal(translations[id]) {
+			t.Errorf("Expected the same Translation for '%s'", id)
+		}
+	}
+	if tr := l.GetC("Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+		t.Errorf("Expected 'Some random translation in a context' but got '%s'", tr)
+	}
+
+	l.EnableInterning(false)
+	l.AddDomain("keys")
+	if l.Domains["keys"].GetDomain().pool != nil {
+		t.Error("Expected the domain to be parsed without pool")
+	}
+}
+
+func TestLocale_HasTranslation(t *testing.T) {
+	po, err := NewPoFromString(`msgid "Translated"
+msgstr "Traduit"
+
+msgid "Empty"
+msgstr ""
+
+#, fuzzy
+msgid "Fuzzy"
+msgstr "Flou"
+
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("app", po)
+
+	tests := map[string]struct {
+		dom, id, ctx string
+		expected     bool
+	}{
+		"translated":     {"app", "Translated", "", true},
+		"empty":          {"app", "Empty", "", false},
+		"fuzzy":          {"app", "Fuzzy", "", false},
+		"missing":        {"app", "Missing", "", false},
+		"context":        {"app", "Open", "menu", true},
+		"wrong context":  {"app", "Translated", "menu", false},
+		"no context":     {"app", "Open", "", false},
+		"missing domain": {"default", "Translated", "", false},
+	}
+	for name, tc := range tests {
+		if has := l.HasTranslation(tc.dom, tc.id, tc.ctx); has != tc.expected {
+			t.Errorf("%s: expected %v but got %v", name, tc.expected, has)
+		}
+	}
+
+	// The domain isn't loaded by the check
+	if _, ok := l.Domains["default"]; ok {
+		t.Error("Expected the 'default' domain not to be loaded")
+	}
+}
+
+func BenchmarkLocale_Get(b *testing.B) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.GetN("One with var: %s", "Several with vars: %s", 3, "v")
+		}
+	})
+}
+
+func BenchmarkFrozenLocale_Get(b *testing.B) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+	fl := l.Freeze()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			fl.GetN("One with var: %s", "Several with vars: %s", 3, "v")
+		}
+	})
+}
+
+func TestLocale_Localize(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "greeting"
+msgstr "Hello {Name}"
+
+msgid "unread"
+msgid_plural "unread"
+msgstr[0] "{Name} has one unread email"
+msgstr[1] "{Name} has {PluralCount} unread emails"
+
+msgctxt "menu"
+msgid "files"
+msgid_plural "files"
+msgstr[0] "One file"
+msgstr[1] "{count} files"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	if _, err = l.Localize(LocalizeConfig{MessageID: "greeting"}); !errors.Is(err, ErrNoConfig) {
+		t.Errorf("Expected ErrNoConfig but got '%v'", err)
+	}
+
+	l.AddTranslator("app", po)
+
+	data := map[string]interface{}{"Name": "Nick"}
+	tests := map[string]struct {
+		cfg      LocalizeConfig
+		expected string
+	}{
+		"simple":    {LocalizeConfig{MessageID: "greeting", TemplateData: data}, "Hello Nick"},
+		"struct":    {LocalizeConfig{MessageID: "greeting", TemplateData: &struct{ Name string }{"Ann"}}, "Hello Ann"},
+		"strings":   {LocalizeConfig{MessageID: "greeting", TemplateData: map[string]string{"Name": "Bob"}}, "Hello Bob"},
+		"one":       {LocalizeConfig{MessageID: "unread", TemplateData: data, PluralCount: 1}, "Nick has one unread email"},
+		"several":   {LocalizeConfig{MessageID: "unread", TemplateData: data, PluralCount: "3"}, "Nick has 3 unread emails"},
+		"context":   {LocalizeConfig{MessageID: "files", PluralCount: int64(2), Context: "menu"}, "2 files"},
+		"no values": {LocalizeConfig{MessageID: "greeting"}, "Hello {Name}"},
+	}
+	for name, tc := range tests {
+		if tr, err := l.Localize(tc.cfg); err != nil || tr != tc.expected {
+			t.Errorf("%s: expected '%s' but got '%s' (%v)", name, tc.expected, tr, err)
+		}
+	}
+	if len(data) != 1 {
+		t.Errorf("Expected TemplateData to be left untouched but got '%v'", data)
+	}
+
+	errs := map[string]struct {
+		cfg LocalizeConfig
+		err error
+	}{
+		"missing":      {LocalizeConfig{MessageID: "missing"}, ErrMsgIDNotFound},
+		"no context":   {LocalizeConfig{MessageID: "files", PluralCount: 2}, ErrMsgIDNotFound},
+		"bad count":    {LocalizeConfig{MessageID: "unread", PluralCount: 1.5}, ErrFormat},
+		"bad template": {LocalizeConfig{MessageID: "greeting", TemplateData: []string{"Nick"}}, ErrFormat},
+	}
+	for name, tc := range errs {
+		if _, err := l.Localize(tc.cfg); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected '%v' but got '%v'", name, tc.err, err)
+		}
+	}
+
+	l.SetDomain("missing")
+	if _, err = l.Localize(LocalizeConfig{MessageID: "greeting"}); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%v'", err)
+	}
+}
+
+func TestLocale_SetPlaceholderStyle(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+msgid "Hello %s"
+msgstr "Bonjour %s"
+
+msgid "Hello {name}"
+msgstr "Bonjour {name}"
+
+msgid "Hello {{.Name}}"
+msgstr "Bonjour {{.Name}}"
+
+msgid "{{.Count}} file"
+msgid_plural "{{.Count}} files"
+msgstr[0] "{{.Count}} fichier"
+msgstr[1] "{{.Count}} fichiers"
+
+msgid "Broken {{.Name"
+msgstr "Cassé {{.Name"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	// Printf style is the default
+	if tr := l.Get("Hello %s", "Ann"); tr != "Bonjour Ann" {
+		t.Errorf("Expected 'Bonjour Ann' but got '%s'", tr)
+	}
+
+	l.SetPlaceholderStyle(NamedBraceStyle)
+	named := "Hello {name}"
+	tests := map[string][2]string{
+		"map":          {l.Get(named, map[string]interface{}{"name": "Ann"}), "Bonjour Ann"},
+		"struct":       {l.Get("Hello {Name}", struct{ Name string }{"Bob"}), "Hello Bob"},
+		"no vars":      {l.Get(named), "Bonjour {name}"},
+		"printf verbs": {l.Get("Hello %s", map[string]interface{}{}), "Bonjour %s"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("named %s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	l.SetPlaceholderStyle(TemplateStyle)
+	data := map[string]interface{}{"Name": "Ann", "Count": 3}
+	tmpl := "Hello {{.Name}}"
+	tests = map[string][2]string{
+		"translated": {l.Get(tmpl, data), "Bonjour Ann"},
+		"plural":     {l.GetN("{{.Count}} file", "{{.Count}} files", 3, data), "3 fichiers"},
+		"source":     {l.GetD("missing", tmpl, struct{ Name string }{"Bob"}), "Hello Bob"},
+		"broken":     {l.Get("Broken {{.Name", data), "Cassé {{.Name"},
+		"frozen":     {l.Freeze().Get(tmpl, data), "Bonjour Ann"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("template %s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	if _, err = l.GetE("Broken {{.Name", data); !errors.Is(err, ErrFormat) {
+		t.Errorf("Expected ErrFormat but got '%v'", err)
+	}
+
+	l.SetFormatErrorHandler(func(id, result string) string {
+		return "error: " + id
+	})
+	if tr := l.Get("Broken {{.Name", data); tr != "error: Broken {{.Name" {
+		t.Errorf("Expected 'error: Broken {{.Name' but got '%s'", tr)
+	}
+
+	// Templates are parsed once, broken ones included
+	cached, ok := l.templates.templates.Load("Bonjour {{.Name}}")
+	if !ok {
+		t.Fatal("Expected the template to be cached")
+	}
+	if tr := l.Get(tmpl, map[string]interface{}{"Name": "Max"}); tr != "Bonjour Max" {
+		t.Errorf("Expected 'Bonjour Max' but got '%s'", tr)
+	}
+	if again, _ := l.templates.templates.Load("Bonjour {{.Name}}"); again != cached {
+		t.Error("Expected the cached template to be reused")
+	}
+	if cached, ok := l.templates.templates.Load("Cassé {{.Name"); !ok || cached.(*cachedTemplate).err == nil {
+		t.Error("Expected the parse error to be cached")
+	}
+
+	l.SetPlaceholderStyle(PrintfStyle)
+	if l.templates != nil {
+		t.Error("Expected no template cache with PrintfStyle")
+	}
+}
+
+func TestLocale_SetDomainSeparator(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+
+	// Flat files by default
+	l.AddDomain("a.b.c")
+	if _, ok := l.Domains["a.b.c"]; ok {
+		t.Error("Expected 'a.b.c' not to be found without separator")
+	}
+
+	l.SetDomainSeparator(".")
+	l.AddDomain("a.b.c")
+	if _, ok := l.Domains["a.b.c"]; !ok {
+		t.Fatal("Expected 'a.b.c' to be loaded from 'a/b/c.mo'")
+	}
+	if tr := l.GetD("a.b.c", "My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+
+	// Names without separator are still flat
+	l.AddDomain("default")
+	if tr := l.GetD("default", "My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+}
+
+func TestLocale_GetSafe(t *testing.T) {
+	// Unbalanced verbs, a plural form beyond nplurals and an invalid plural expression
+	po := NewPo()
+	po.Parse([]byte(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n%0;\n"
+
+msgid "Progress %d%"
+msgstr "Avancement %s %d%"
+
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[5] "%d fichiers"
+`))
+
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.Domains["broken"] = po
+	l.SetDomain("broken")
+
+	var panics []string
+	l.SetPanicHandler(func(id string, recovered interface{}) {
+		panics = append(panics, fmt.Sprintf("%s: %v", id, recovered))
+	})
+
+	if tr := l.GetSafe("Progress %d%", 50); tr != l.Get("Progress %d%", 50) {
+		t.Errorf("Expected '%s' but got '%s'", l.Get("Progress %d%", 50), tr)
+	}
+	if tr := l.GetNSafe("One file", "%d files", 3, 3); tr != "3 files" {
+		t.Errorf("Expected '3 files' but got '%s'", tr)
+	}
+	if len(panics) != 0 {
+		t.Errorf("Expected no panic but got %v", panics)
+	}
+
+	// A Translator without Domain and a panicking format error handler
+	l.Domains["broken"] = new(Po)
+	if tr := l.GetSafe("Hello %s", "world"); tr != "Hello %s" {
+		t.Errorf("Expected 'Hello %%s' but got '%s'", tr)
+	}
+	if tr := l.GetNSafe("One file", "%d files", 3, 3); tr != "%d files" {
+		t.Errorf("Expected '%%d files' but got '%s'", tr)
+	}
+	if len(panics) != 2 || panics[1][:10] != "One file: " {
+		t.Errorf("Expected 2 panics but got %v", panics)
+	}
+
+	l.Domains["broken"] = po
+	l.SetFormatErrorHandler(func(id, result string) string {
+		panic("bad format in " + id)
+	})
+	l.SetPanicHandler(func(id string, recovered interface{}) {
+		panic(recovered)
+	})
+	if tr := l.GetSafe("Progress %d%", 50); tr != "Progress %d%" {
+		t.Errorf("Expected 'Progress %%d%%' but got '%s'", tr)
+	}
+
+	// The Locale is still usable
+	l.SetFormatErrorHandler(nil)
+	if tr := l.GetSafe("One file"); tr != "Un fichier" {
+		t.Errorf("Expected 'Un fichier' but got '%s'", tr)
+	}
+}
+
+func TestLocale_DomainNames(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	if names := l.DomainNames(); len(names) != 0 {
+		t.Errorf("Expected no domain but got '%v'", names)
+	}
+
+	// Enumerate while domains are loaded concurrently, for the race detector
+	var wg sync.WaitGroup
+	for _, dom := range []string{"default", "keys", "comments"} {
+		wg.Add(2)
+		go func(dom string) {
+			defer wg.Done()
+			l.AddDomain(dom)
+		}(dom)
+		go func() {
+			defer wg.Done()
+			l.DomainNames()
+		}()
+	}
+	wg.Wait()
+
+	expected := []string{"comments", "default", "keys"}
+	if names := l.DomainNames(); fmt.Sprint(names) != fmt.Sprint(expected) {
+		t.Errorf("Expected '%v' but got '%v'", expected, names)
+	}
+}
+
+func TestSaveLocaleArchive(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	l.SetDomainSeparator(".")
+	l.AddDomain("default")
+	l.AddDomain("keys")
+	l.AddDomain("a.b.c")
+
+	for _, tc := range []struct {
+		format ArchiveFormat
+		ext    string
+		parser func() Translator
+	}{
+		{ArchiveMo, "mo", func() Translator { return NewMo() }},
+		{ArchivePo, "po", func() Translator { return NewPo() }},
+	} {
+		var buf bytes.Buffer
+		if err := SaveLocaleArchiveFormat(l, &buf, tc.format); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		expected := fmt.Sprint([]string{"fr_FR/LC_MESSAGES/a/b/c." + tc.ext, "fr_FR/LC_MESSAGES/default." + tc.ext, "fr_FR/LC_MESSAGES/keys." + tc.ext})
+		if fmt.Sprint(names) != expected {
+			t.Fatalf("Expected '%s' but got '%s'", expected, fmt.Sprint(names))
+		}
+
+		for i, dom := range []string{"a.b.c", "default", "keys"} {
+			rc, err := zr.File[i].Open()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			data, err := ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			saved := tc.parser()
+			saved.Parse(data)
+			diff := DiffDomains(l.Domains[dom].GetDomain(), saved.GetDomain())
+			if len(diff.Added) != 0 {
+				t.Errorf("Expected domain %s to round-trip as %s but got %s", dom, tc.ext, diff)
+			}
+			// The .mo format doesn't tell singular entries from plural ones, so only translations are compared
+			for _, entry := range diff.Changed {
+				if !sameTranslations(entry.Old, entry.New) {
+					t.Errorf("Expected '%v' but got '%v' for entry '%s' of domain %s saved as %s", entry.Old, entry.New, entry.ID, dom, tc.ext)
+				}
+			}
+			for _, entry := range diff.Removed {
+				if tc.format == ArchivePo || !sameTranslations(entry.Old, nil) {
+					t.Errorf("Expected entry '%s' of domain %s to be saved as %s", entry.ID, dom, tc.ext)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := SaveLocaleArchive(l, &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(zr.File) != 3 || path.Ext(zr.File[0].Name) != ".mo" {
+		t.Errorf("Expected 3 .mo files but got %v", err)
+	}
+}
+
+func TestLocale_GetCount(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d élément"
+msgstr[1] "%d éléments"
+
+msgid "%d file in %s"
+msgid_plural "%d files in %s"
+msgstr[0] "%d fichier dans %s"
+msgstr[1] "%d fichiers dans %s"
+
+msgid "One result"
+msgid_plural "Several results"
+msgstr[0] "Un résultat"
+msgstr[1] "Plusieurs résultats"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.GetCount("%d item", "%d items", 1), "1 élément"},
+		{l.GetCount("%d item", "%d items", 3), "3 éléments"},
+		{l.GetCount("%v item", "%5d items", 3), "    3 items"},
+		{l.GetCount("%d missing", "%d missings", 1), "1 missing"},
+		{l.GetCount("%d missing", "%d missings", 4), "4 missings"},
+		// No verb, n isn't inserted
+		{l.GetCount("One result", "Several results", 2), "Plusieurs résultats"},
+		// Several verbs, vars are used as given
+		{l.GetCount("%d file in %s", "%d files in %s", 2, 2, "/tmp"), "2 fichiers dans /tmp"},
+		// Other verbs aren't counts
+		{l.GetCount("%s item", "%s items", 2, "two"), "two items"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+}
+
+func TestLocale_SetDomainConflictPolicy(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	if err := l.AddDomainE("missing"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%v'", err)
+	}
+	if err := l.AddDomainE("default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	do := l.Domains["default"].GetDomain()
+	do.Set("My text", "Changed text")
+	do.SetC("Extra", "Added", "Texte en plus")
+
+	// Merge keeps the loaded domain, with the entries of the file taking precedence
+	l.SetDomainConflictPolicy(MergeDomain)
+	l.AddDomain("default")
+	if l.Domains["default"].GetDomain() != do {
+		t.Error("Expected the loaded domain to be kept")
+	}
+	if tr := l.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if tr := l.GetC("Extra", "Added"); tr != "Texte en plus" {
+		t.Errorf("Expected 'Texte en plus' but got '%s'", tr)
+	}
+
+	l.SetDomainConflictPolicy(ErrorOnConflict)
+	if err := l.AddDomainE("default"); !errors.Is(err, ErrDomainConflict) {
+		t.Errorf("Expected ErrDomainConflict but got '%v'", err)
+	}
+
+	var logged bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&logged)
+	l.AddDomain("default")
+	log.SetOutput(out)
+	if !strings.Contains(logged.String(), ErrDomainConflict.Error()) {
+		t.Errorf("Expected the conflict to be logged but got '%s'", logged.String())
+	}
+	if tr := l.GetC("Extra", "Added"); tr != "Texte en plus" {
+		t.Errorf("Expected 'Texte en plus' but got '%s'", tr)
+	}
+
+	// Replace, the default, reloads the domain
+	l.SetDomainConflictPolicy(ReplaceDomain)
+	l.AddDomain("default")
+	if tr := l.GetC("Extra", "Added"); tr != "Extra" {
+		t.Errorf("Expected 'Extra' but got '%s'", tr)
+	}
+}
+
+func TestLocale_AddDomainGlob(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	if err := l.AddDomainGlob("features", "fixtures/fr/features/*.po"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Later files take precedence
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.GetD("features", "Sign in"), "Se connecter"},
+		{l.GetD("features", "Checkout"), "Paiement"},
+		{l.GetD("features", "Save"), "Enregistrer"},
+		{l.GetDC("features", "Empty", "button"), "Vider"},
+		{l.GetND("features", "One session", "%d sessions", 2, 2), "2 sessions"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+
+	do := l.Domains["features"].GetDomain()
+	if dups := do.FindDuplicates(false); len(dups) != 1 || dups[0][0].Get() != "Enregistrer le compte" {
+		t.Errorf("Expected the replaced 'Save' entry as duplicate but got %v", dups)
+	}
+	if text, _ := do.MarshalText(); strings.Index(string(text), `"Sign in"`) > strings.Index(string(text), `"Checkout"`) {
+		t.Errorf("Expected the entries in filename order in:\n%s", text)
+	}
+
+	// Parse errors are listed, and the valid entries loaded
+	err := l.AddDomainGlob("broken", "fixtures/fr/features/*")
+	if !errors.Is(err, ErrParse) || !strings.Contains(err.Error(), "zz.broken") || strings.Contains(err.Error(), "cart.po") {
+		t.Errorf("Expected a parse error for zz.broken but got '%v'", err)
+	}
+	if tr := l.GetD("broken", "Wishlist"); tr != "Liste d'envies" {
+		t.Errorf("Expected 'Liste d'envies' but got '%s'", tr)
+	}
+
+	if err = l.AddDomainGlob("none", "fixtures/fr/features/*.mo"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%v'", err)
+	}
+	if err = l.AddDomainGlob("none", "fixtures/fr/[features"); err == nil {
+		t.Error("Expected an error for a malformed pattern")
+	}
+
+	l.SetDomainConflictPolicy(ErrorOnConflict)
+	if err = l.AddDomainGlob("features", "fixtures/fr/features/*.po"); !errors.Is(err, ErrDomainConflict) {
+		t.Errorf("Expected ErrDomainConflict but got '%v'", err)
+	}
+}
+
+func TestLocale_AddTranslation(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	l.setMaxLoadedDomains(1)
+
+	l.AddTranslation("default", "Injected", "", "Texte injecté")
+	l.AddTranslation("empty", "Injected", "Ctx", "Injecté en contexte")
+
+	// Changed domains aren't evicted
+	l.loadDomain("keys")
+	if names := l.DomainNames(); strings.Join(names, " ") != "default empty keys" {
+		t.Errorf("Expected [default empty keys] but got %v", names)
+	}
+
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.Get("Injected"), "Texte injecté"},
+		{l.Get("My text"), "Translated text"},
+		{l.GetDC("empty", "Injected", "Ctx"), "Injecté en contexte"},
+		{l.GetD("empty", "Injected"), "Injected"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+	if lang := l.Domains["empty"].GetDomain().Language; lang != "fr_FR" {
+		t.Errorf("Expected 'fr_FR' but got '%s'", lang)
+	}
+	if l.GetDomain() != "default" {
+		t.Errorf("Expected 'default' but got '%s'", l.GetDomain())
+	}
+}
+
+func TestNewLocaleFromMap(t *testing.T) {
+	l := NewLocaleFromMap("fr_FR", map[string]string{
+		"Hello %s":                     "Bonjour %s",
+		"menu" + EotSeparator + "Open": "Ouvrir",
+	})
+
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.Get("Hello %s", "Alice"), "Bonjour Alice"},
+		{l.GetC("Open", "menu"), "Ouvrir"},
+		{l.Get("Open"), "Open"},
+		{l.Get("Missing"), "Missing"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+	if lang := l.Domains["default"].GetDomain().Language; lang != "fr_FR" {
+		t.Errorf("Expected 'fr_FR' but got '%s'", lang)
+	}
+}
+
+func TestNewLocaleFromTranslations(t *testing.T) {
+	file := NewTranslation()
+	file.ID = "One file"
+	file.PluralID = "%d files"
+	file.SetN(0, "%d plik")
+	file.SetN(1, "%d pliki")
+	file.SetN(2, "%d plików")
+
+	fuzzy := NewTranslation()
+	fuzzy.ID = "button" + EotSeparator + "Save"
+	fuzzy.Set("Zapisz")
+	fuzzy.Flags = []string{"fuzzy"}
+
+	// Polish plural forms from the built-in rule
+	l := NewLocaleFromTranslations("pl", []*Translation{file, fuzzy})
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.GetN("One file", "%d files", 1, 1), "1 plik"},
+		{l.GetN("One file", "%d files", 3, 3), "3 pliki"},
+		{l.GetN("One file", "%d files", 5, 5), "5 plików"},
+		{l.GetC("Save", "button"), "Zapisz"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+	if fuzzy.ID != "button"+EotSeparator+"Save" {
+		t.Errorf("Expected the given Translation to be left as is but got '%s'", fuzzy.ID)
+	}
+	if l.HasTranslation("default", "Save", "button") {
+		t.Error("Expected the fuzzy Translation to be reported as missing")
+	}
+
+	// Same as a parsed file
+	text, _ := l.Domains["default"].GetDomain().MarshalText()
+	po, err := NewPoFromString(string(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr := po.GetN("One file", "%d files", 5, 5); tr != "5 plików" {
+		t.Errorf("Expected '5 plików' but got '%s'", tr)
+	}
+
+	// Header entry
+	header := NewTranslation()
+	header.Set("Plural-Forms: nplurals=2; plural=(n != 1);\n")
+	l = NewLocaleFromTranslations("pl", []*Translation{header, file})
+	if tr := l.GetN("One file", "%d files", 5, 5); tr != "5 pliki" {
+		t.Errorf("Expected '5 pliki' but got '%s'", tr)
+	}
+	if lang := l.Domains["default"].GetDomain().Language; lang != "pl" {
+		t.Errorf("Expected 'pl' but got '%s'", lang)
+	}
+}
+
+func TestLocale_EnableEnvOverrides(t *testing.T) {
+	if name := EnvOverrideName("default", "", "Hello, world!"); name != "GOTEXT_OVERRIDE_default_Hello_2C_20world_21" {
+		t.Errorf("Expected 'GOTEXT_OVERRIDE_default_Hello_2C_20world_21' but got '%s'", name)
+	}
+	if name := EnvOverrideName("my_domain", "Ctx", "é"); name != "GOTEXT_OVERRIDE_my_5Fdomain_Ctx_04_C3_A9" {
+		t.Errorf("Expected 'GOTEXT_OVERRIDE_my_5Fdomain_Ctx_04_C3_A9' but got '%s'", name)
+	}
+
+	t.Setenv(EnvOverrideName("default", "", "My text"), "Texte surchargé")
+	t.Setenv(EnvOverrideName("default", "", "One with var: %s"), "Surchargé : %s")
+	t.Setenv(EnvOverrideName("default", "Ctx", "Some random in a context"), "Contexte surchargé")
+
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	l.AddDomain("default")
+
+	// Off by default
+	if tr := l.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+
+	l.EnableEnvOverrides(true)
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.Get("My text"), "Texte surchargé"},
+		{l.GetN("One with var: %s", "Several with vars: %s", 2, "x"), "Surchargé : x"},
+		{l.GetC("Some random in a context", "Ctx"), "Contexte surchargé"},
+		{l.GetNC("Some random in a context", "plural", 2, "Ctx"), "Contexte surchargé"},
+		{l.GetDC("missing", "Some random in a context", "Ctx"), "Some random in a context"},
+		{l.GetC("My text", "Ctx"), "My text"},
+		{l.Get("Another string"), "Another string"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+}
+
+func TestLocale_setMaxLoadedDomains(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures/", "fr_FR")
+	l.SetDomainSeparator(".")
+	l.AddDomain("a.b.c")
+	l.setMaxLoadedDomains(1)
+
+	get := func(dom, str string) string {
+		return l.withDomain(dom, func() string {
+			return l.GetD(dom, str)
+		})
+	}
+
+	if tr := get("default", "My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if tr := get("keys", "checkout.title"); tr != "Paiement" {
+		t.Errorf("Expected 'Paiement' but got '%s'", tr)
+	}
+	// Domains added explicitly aren't evicted
+	if names := fmt.Sprint(l.DomainNames()); names != "[a.b.c keys]" {
+		t.Errorf("Expected '[a.b.c keys]' but got '%s'", names)
+	}
+
+	// Pinned domains aren't evicted until the lookup returns
+	tr := l.withDomain("default", func() string {
+		if tr := get("keys", "checkout.title"); tr != "Paiement" {
+			t.Errorf("Expected 'Paiement' but got '%s'", tr)
+		}
+		return l.GetD("default", "My text")
+	})
+	if tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if names := fmt.Sprint(l.DomainNames()); names != "[a.b.c default]" {
+		t.Errorf("Expected '[a.b.c default]' but got '%s'", names)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if i%2 == 0 {
+					if tr := get("default", "My text"); tr != "Translated text" {
+						t.Errorf("Expected 'Translated text' but got '%s'", tr)
+					}
+				} else if tr := get("keys", "checkout.title"); tr != "Paiement" {
+					t.Errorf("Expected 'Paiement' but got '%s'", tr)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(l.DomainNames()); n != 2 {
+		t.Errorf("Expected 2 domains but got %d", n)
+	}
+
+	l.setMaxLoadedDomains(0)
+	get("default", "My text")
+	get("keys", "checkout.title")
+	if n := len(l.DomainNames()); n != 3 {
+		t.Errorf("Expected 3 domains but got %d", n)
+	}
+}
+
+func TestLocale_Metrics(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+msgid "Hello"
+msgstr "Bonjour"
+
+#, fuzzy
+msgid "Draft"
+msgstr "Brouillon"
+
+msgid "One file"
+msgid_plural "Some files"
+msgstr[0] "Un fichier"
+msgstr[1] ""
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	l.Get("Hello")
+	if m := l.Metrics(); len(m) != 0 {
+		t.Errorf("Expected no metrics while disabled but got %+v", m)
+	}
+
+	l.EnableMetrics(true)
+	l.Get("Hello")
+	l.Get("Draft")
+	l.Get("Missing")
+	l.GetN("One file", "Some files", 1)
+	l.GetN("One file", "Some files", 2)
+	l.GetC("Hello", "menu")
+	l.GetD("other", "Hello")
+
+	m := l.Metrics()
+	expected := DomainMetrics{Language: "fr", Lookups: 6, Hits: 3, Misses: 3, Fuzzy: 1}
+	if m["default"] != expected {
+		t.Errorf("Expected %+v but got %+v", expected, m["default"])
+	}
+	expected = DomainMetrics{Language: "fr", Lookups: 1, Misses: 1}
+	if m["other"] != expected {
+		t.Errorf("Expected %+v but got %+v", expected, m["other"])
+	}
+
+	l.ResetMetrics()
+	if m = l.Metrics(); len(m) != 0 {
+		t.Errorf("Expected no metrics after reset but got %+v", m)
+	}
+	l.GetResult("Hello")
+	expected = DomainMetrics{Language: "fr", Lookups: 1, Hits: 1}
+	if m = l.Metrics(); m["default"] != expected {
+		t.Errorf("Expected %+v but got %+v", expected, m["default"])
+	}
+}
+
+func TestLocale_ReloadDomain(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+
+	// Runtime changes are dropped
+	l.AddTranslation("default", "My text", "", "Changed text")
+	if tr := l.GetD("default", "My text"); tr != "Changed text" {
+		t.Errorf("Expected 'Changed text' but got '%s'", tr)
+	}
+	prev := l.Domains["default"]
+	if err := l.ReloadDomain("default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tr := l.GetD("default", "My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if l.Domains["default"] == prev {
+		t.Error("Expected the domain to be swapped")
+	}
+
+	// Filters are applied again
+	l = NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomainFiltered("default", func(id string) bool { return id == "My text" })
+	if err := l.ReloadDomain("default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if l.GetD("default", "My text") != "Translated text" || l.Domains["default"].GetDomain().IsTranslated("Another string") {
+		t.Error("Expected only the filtered entries to be reloaded")
+	}
+
+	// Merged files are merged again in the same order
+	l = NewLocale(enUSFixture, "fixtures", "fr")
+	l.SetDomainConflictPolicy(MergeDomain)
+	if err := l.AddDomainGlob("features", "fixtures/fr/features/account.po"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := l.AddDomainGlob("features", "fixtures/fr/features/cart.po"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	l.AddTranslation("features", "Save", "", "Sauver")
+	if err := l.ReloadDomain("features"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tr := l.GetD("features", "Save"); tr != "Enregistrer" {
+		t.Errorf("Expected 'Enregistrer' but got '%s'", tr)
+	}
+	if tr := l.GetD("features", "Sign in"); tr != "Se connecter" {
+		t.Errorf("Expected 'Se connecter' but got '%s'", tr)
+	}
+
+	// Parse errors keep the loaded version
+	l.AddDomainGlob("broken", "fixtures/fr/features/*")
+	prev = l.Domains["broken"]
+	if err := l.ReloadDomain("broken"); !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+	if l.Domains["broken"] != prev {
+		t.Error("Expected the loaded domain to be kept")
+	}
+
+	// Domains not loaded from files
+	l.AddTranslator("memory", NewPo())
+	for _, dom := range []string{"memory", "missing"} {
+		if err := l.ReloadDomain(dom); !errors.Is(err, ErrDomainNotFound) {
+			t.Errorf("Expected ErrDomainNotFound for '%s' but got '%v'", dom, err)
+		}
+	}
+	l.AddTranslator("features", NewPo())
+	if err := l.ReloadDomain("features"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound once replaced but got '%v'", err)
+	}
+}
+
+func TestLocale_ReloadDomainFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "fr", "LC_MESSAGES"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "fr", "LC_MESSAGES", "default.po")
+	writePo := func(translation string) {
+		po := "msgid \"\"\nmsgstr \"\"\n\"Language: fr\\n\"\n\nmsgid \"Hello\"\nmsgstr \"" + translation + "\"\n"
+		if err := os.WriteFile(file, []byte(po), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writePo("Bonjour")
+	l := NewLocale(os.DirFS(dir), "", "fr")
+	l.AddDomain("default")
+	if tr := l.Get("Hello"); tr != "Bonjour" {
+		t.Errorf("Expected 'Bonjour' but got '%s'", tr)
+	}
+
+	// Changes on disk are picked up
+	writePo("Salut")
+	if err := l.ReloadDomain("default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tr := l.Get("Hello"); tr != "Salut" {
+		t.Errorf("Expected 'Salut' but got '%s'", tr)
+	}
+
+	// The loaded version is kept when the file is gone
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ReloadDomain("default"); !errors.Is(err, ErrDomainNotFound) {
+		t.Errorf("Expected ErrDomainNotFound but got '%v'", err)
+	}
+	if tr := l.Get("Hello"); tr != "Salut" {
+		t.Errorf("Expected 'Salut' but got '%s'", tr)
+	}
+}
+
+func TestLocale_ReloadDomainConcurrent(t *testing.T) {
+	l := NewLocale(enUSFixture, "fixtures", "en_US")
+	l.AddDomain("default")
+	l.AddDomainGlob("features", "fixtures/fr/features/*.po")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if tr := l.GetD("default", "My text"); tr != "Translated text" {
+					t.Errorf("Expected 'Translated text' but got '%s'", tr)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := l.ReloadDomain("features"); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLocale_AnnotateWithRefs(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+#: ui/login.go:42 ui/signup.go:10
+msgid "Welcome %s"
+msgstr "Bienvenue %s"
+
+#: ui/files.go:7
+msgid "One file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+#: ui/menu.go:3
+msgctxt "menu"
+msgid "Open"
+msgstr "Ouvrir"
+
+#: ui/draft.go:5
+msgid "Draft"
+msgstr ""
+
+msgid "No reference"
+msgstr "Sans référence"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLocale(enUSFixture, "fixtures", "fr")
+	l.AddTranslator("default", po)
+
+	if tr := l.Get("Welcome %s", "Alice"); tr != "Bienvenue Alice" {
+		t.Errorf("Expected 'Bienvenue Alice' but got '%s'", tr)
+	}
+
+	l.AnnotateWithRefs(true)
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{l.Get("Welcome %s", "Alice"), "[ui/login.go:42] Bienvenue Alice"},
+		{l.GetN("One file", "%d files", 2, 2), "[ui/files.go:7] 2 fichiers"},
+		{l.GetC("Open", "menu"), "[ui/menu.go:3] Ouvrir"},
+		{l.GetC("Open", "file"), "Open"},
+		{l.Get("Draft"), "[ui/draft.go:5] Draft"},
+		{l.Get("No reference"), "Sans référence"},
+		{l.Get("Missing"), "Missing"},
+		{l.GetD("other", "Welcome %s", "Bob"), "Welcome Bob"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+
+	// Empty strings stay empty
+	l.SetFallbackToSource(false)
+	if tr := l.Get("Draft"); tr != "" {
+		t.Errorf("Expected an empty string but got '%s'", tr)
+	}
+
+	l.AnnotateWithRefs(false)
+	if tr := l.GetC("Open", "menu"); tr != "Ouvrir" {
+		t.Errorf("Expected 'Ouvrir' but got '%s'", tr)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// LocalizeConfig describes a message to translate with Locale.Localize.
+// Its fields match the LocalizeConfig of github.com/nicksnyder/go-i18n, to ease migrating call sites.
+type LocalizeConfig struct {
+	// MessageID is the msgid to translate, from the default domain
+	MessageID string
+
+	// TemplateData holds the values of the {name} placeholders of the Translation:
+	// a map with string keys, or a struct (or a pointer to one) whose exported fields are used by name.
+	TemplateData interface{}
+
+	// PluralCount selects the plural form when set, as an integer or a string holding one.
+	// It's available as the "count" and "PluralCount" placeholders, unless TemplateData sets them.
+	PluralCount interface{}
+
+	// Context is the msgctxt of the message, empty for none. It has no go-i18n equivalent.
+	Context string
+}
+
+// Localize translates a message described in the go-i18n way, using the plural rules of the default domain
+// and replacing the {name} placeholders of the Translation like GetNamedN.
+// Like GetE, it returns ErrNoConfig, ErrDomainNotFound or ErrMsgIDNotFound instead of falling back to the source string,
+// and an error matching ErrFormat if PluralCount or TemplateData have an unsupported type.
+func (l *Locale) Localize(cfg LocalizeConfig) (string, error) {
+	dom := l.GetDomain()
+	if dom == "" {
+		return "", ErrNoConfig
+	}
+
+	l.RLock()
+	tr, ok := l.Domains[dom]
+	l.RUnlock()
+
+	if !ok || tr == nil {
+		return "", fmt.Errorf("%w: %q", ErrDomainNotFound, dom)
+	}
+
+	data, err := templateData(cfg.TemplateData)
+	if err != nil {
+		return "", err
+	}
+
+	do, id, ctx := tr.GetDomain(), cfg.MessageID, cfg.Context
+
+	if cfg.PluralCount == nil {
+		if (ctx == "" && !do.IsTranslated(id)) || (ctx != "" && !do.IsTranslatedC(id, ctx)) {
+			return "", fmt.Errorf("%w: %q", ErrMsgIDNotFound, id)
+		}
+		if ctx == "" {
+			return formatNamed(tr.Get(id), data), nil
+		}
+		return formatNamed(tr.GetC(id, ctx), data), nil
+	}
+
+	n, err := pluralCount(cfg.PluralCount)
+	if err != nil {
+		return "", err
+	}
+	if (ctx == "" && !do.IsTranslatedN(id, n)) || (ctx != "" && !do.IsTranslatedNC(id, n, ctx)) {
+		return "", fmt.Errorf("%w: %q", ErrMsgIDNotFound, id)
+	}
+
+	values := map[string]interface{}{"count": n, "PluralCount": cfg.PluralCount}
+	for k, v := range data {
+		values[k] = v
+	}
+	data = values
+
+	if ctx == "" {
+		return formatNamed(tr.GetN(id, id, n), data), nil
+	}
+	return formatNamed(tr.GetNC(id, id, n, ctx), data), nil
+}
+
+// pluralCount returns the integer value of a LocalizeConfig.PluralCount
+func pluralCount(count interface{}) (int, error) {
+	v := reflect.ValueOf(count)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(v.Uint()), nil
+	case reflect.String:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: invalid plural count %v (%T)", ErrFormat, count, count)
+}
+
+// templateData returns the values of a LocalizeConfig.TemplateData by name
+func templateData(data interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	if m, ok := data.(map[string]interface{}); ok {
+		return m, nil
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	values := make(map[string]interface{})
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = iter.Value().Interface()
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.PkgPath == "" {
+				values[field.Name] = v.Field(i).Interface()
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid template data %T", ErrFormat, data)
+	}
+
+	return values, nil
+}
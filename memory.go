@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"embed"
+	"strings"
+)
+
+// NewLocaleFromMap creates a Locale for the language code lang with a single "default" domain holding
+// the given translations by msgid, i.e. to set up translations in a unit test without fixture files,
+// or to load translations kept in a database. See NewLocaleFromTranslations.
+func NewLocaleFromMap(lang string, entries map[string]string) *Locale {
+	trs := make([]*Translation, 0, len(entries))
+	for id, str := range entries {
+		trans := NewTranslation()
+		trans.ID = id
+		trans.Set(str)
+		trs = append(trs, trans)
+	}
+
+	return NewLocaleFromTranslations(lang, trs)
+}
+
+// NewLocaleFromTranslations creates a Locale for the language code lang with a single "default" domain holding
+// copies of the given Translations, so they can have plural forms, flags and comments.
+// As in .mo files, the context of an entry is given in its ID, as "ctx\x04msgid" (see EotSeparator),
+// and the header by an entry with an empty ID, i.e. to set the Plural-Forms. Otherwise, the domain gets
+// a Language header, and the plural forms follow the built-in rule of the language.
+// The domain works like one parsed from a file; when several Translations have the same context and msgid,
+// the last one is used.
+func NewLocaleFromTranslations(lang string, trs []*Translation) *Locale {
+	l := NewLocale(embed.FS{}, "", lang)
+
+	po := NewPo()
+	do := po.domain
+	for _, trans := range trs {
+		trans = trans.clone()
+
+		ctx := ""
+		if idx := strings.Index(trans.ID, EotSeparator); idx >= 0 {
+			ctx, trans.ID = trans.ID[:idx], trans.ID[idx+len(EotSeparator):]
+		}
+
+		entries := do.translations
+		if ctx != "" {
+			if _, ok := do.contexts[ctx]; !ok {
+				do.contexts[ctx] = make(map[string]*Translation)
+			}
+			entries = do.contexts[ctx]
+		}
+		if _, ok := entries[trans.ID]; !ok {
+			do.recordOrder(ctx, trans.ID)
+		}
+		entries[trans.ID] = trans
+		if trans.PluralID != "" {
+			do.pluralTranslations[trans.PluralID] = trans
+		}
+	}
+
+	// Header
+	header, ok := do.translations[""]
+	if !ok {
+		header = NewTranslation()
+		do.translations[""] = header
+	}
+	if !hasHeader(header.Get(), "Language") {
+		header.Set("Language: " + l.lang + "\n" + header.Get())
+	}
+	do.parseHeaders()
+
+	l.AddTranslator("default", po)
+	return l
+}
+
+// hasHeader reports whether the raw header, as the Translation of the empty msgid, has a non-empty value for key
+func hasHeader(raw, key string) bool {
+	for _, line := range strings.Split(raw, "\n") {
+		idx := strings.Index(line, ":")
+		if idx >= 0 && strings.EqualFold(strings.TrimSpace(line[:idx]), key) && strings.TrimSpace(line[idx+1:]) != "" {
+			return true
+		}
+	}
+	return false
+}
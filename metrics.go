@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DomainMetrics holds the lookup counters of a domain of a Locale, see Locale.EnableMetrics
+type DomainMetrics struct {
+	// Language is the language of the Locale
+	Language string
+
+	// Lookups is the number of strings looked up by the Get* methods
+	Lookups uint64
+
+	// Hits is the number of lookups that found a non-empty Translation in the language of the Locale
+	Hits uint64
+
+	// Misses is the number of lookups that fell back to the source string, or in key mode to the base language
+	Misses uint64
+
+	// Fuzzy is the number of lookups that found a Translation flagged as fuzzy. They're counted as hits or misses as well.
+	Fuzzy uint64
+}
+
+// domainCounters are the counters of a domain, updated atomically
+type domainCounters struct {
+	lookups uint64
+	hits    uint64
+	misses  uint64
+	fuzzy   uint64
+}
+
+// localeMetrics holds the domainCounters by domain name.
+// It's separate from the Locale lock, so lookups only share the counters.
+type localeMetrics struct {
+	domains sync.Map
+}
+
+// EnableMetrics sets whether the GetD, GetND, GetDC and GetNDC methods, and the methods built on them, count
+// lookups, hits, misses and fuzzy translations by domain, see Metrics.
+// It's disabled by default. The counters are updated atomically, from the lookups the methods run anyway.
+func (l *Locale) EnableMetrics(enabled bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	if !enabled {
+		l.metrics = nil
+	} else if l.metrics == nil {
+		l.metrics = &localeMetrics{}
+	}
+}
+
+// Metrics returns the counters of the domains looked up since metrics were enabled or reset, by domain name,
+// i.e. to be exported to a monitoring system. It's empty when metrics are disabled.
+func (l *Locale) Metrics() map[string]DomainMetrics {
+	l.RLock()
+	m, lang := l.metrics, l.lang
+	l.RUnlock()
+
+	res := make(map[string]DomainMetrics)
+	if m == nil {
+		return res
+	}
+	m.domains.Range(func(key, value interface{}) bool {
+		c := value.(*domainCounters)
+		res[key.(string)] = DomainMetrics{
+			Language: lang,
+			Lookups:  atomic.LoadUint64(&c.lookups),
+			Hits:     atomic.LoadUint64(&c.hits),
+			Misses:   atomic.LoadUint64(&c.misses),
+			Fuzzy:    atomic.LoadUint64(&c.fuzzy),
+		}
+		return true
+	})
+	return res
+}
+
+// ResetMetrics clears the counters of all domains, i.e. between tests.
+func (l *Locale) ResetMetrics() {
+	l.RLock()
+	m := l.metrics
+	l.RUnlock()
+
+	if m == nil {
+		return
+	}
+	m.domains.Range(func(key, _ interface{}) bool {
+		m.domains.Delete(key)
+		return true
+	})
+}
+
+// recordLookup counts a lookup in dom, whose result is res, when metrics are enabled.
+// The Locale must be read-locked.
+func (l *Locale) recordLookup(dom string, res TranslationResult) {
+	if l.metrics != nil {
+		l.metrics.record(dom, res)
+	}
+}
+
+// record counts a lookup in dom, whose result is res
+func (m *localeMetrics) record(dom string, res TranslationResult) {
+	value, ok := m.domains.Load(dom)
+	if !ok {
+		value, _ = m.domains.LoadOrStore(dom, &domainCounters{})
+	}
+	c := value.(*domainCounters)
+
+	atomic.AddUint64(&c.lookups, 1)
+	if res.Translated && !res.UsedFallback {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	if res.Fuzzy {
+		atomic.AddUint64(&c.fuzzy, 1)
+	}
+}
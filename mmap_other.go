@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"errors"
+	"os"
+)
+
+// mapFile isn't supported on this platform, files are read into memory instead
+func mapFile(f *os.File) (*mapping, error) {
+	return nil, errors.New("mmap isn't supported on this platform")
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// mapFile maps the whole content of f read-only into memory.
+// The mapping is released by the garbage collector once it isn't referenced anymore.
+func mapFile(f *os.File) (*mapping, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := fi.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, fmt.Errorf("can't map file of size %d", size)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mapping{data: data}
+	runtime.SetFinalizer(m, func(m *mapping) {
+		syscall.Munmap(m.data)
+	})
+	return m, nil
+}
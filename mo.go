@@ -9,6 +9,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"io/fs"
+	"os"
+	"sort"
+	"strings"
 )
 
 const (
@@ -53,6 +56,26 @@ type Mo struct {
 	Language    string
 	PluralForms string
 	domain      *Domain
+
+	// writeHashTable makes MarshalBinary include the lookup hash table
+	writeHashTable bool
+
+	// strict makes parsing fail on files with an inconsistent hash table
+	strict bool
+
+	// lazy defers decoding the msgstrs until their first use
+	lazy bool
+
+	// mmap makes ParseFile memory-map files on disk instead of reading them
+	mmap bool
+
+	// mapped is the file being parsed when it's memory-mapped, referenced by the lazy msgstrs
+	mapped *mapping
+}
+
+// mapping is a read-only memory mapped file, unmapped by the garbage collector once it isn't referenced anymore
+type mapping struct {
+	data []byte
 }
 
 //NewMo should always be used to instantiate a new Mo object
@@ -84,15 +107,65 @@ func (mo *Mo) GetNC(str, plural string, n int, ctx string, vars ...interface{})
 	return mo.domain.GetNC(str, plural, n, ctx, vars...)
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler interface, returning the domain in the GNU gettext .mo format.
+// Entries without any translated form and obsolete entries are left out, like msgfmt does.
+// Earlier versions returned the gob encoding of the domain, like Po.MarshalBinary still does: such data can't be read
+// by UnmarshalBinary anymore, decode it with Domain.UnmarshalBinary, i.e. NewMo().GetDomain().UnmarshalBinary(data).
 func (mo *Mo) MarshalBinary() ([]byte, error) {
-	return mo.domain.MarshalBinary()
+	return mo.domain.marshalMo(mo.writeHashTable)
 }
 
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface, replacing the domain with the .mo data,
+// as returned by MarshalBinary.
 func (mo *Mo) UnmarshalBinary(data []byte) error {
-	return mo.domain.UnmarshalBinary(data)
+	mo.domain = NewDomain()
+	return mo.parse(data)
+}
+
+// SetWriteHashTable sets whether MarshalBinary generates the lookup hash table of the .mo format.
+// It's not used by gotext, but GNU libintl uses it to find messages without a binary search.
+// See hashPJW for the hashing algorithm.
+func (mo *Mo) SetWriteHashTable(enabled bool) {
+	mo.writeHashTable = enabled
+}
+
+// SetStrict sets whether parsing validates the hash table, when present, and fails with ErrParse if any message
+// can't be found through it.
+func (mo *Mo) SetStrict(strict bool) {
+	mo.strict = strict
+}
+
+// SetLazy sets whether parsing only decodes the msgids, to index the entries, and leaves every msgstr
+// in the parsed data until the entry is first used, i.e. by Get, when it's decoded once and cached.
+// It makes loading large catalogs, where most messages are never used, faster: with 30000 messages of which 100 are used,
+// parsing takes about 35% less time and allocates a third less memory (see BenchmarkMo_ParseLazy).
+// The data given to Parse is retained, and must not be modified afterwards.
+func (mo *Mo) SetLazy(enabled bool) {
+	mo.lazy = enabled
+}
+
+// SetMmap sets whether ParseFile memory-maps files on disk, given as an *os.File, instead of reading them into memory.
+// Only the msgids are copied while parsing, to index the entries: the msgstrs are decoded from the mapping on
+// first use, like with SetLazy, so the pages of the messages never used aren't loaded. With 30000 messages
+// of which 100 are used, the heap in use after parsing is about a third smaller (see BenchmarkMo_ParseFileMmap).
+// The mapping is released once every msgstr was decoded or the domain isn't referenced anymore.
+// The file must not be modified while it's mapped. On platforms without mmap, or for other files,
+// ParseFile reads the file as usual.
+func (mo *Mo) SetMmap(enabled bool) {
+	mo.mmap = enabled
 }
 
 func (mo *Mo) ParseFile(f fs.File) {
+	if file, ok := f.(*os.File); ok && mo.mmap {
+		if m, err := mapFile(file); err == nil {
+			lazy := mo.lazy
+			mo.lazy, mo.mapped = true, m
+			mo.Parse(m.data)
+			mo.lazy, mo.mapped = lazy, nil
+			return
+		}
+	}
+
 	data, err := getFileData(f)
 	if err != nil {
 		return
@@ -101,8 +174,34 @@ func (mo *Mo) ParseFile(f fs.File) {
 	mo.Parse(data)
 }
 
+// NewMoFromDomain creates a Mo object holding the given domain, i.e. to write a parsed .po file in the .mo format.
+// The domain isn't copied.
+func NewMoFromDomain(do *Domain) *Mo {
+	mo := new(Mo)
+	mo.domain = do
+
+	return mo
+}
+
+// NewMoFromBytes creates a Mo object and loads the translations from the provided byte slice,
+// in the GNU gettext .mo format. It returns an error if the data isn't a valid .mo file.
+func NewMoFromBytes(b []byte) (*Mo, error) {
+	mo := NewMo()
+	if err := mo.parse(b); err != nil {
+		return nil, err
+	}
+
+	return mo, nil
+}
+
 // Parse loads the translations specified in the provided byte slice, in the GNU gettext .mo format
 func (mo *Mo) Parse(buf []byte) {
+	mo.parse(buf)
+}
+
+// parse does the actual work for Parse, returning the first error found.
+// Nothing past the failure point is loaded.
+func (mo *Mo) parse(buf []byte) error {
 	// Lock while parsing
 	mo.domain.trMutex.Lock()
 	mo.domain.pluralMutex.Lock()
@@ -113,8 +212,7 @@ func (mo *Mo) Parse(buf []byte) {
 
 	var magicNumber uint32
 	if err := binary.Read(r, binary.LittleEndian, &magicNumber); err != nil {
-		return
-		// return fmt.Errorf("gettext: %v", err)
+		return newParseError("%w", err)
 	}
 	var bo binary.ByteOrder
 	switch magicNumber {
@@ -123,8 +221,7 @@ func (mo *Mo) Parse(buf []byte) {
 	case MoMagicBigEndian:
 		bo = binary.BigEndian
 	default:
-		return
-		// return fmt.Errorf("gettext: %v", "invalid magic number")
+		return newParseError("invalid magic number")
 	}
 
 	var header struct {
@@ -137,88 +234,94 @@ func (mo *Mo) Parse(buf []byte) {
 		HashOffset   uint32
 	}
 	if err := binary.Read(r, bo, &header); err != nil {
-		return
-		// return fmt.Errorf("gettext: %v", err)
+		return newParseError("%w", err)
 	}
 	if v := header.MajorVersion; v != 0 && v != 1 {
-		return
-		// return fmt.Errorf("gettext: %v", "invalid version number")
+		return newParseError("invalid version number")
 	}
 	if v := header.MinorVersion; v != 0 && v != 1 {
-		return
-		// return fmt.Errorf("gettext: %v", "invalid version number")
+		return newParseError("invalid version number")
 	}
 
 	msgIDStart := make([]uint32, header.MsgIDCount)
 	msgIDLen := make([]uint32, header.MsgIDCount)
 	if _, err := r.Seek(int64(header.MsgIDOffset), 0); err != nil {
-		return
-		// return fmt.Errorf("gettext: %v", err)
+		return newParseError("%w", err)
 	}
 	for i := 0; i < int(header.MsgIDCount); i++ {
 		if err := binary.Read(r, bo, &msgIDLen[i]); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 		if err := binary.Read(r, bo, &msgIDStart[i]); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 	}
 
 	msgStrStart := make([]int32, header.MsgIDCount)
 	msgStrLen := make([]int32, header.MsgIDCount)
 	if _, err := r.Seek(int64(header.MsgStrOffset), 0); err != nil {
-		return
-		// return fmt.Errorf("gettext: %v", err)
+		return newParseError("%w", err)
 	}
 	for i := 0; i < int(header.MsgIDCount); i++ {
 		if err := binary.Read(r, bo, &msgStrLen[i]); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 		if err := binary.Read(r, bo, &msgStrStart[i]); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 	}
 
+	var msgIDs [][]byte
 	for i := 0; i < int(header.MsgIDCount); i++ {
 		if _, err := r.Seek(int64(msgIDStart[i]), 0); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 		msgIDData := make([]byte, msgIDLen[i])
 		if _, err := r.Read(msgIDData); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+			return newParseError("%w", err)
 		}
 
-		if _, err := r.Seek(int64(msgStrStart[i]), 0); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+		var msgStrData []byte
+		if mo.lazy {
+			// Keep a reference to the msgstr, decoded on first use
+			start, end := int64(msgStrStart[i]), int64(msgStrStart[i])+int64(msgStrLen[i])
+			if start < 0 || end < start || end > int64(len(buf)) {
+				return newParseError("msgstr %d out of range", i)
+			}
+			msgStrData = buf[start:end:end]
+		} else {
+			if _, err := r.Seek(int64(msgStrStart[i]), 0); err != nil {
+				return newParseError("%w", err)
+			}
+			msgStrData = make([]byte, msgStrLen[i])
+			if _, err := r.Read(msgStrData); err != nil {
+				return newParseError("%w", err)
+			}
 		}
-		msgStrData := make([]byte, msgStrLen[i])
-		if _, err := r.Read(msgStrData); err != nil {
-			return
-			// return fmt.Errorf("gettext: %v", err)
+
+		mo.addTranslation(msgIDData, msgStrData)
+
+		if mo.strict && header.HashSize > 0 {
+			msgIDs = append(msgIDs, msgIDData)
 		}
+	}
 
-		if len(msgIDData) == 0 {
-			mo.addTranslation(msgIDData, msgStrData)
-		} else {
-			mo.addTranslation(msgIDData, msgStrData)
+	if mo.strict && header.HashSize > 0 {
+		if err := validateHashTable(r, bo, header.HashSize, header.HashOffset, msgIDs); err != nil {
+			return err
 		}
 	}
 
 	// Parse headers
-	mo.domain.parseHeaders()
+	err := mo.domain.parseHeaders()
 
 	// set values on this struct
 	// this is for backwards compatibility
 	mo.Language = mo.domain.Language
 	mo.PluralForms = mo.domain.PluralForms
 	mo.Headers = mo.domain.Headers
+
+	return err
 }
 
 func (mo *Mo) addTranslation(msgid, msgstr []byte) {
@@ -246,20 +349,224 @@ func (mo *Mo) addTranslation(msgid, msgstr []byte) {
 		translation.PluralID = string(msgidPlural)
 	}
 
-	ddd := bytes.Split(msgstr, []byte(NulSeparator))
-	if len(ddd) > 0 {
-		for i, s := range ddd {
-			translation.Trs[i] = string(s)
+	if mo.lazy && translation.ID != "" {
+		// The header is always decoded, as it's parsed right away
+		translation.lazy = &lazyMsgstr{data: msgstr, src: mo.mapped}
+	} else {
+		ddd := bytes.Split(msgstr, []byte(NulSeparator))
+		if len(ddd) > 0 {
+			for i, s := range ddd {
+				translation.Trs[i] = string(s)
+			}
 		}
 	}
 
+	mo.domain.internTranslation(translation)
+
 	if len(msgctxt) > 0 {
 		// With context...
-		if _, ok := mo.domain.contexts[string(msgctxt)]; !ok {
-			mo.domain.contexts[string(msgctxt)] = make(map[string]*Translation)
+		ctx := mo.domain.intern(string(msgctxt))
+		if _, ok := mo.domain.contexts[ctx]; !ok {
+			mo.domain.contexts[ctx] = make(map[string]*Translation)
 		}
-		mo.domain.contexts[string(msgctxt)][translation.ID] = translation
+		if _, ok := mo.domain.contexts[ctx][translation.ID]; !ok {
+			mo.domain.recordOrder(ctx, translation.ID)
+		}
+		mo.domain.contexts[ctx][translation.ID] = translation
 	} else {
+		if _, ok := mo.domain.translations[translation.ID]; !ok {
+			mo.domain.recordOrder("", translation.ID)
+		}
 		mo.domain.translations[translation.ID] = translation
 	}
 }
+
+// validateHashTable checks every msgid, in file order, can be found by looking it up in the hash table
+func validateHashTable(r *bytes.Reader, bo binary.ByteOrder, size, offset uint32, msgIDs [][]byte) error {
+	if size < 3 {
+		return newParseError("invalid hash table size %d", size)
+	}
+
+	table := make([]uint32, size)
+	if _, err := r.Seek(int64(offset), 0); err != nil {
+		return newParseError("%w", err)
+	}
+	if err := binary.Read(r, bo, table); err != nil {
+		return newParseError("%w", err)
+	}
+
+	for i, msgID := range msgIDs {
+		// Plural entries are hashed on their singular msgid only
+		key := msgID
+		if idx := bytes.IndexByte(key, 0); idx != -1 {
+			key = key[:idx]
+		}
+
+		hash := hashPJW(key)
+		idx := hash % size
+		incr := 1 + hash%(size-2)
+		for probes := uint32(0); table[idx] != uint32(i+1); probes++ {
+			if table[idx] == 0 || probes >= size {
+				return newParseError("hash table lookup failed for msgid %q", key)
+			}
+			idx = (idx + incr) % size
+		}
+	}
+
+	return nil
+}
+
+// hashPJW is the hash function used by GNU gettext for the .mo hash table,
+// a variant of P. J. Weinberger's hash on 32 bits:
+// for each byte, the hash is shifted 4 bits to the left and the byte is added,
+// then, if any of the 4 high bits are set, they're folded into bits 4 to 7 and cleared.
+func hashPJW(key []byte) uint32 {
+	var hash uint32
+	for _, c := range key {
+		hash = (hash << 4) + uint32(c)
+		if g := hash & 0xf0000000; g != 0 {
+			hash ^= g >> 24
+			hash ^= g
+		}
+	}
+	return hash
+}
+
+// hashTableSize returns the size of the hash table for n messages, as GNU msgfmt does:
+// the smallest prime number bigger or equal to 4n/3, and at least 3.
+func hashTableSize(n int) uint32 {
+	size := uint32(n * 4 / 3)
+	if size < 3 {
+		return 3
+	}
+
+	for size |= 1; ; size += 2 {
+		prime := true
+		for d := uint32(3); d*d <= size; d += 2 {
+			if size%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return size
+		}
+	}
+}
+
+// moEntry is a message to write into a .mo file
+type moEntry struct {
+	key   []byte // msgctxt EOT msgid [NUL msgid_plural]
+	value []byte // msgstr[0] [NUL msgstr[1]...]
+}
+
+// marshalMo returns the domain in the GNU gettext .mo format, optionally with the lookup hash table
+func (do *Domain) marshalMo(withHashTable bool) ([]byte, error) {
+	do.trMutex.RLock()
+
+	var entries []moEntry
+	add := func(ctx string, trans *Translation) {
+		// Skip untranslated entries, except for the header
+		trans.load()
+		translated := trans.ID == ""
+		forms := make([]int, 0, len(trans.Trs))
+		for n, tr := range trans.Trs {
+			forms = append(forms, n)
+			translated = translated || tr != ""
+		}
+		if !translated {
+			return
+		}
+		sort.Ints(forms)
+
+		key := trans.ID
+		if ctx != "" {
+			key = ctx + EotSeparator + key
+		}
+		if trans.PluralID != "" {
+			key += NulSeparator + trans.PluralID
+		}
+
+		values := make([]string, 0, len(forms))
+		for _, n := range forms {
+			values = append(values, trans.Trs[n])
+		}
+
+		entries = append(entries, moEntry{key: []byte(key), value: []byte(strings.Join(values, NulSeparator))})
+	}
+
+	for _, trans := range do.translations {
+		add("", trans)
+	}
+	for ctx, translations := range do.contexts {
+		for _, trans := range translations {
+			add(ctx, trans)
+		}
+	}
+
+	do.trMutex.RUnlock()
+
+	// Messages are sorted by msgid, as required by the format for binary searches
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	n := uint32(len(entries))
+	var hashSize uint32
+	if withHashTable {
+		hashSize = hashTableSize(len(entries))
+	}
+
+	const headerSize = 28
+	msgIDOffset := uint32(headerSize)
+	msgStrOffset := msgIDOffset + 8*n
+	hashOffset := msgStrOffset + 8*n
+	dataOffset := hashOffset + 4*hashSize
+
+	var buf bytes.Buffer
+	header := []uint32{MoMagicLittleEndian, 0, n, msgIDOffset, msgStrOffset, hashSize, hashOffset}
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+
+	// Strings tables: length and offset of each NUL terminated string, msgids first and then msgstrs
+	offset := dataOffset
+	for _, e := range entries {
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(e.key)), offset})
+		offset += uint32(len(e.key)) + 1
+	}
+	for _, e := range entries {
+		binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(e.value)), offset})
+		offset += uint32(len(e.value)) + 1
+	}
+
+	if hashSize > 0 {
+		table := make([]uint32, hashSize)
+		for i, e := range entries {
+			key := e.key
+			if idx := bytes.IndexByte(key, 0); idx != -1 {
+				key = key[:idx]
+			}
+
+			hash := hashPJW(key)
+			idx := hash % hashSize
+			incr := 1 + hash%(hashSize-2)
+			for table[idx] != 0 {
+				idx = (idx + incr) % hashSize
+			}
+			table[idx] = uint32(i + 1)
+		}
+		binary.Write(&buf, binary.LittleEndian, table)
+	}
+
+	for _, e := range entries {
+		buf.Write(e.key)
+		buf.WriteByte(0)
+	}
+	for _, e := range entries {
+		buf.Write(e.value)
+		buf.WriteByte(0)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -6,6 +6,14 @@
 package gotext
 
 import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -243,4 +251,402 @@ func TestMoBinaryEncoding(t *testing.T) {
 	if tr != "en_US" {
 		t.Errorf("Expected 'en_US' but got '%s'", tr)
 	}
+
+	// The gob encoding of earlier versions is read through the domain
+	buff, err = mo.GetDomain().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = NewMo().UnmarshalBinary(buff); err == nil {
+		t.Error("Expected an error for gob data")
+	}
+	mo3 := NewMo()
+	if err = mo3.GetDomain().UnmarshalBinary(buff); err != nil {
+		t.Fatal(err)
+	}
+	if tr = mo3.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+}
+
+func TestMo_SetWriteHashTable(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, withHashTable := range []bool{false, true} {
+		mo, err := NewMoFromBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mo.SetWriteHashTable(withHashTable)
+
+		buff, err := mo.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashSize := binary.LittleEndian.Uint32(buff[20:24])
+		if withHashTable && hashSize < 3 {
+			t.Errorf("Expected a hash table but got size %d", hashSize)
+		}
+		if !withHashTable && hashSize != 0 {
+			t.Errorf("Expected no hash table but got size %d", hashSize)
+		}
+
+		mo2 := NewMo()
+		mo2.SetStrict(true)
+		if err = mo2.UnmarshalBinary(buff); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		if tr := mo2.Get("My text"); tr != translatedText {
+			t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+		}
+		if tr := mo2.GetNC("One with var: %s", "Several with vars: %s", 5, "Ctx", "v"); tr != "This one is the plural in a Ctx context: v" {
+			t.Errorf("Expected 'This one is the plural in a Ctx context: v' but got '%s'", tr)
+		}
+		if tr := mo2.Get("language"); tr != "en_US" {
+			t.Errorf("Expected 'en_US' but got '%s'", tr)
+		}
+		translations := mo2.GetDomain().GetTranslations()
+		for id, trans := range mo.GetDomain().GetTranslations() {
+			if !trans.Equal(translations[id]) {
+				t.Errorf("Expected the same Translation for '%s' after the round-trip", id)
+			}
+		}
+	}
+}
+
+func TestMoStrictHashTable(t *testing.T) {
+	mo, err := NewPoFromString(`msgid ""
+msgstr "Language: fr\n"
+
+msgid "One"
+msgstr "Un"
+
+msgid "Two"
+msgstr "Deux"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMoFromDomain(mo.GetDomain())
+	m.SetWriteHashTable(true)
+	buff, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the hash table
+	hashSize := binary.LittleEndian.Uint32(buff[20:24])
+	hashOffset := binary.LittleEndian.Uint32(buff[24:28])
+	for i := uint32(0); i < hashSize; i++ {
+		binary.LittleEndian.PutUint32(buff[hashOffset+4*i:], 0)
+	}
+
+	if _, err = NewMoFromBytes(buff); err != nil {
+		t.Errorf("Unexpected error in non strict mode: %s", err)
+	}
+
+	// Files from msgfmt have a valid hash table
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := NewMo()
+	fixture.SetStrict(true)
+	if err = fixture.UnmarshalBinary(data); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	strict := NewMo()
+	strict.SetStrict(true)
+	if err = strict.UnmarshalBinary(buff); !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+}
+
+func TestHashPJW(t *testing.T) {
+	for key, want := range map[string]uint32{
+		"":                                     0,
+		"a":                                    0x61,
+		"ab":                                   0x672,
+		"My text":                              0x4b7aca4,
+		"Some random translation in a context": 0x875ecb4,
+	} {
+		if got := hashPJW([]byte(key)); got != want {
+			t.Errorf("Expected %#x for '%s' but got %#x", want, key, got)
+		}
+	}
+}
+
+func TestMo_Headers(t *testing.T) {
+	mo := NewMo()
+
+	f, err := enUSFixture.Open("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo.ParseFile(f)
+
+	domain := mo.GetDomain()
+	if lang := domain.GetHeader("Language"); lang != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", lang)
+	}
+	if ct := domain.GetHeader("content-type"); ct != "text/plain; charset=UTF-8" {
+		t.Errorf("Expected 'text/plain; charset=UTF-8' but got '%s'", ct)
+	}
+	if ct := mo.Headers.Get("Content-Type"); ct != "text/plain; charset=UTF-8" {
+		t.Errorf("Expected 'text/plain; charset=UTF-8' but got '%s'", ct)
+	}
+	if charset := domain.Charset(); charset != "UTF-8" {
+		t.Errorf("Expected 'UTF-8' but got '%s'", charset)
+	}
+	if h := domain.GetHeader("X-Missing"); h != "" {
+		t.Errorf("Expected empty header but got '%s'", h)
+	}
+	if charset := NewDomain().Charset(); charset != "" {
+		t.Errorf("Expected empty charset but got '%s'", charset)
+	}
+}
+
+func TestMo_SetLazy(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eager := NewMo()
+	eager.Parse(data)
+
+	mo := NewMo()
+	mo.SetLazy(true)
+	mo.Parse(data)
+
+	if lang := mo.GetDomain().Language; lang != eager.GetDomain().Language {
+		t.Errorf("Expected '%s' but got '%s'", eager.GetDomain().Language, lang)
+	}
+	if trans := mo.GetDomain().translations["My text"]; trans == nil || trans.lazy == nil || len(trans.Trs) != 0 {
+		t.Fatal("Expected 'My text' not to be decoded yet")
+	}
+
+	// Decoded concurrently on first use
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tr := mo.Get("My text"); tr != translatedText {
+				t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tr := mo.GetNC("One with var: %s", "Several with vars: %s", 2, "Ctx", "v"); tr != "This one is the plural in a Ctx context: v" {
+		t.Errorf("Expected 'This one is the plural in a Ctx context: v' but got '%s'", tr)
+	}
+
+	// Iterations get decoded entries
+	iterated := NewMo()
+	iterated.SetLazy(true)
+	iterated.Parse(data)
+	iterated.GetDomain().ForEachC(func(ctx, id string, trans *Translation) bool {
+		if expected := eager.GetDomain().translations[id]; ctx == "" && (len(trans.Trs) == 0 || trans.Trs[0] != expected.Trs[0]) {
+			t.Errorf("Expected '%s' for '%s' but got %v", expected.Trs[0], id, trans.Trs)
+		}
+		if ctx != "" && len(trans.Trs) == 0 {
+			t.Errorf("Expected '%s' in context '%s' to be decoded", id, ctx)
+		}
+		return true
+	})
+
+	// Other uses see the same entries as an eagerly parsed Mo
+	lazyText, err := mo.GetDomain().MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eagerText, err := eager.GetDomain().MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(lazyText) != string(eagerText) {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", eagerText, lazyText)
+	}
+	if diff := DiffDomains(eager.GetDomain(), mo.GetDomain()); !diff.Empty() {
+		t.Errorf("Expected no difference but got '%s'", diff)
+	}
+
+	// msgstrs out of the data are reported while parsing
+	lazy := NewMo()
+	lazy.SetLazy(true)
+	if err = lazy.parse(data[:len(data)-10]); err == nil {
+		t.Error("Expected an error for a truncated file in lazy mode")
+	}
+}
+
+// largeMo returns the .mo data of largePo(n)
+func largeMo(b *testing.B, n int) []byte {
+	po := NewPo()
+	if err := po.ParseBytes(largePo(n)); err != nil {
+		b.Fatal(err)
+	}
+	data, err := NewMoFromDomain(po.GetDomain()).MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+// BenchmarkMo_ParseLazy compares loading a catalog of 30000 messages and using 100 of them, with and without SetLazy
+func BenchmarkMo_ParseLazy(b *testing.B) {
+	data := largeMo(b, 10000)
+
+	for _, lazy := range []bool{false, true} {
+		name := "eager"
+		if lazy {
+			name = "lazy"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mo := NewMo()
+				mo.SetLazy(lazy)
+				mo.Parse(data)
+				for n := 0; n < 100; n++ {
+					mo.Get("Text " + strconv.Itoa(n))
+				}
+			}
+		})
+	}
+}
+
+func TestMo_SetMmap(t *testing.T) {
+	f, err := os.Open("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	mo := NewMo()
+	mo.SetMmap(true)
+	mo.ParseFile(f)
+
+	trans := mo.GetDomain().translations["My text"]
+	if trans == nil {
+		t.Fatal("Expected 'My text' to be parsed")
+	}
+	if _, err = mapFile(f); err == nil && (trans.lazy == nil || trans.lazy.src == nil) {
+		t.Error("Expected 'My text' to be decoded from the mapping")
+	}
+	if tr := mo.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+	if trans.lazy != nil && trans.lazy.src != nil {
+		t.Error("Expected the mapping to be released by 'My text' once decoded")
+	}
+	if tr := mo.GetNC("One with var: %s", "Several with vars: %s", 2, "Ctx", "v"); tr != "This one is the plural in a Ctx context: v" {
+		t.Errorf("Expected 'This one is the plural in a Ctx context: v' but got '%s'", tr)
+	}
+	if mo.lazy || mo.mapped != nil {
+		t.Error("Expected the lazy option to be restored after parsing")
+	}
+
+	// Files of other filesystems are read as usual
+	embedded, err := enUSFixture.Open("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer embedded.Close()
+
+	mo = NewMo()
+	mo.SetMmap(true)
+	mo.ParseFile(embedded)
+	if tr := mo.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+}
+
+// BenchmarkMo_ParseFileMmap compares the heap in use after loading a catalog of 30000 messages from disk
+// and using 100 of them, with and without SetMmap
+func BenchmarkMo_ParseFileMmap(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "large.mo")
+	if err := os.WriteFile(path, largeMo(b, 10000), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, mmap := range []bool{false, true} {
+		name := "heap"
+		if mmap {
+			name = "mmap"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			var inUse uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				f, err := os.Open(path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				mo := NewMo()
+				mo.SetMmap(mmap)
+				mo.ParseFile(f)
+				f.Close()
+				for n := 0; n < 100; n++ {
+					mo.Get("Text " + strconv.Itoa(n))
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				inUse += after.HeapInuse - before.HeapInuse
+				runtime.KeepAlive(mo)
+			}
+			b.ReportMetric(float64(inUse)/float64(b.N), "heap-B/op")
+		})
+	}
+}
+
+func TestNewMoFromBytes(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mo, err := NewMoFromBytes(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := mo.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+
+	if _, err = NewMoFromBytes([]byte("not a mo file")); err == nil {
+		t.Error("Expected an error for an invalid magic number")
+	}
+	if _, err = NewMoFromBytes(data[:30]); err == nil {
+		t.Error("Expected an error for a truncated file")
+	}
+}
+
+func TestMoParseErrors(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.mo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewMoFromBytes(data[:10])
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF but got '%v'", err)
+	}
 }
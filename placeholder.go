@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// PlaceholderStyle selects how the vars given to the Get* methods of a Locale are inserted in translations,
+// set with Locale.SetPlaceholderStyle
+type PlaceholderStyle int
+
+const (
+	// PrintfStyle formats translations with all the vars using the fmt.Printf syntax, i.e. "Hello %s". It's the default.
+	PrintfStyle PlaceholderStyle = iota
+
+	// NamedBraceStyle replaces {name} placeholders, i.e. "Hello {name}", with the values of the last var:
+	// a map with string keys, or a struct (or a pointer to one) whose exported fields are used by name.
+	// Placeholders without value are left as is. Unlike GetNamedN, {count} isn't set by the plural methods.
+	NamedBraceStyle
+
+	// TemplateStyle executes translations as text/template templates, i.e. "Hello {{.Name}}", with the last var as data.
+	// Templates are parsed once per Locale and string, and cached. If parsing or executing fails, the Translation is
+	// returned as is.
+	// With this style, catalogs are code: a Translation can read every exported field and call every exported method
+	// of the data, and the functions it holds. Only use it with catalogs as trusted as the source code, and pass
+	// data holding nothing more than the values translations may show, like a map[string]interface{}.
+	TemplateStyle
+)
+
+// printfArgs returns the vars to format with fmt, which are none unless the style is PrintfStyle
+func (style PlaceholderStyle) printfArgs(vars []interface{}) []interface{} {
+	if style != PrintfStyle {
+		return nil
+	}
+	return vars
+}
+
+// templateCache holds the templates parsed by TemplateStyle, by source string
+type templateCache struct {
+	templates sync.Map
+}
+
+// cachedTemplate is the result of parsing a string as template
+type cachedTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// parse returns the template parsed from str, parsing it on first use
+func (c *templateCache) parse(str string) (*template.Template, error) {
+	if cached, ok := c.templates.Load(str); ok {
+		return cached.(*cachedTemplate).tmpl, cached.(*cachedTemplate).err
+	}
+
+	tmpl, err := template.New("").Parse(str)
+	c.templates.Store(str, &cachedTemplate{tmpl: tmpl, err: err})
+	return tmpl, err
+}
+
+// interpolate inserts the last of vars in str according to the style. It returns str as is if there are no vars,
+// and along with an error if they can't be inserted. Nothing is done for PrintfStyle, which is formatted by Printf.
+// templates caches the templates of TemplateStyle; they're parsed on every call if it's nil.
+func (style PlaceholderStyle) interpolate(str string, vars []interface{}, templates *templateCache) (string, error) {
+	if style == PrintfStyle || len(vars) == 0 {
+		return str, nil
+	}
+	data := vars[len(vars)-1]
+
+	switch style {
+	case NamedBraceStyle:
+		values, err := templateData(data)
+		if err != nil {
+			return str, err
+		}
+		return formatNamed(str, values), nil
+
+	case TemplateStyle:
+		if !strings.Contains(str, "{{") {
+			return str, nil
+		}
+		var tmpl *template.Template
+		var err error
+		if templates != nil {
+			tmpl, err = templates.parse(str)
+		} else {
+			tmpl, err = template.New("").Parse(str)
+		}
+		if err != nil {
+			return str, err
+		}
+		var buf strings.Builder
+		if err = tmpl.Execute(&buf, data); err != nil {
+			return str, err
+		}
+		return buf.String(), nil
+	}
+
+	return str, nil
+}
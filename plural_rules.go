@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/tanyinloo/gotext/plurals"
+)
+
+// pluralRule is a built-in Plural-Forms rule for a language
+type pluralRule struct {
+	nplurals int
+	plural   string
+	expr     plurals.Expression
+
+	// CLDR plural category of each form
+	categories []string
+}
+
+// Built-in plural rules, as Plural-Forms expressions following the CLDR cardinal rules for integers,
+// along with the CLDR category ("zero", "one", "two", "few", "many" or "other") of each form.
+// A form can hold integers of several categories (i.e. the second Latvian form also holds 10 to 20, which are "zero"
+// for CLDR): it's then named after the category of most of its numbers.
+// Keys are language codes, with an optional region for languages where it changes the rule.
+var defaultPluralRules = map[string]*pluralRule{
+	// One form
+	"id": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"ja": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"ko": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"ms": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"th": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"vi": {nplurals: 1, plural: "0", categories: []string{"other"}},
+	"zh": {nplurals: 1, plural: "0", categories: []string{"other"}},
+
+	// Singular for 1 only
+	"af": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"bg": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"ca": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"da": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"de": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"el": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"en": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"es": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"et": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"eu": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"fi": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"gl": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"hu": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"it": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"nb": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"nl": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"nn": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"no": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"pt": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"sv": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"sw": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+	"tr": {nplurals: 2, plural: "(n != 1)", categories: []string{"one", "other"}},
+
+	// Singular for 0 and 1
+	"bn":    {nplurals: 2, plural: "(n > 1)", categories: []string{"one", "other"}},
+	"fa":    {nplurals: 2, plural: "(n > 1)", categories: []string{"one", "other"}},
+	"fr":    {nplurals: 2, plural: "(n > 1)", categories: []string{"one", "other"}},
+	"hi":    {nplurals: 2, plural: "(n > 1)", categories: []string{"one", "other"}},
+	"pt_BR": {nplurals: 2, plural: "(n > 1)", categories: []string{"one", "other"}},
+
+	// Other two forms rules
+	"is": {nplurals: 2, plural: "(n%10 != 1 || n%100 == 11)", categories: []string{"one", "other"}},
+	"mk": {nplurals: 2, plural: "(n%10 == 1 && n%100 != 11 ? 0 : 1)", categories: []string{"one", "other"}},
+
+	// Three forms
+	"be": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "many"}},
+	"bs": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"hr": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"ru": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "many"}},
+	"sr": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"uk": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "many"}},
+	"pl": {nplurals: 3, plural: "(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "many"}},
+	"cs": {nplurals: 3, plural: "(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"sk": {nplurals: 3, plural: "(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"lt": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && (n%100<10 || n%100>=20) ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"lv": {nplurals: 3, plural: "(n%10==1 && n%100!=11 ? 0 : n != 0 ? 1 : 2)", categories: []string{"one", "other", "zero"}},
+	"ro": {nplurals: 3, plural: "(n==1 ? 0 : n==0 || (n%100>0 && n%100<20) ? 1 : 2)", categories: []string{"one", "few", "other"}},
+	"he": {nplurals: 3, plural: "(n==1 ? 0 : n==2 ? 1 : 2)", categories: []string{"one", "two", "other"}},
+
+	// Four or more forms
+	"sl": {nplurals: 4, plural: "(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3)", categories: []string{"one", "two", "few", "other"}},
+	"cy": {nplurals: 4, plural: "(n==1 ? 0 : n==2 ? 1 : n != 8 && n != 11 ? 2 : 3)", categories: []string{"one", "two", "other", "many"}},
+	"ga": {nplurals: 5, plural: "(n==1 ? 0 : n==2 ? 1 : (n>2 && n<7) ? 2 : (n>6 && n<11) ? 3 : 4)", categories: []string{"one", "two", "few", "many", "other"}},
+	"ar": {nplurals: 6, plural: "(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5)", categories: []string{"zero", "one", "two", "few", "many", "other"}},
+}
+
+var compilePluralRules sync.Once
+
+// findPluralRule returns the built-in rule for lang, looking for the full locale (i.e. "pt_BR") and then for the language only.
+func findPluralRule(lang string) *pluralRule {
+	compilePluralRules.Do(func() {
+		for _, rule := range defaultPluralRules {
+			rule.expr, _ = plurals.Compile(rule.plural)
+		}
+	})
+
+	lang = strings.Replace(SimplifiedLocale(lang), "-", "_", -1)
+	if rule, ok := defaultPluralRules[lang]; ok && rule.expr != nil {
+		return rule
+	}
+	if idx := strings.Index(lang, "_"); idx != -1 {
+		if rule, ok := defaultPluralRules[strings.ToLower(lang[:idx])]; ok && rule.expr != nil {
+			return rule
+		}
+	}
+	if rule, ok := defaultPluralRules[strings.ToLower(lang)]; ok && rule.expr != nil {
+		return rule
+	}
+
+	return nil
+}
+
+// pluralCategory returns the CLDR plural category of n for lang, from the built-in rules.
+// Unknown languages use the Germanic rule: "one" for 1 and "other" otherwise.
+func pluralCategory(lang string, n int) string {
+	rule := findPluralRule(lang)
+	if rule == nil {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+
+	return rule.categories[rule.expr.Eval(uint32(n))]
+}
+
+// DefaultPluralRule returns the built-in plural rule for a language code (i.e. "ru", "pt_BR" or "fr-CA"):
+// the number of plural forms and a function returning the form index for n.
+// These rules are used by Domain when the Plural-Forms header is missing or invalid. ok is false for unknown languages.
+func DefaultPluralRule(lang string) (nplurals int, fn func(n int) int, ok bool) {
+	rule := findPluralRule(lang)
+	if rule == nil {
+		return 0, nil, false
+	}
+
+	return rule.nplurals, func(n int) int {
+		return rule.expr.Eval(uint32(n))
+	}, true
+}
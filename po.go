@@ -6,6 +6,8 @@
 package gotext
 
 import (
+	"bufio"
+	"bytes"
 	"io/fs"
 	"strconv"
 	"strings"
@@ -42,8 +44,20 @@ type Po struct {
 	PluralForms string
 
 	domain *Domain
+
+	// strict makes blank lines and comments inside an entry parse errors
+	strict bool
+
+	// progress is called every progressInterval entries while parsing
+	progress func(parsed int)
+
+	// splitter splits the content into lines, nil for the built-in line splitting
+	splitter bufio.SplitFunc
 }
 
+// progressInterval is the number of entries parsed between calls to the progress function set with Po.SetProgress
+const progressInterval = 1000
+
 type parseState int
 
 const (
@@ -66,6 +80,18 @@ func (po *Po) GetDomain() *Domain {
 	return po.domain
 }
 
+// SetStrict sets whether parsing reports blank lines and comments inside an entry as errors, like msgfmt does.
+// By default the parser tolerates them, to load hand-edited files:
+//   - blank lines between the keywords and strings of an entry are skipped
+//   - comments between msgctxt and msgid, or between msgid and msgstr, belong to that entry
+//   - comments between msgstr lines (i.e. before a continuation string or msgstr[1]) belong to that entry too
+//
+// Comments after the last msgstr line of an entry always belong to the next one.
+// In strict mode, the entries are still loaded the same way, but the first such line is reported as parse error.
+func (po *Po) SetStrict(strict bool) {
+	po.strict = strict
+}
+
 // Convenience interfaces
 func (po *Po) DropStaleTranslations() {
 	po.domain.DropStaleTranslations()
@@ -74,6 +100,9 @@ func (po *Po) DropStaleTranslations() {
 func (po *Po) SetRefs(str string, refs []string) {
 	po.domain.SetRefs(str, refs)
 }
+func (po *Po) AddRef(str string, ref string) {
+	po.domain.AddRef(str, ref)
+}
 func (po *Po) GetRefs(str string) []string {
 	return po.domain.GetRefs(str)
 }
@@ -129,6 +158,50 @@ func (po *Po) ParseFile(f fs.File) {
 
 // Parse loads the translations specified in the provided byte slice (buf)
 func (po *Po) Parse(buf []byte) {
+	po.parse(buf)
+}
+
+// ParseBytes loads the translations specified in the provided byte slice, in the GNU gettext .po format,
+// and returns the first parse error found, if any. The slice is scanned in place, so it's the fastest way
+// to load translations already in memory, like files embedded with go:embed.
+func (po *Po) ParseBytes(b []byte) error {
+	return po.parse(b)
+}
+
+// NewPoFromString creates a Po object and loads the translations from the provided string,
+// in the GNU gettext .po format. It returns an error if any line of the input can't be parsed.
+func NewPoFromString(s string) (*Po, error) {
+	po := NewPo()
+	if err := po.parse([]byte(s)); err != nil {
+		return nil, err
+	}
+
+	return po, nil
+}
+
+// SetProgress sets a function called while parsing with the number of entries parsed so far,
+// header and obsolete entries included: every 1000 entries, and once at the end with the total.
+// It's meant to log the progress of loading huge catalogs. The function is called with the domain locked,
+// so it must not use the Po. Set it to nil to disable it.
+func (po *Po) SetProgress(progress func(parsed int)) {
+	po.progress = progress
+}
+
+// SetLineSplitter sets the function splitting the content into lines while parsing, for files using unusual
+// separators, like form feeds or vertical tabs.
+// It follows the bufio.Scanner contract, and every token it returns must be a whole line: a comment,
+// a keyword line (i.e. `msgid "text"`), a string continuation line or a blank line. Tokens are trimmed of
+// surrounding white space, and the line numbers of parse errors count tokens. The splitter isn't called for
+// the UTF-8 byte order mark, which is skipped first. Set it to nil to restore the built-in splitting, which handles
+// "\n", "\r\n" and "\r" line endings without copying the content.
+func (po *Po) SetLineSplitter(split bufio.SplitFunc) {
+	po.splitter = split
+}
+
+// parse does the actual work for Parse.
+// Parsing is lenient and skips malformed lines, but the first one found is reported as error.
+// See SetStrict for the blank lines and comments tolerated inside entries.
+func (po *Po) parse(buf []byte) error {
 	if po.domain == nil {
 		panic("NewPo() was not used to instantiate this object")
 	}
@@ -139,43 +212,108 @@ func (po *Po) Parse(buf []byte) {
 	defer po.domain.trMutex.Unlock()
 	defer po.domain.pluralMutex.Unlock()
 
-	// Get lines
-	lines := strings.Split(string(buf), "\n")
-
 	// Init buffer
 	po.domain.trBuffer = NewTranslation()
 	po.domain.ctxBuffer = ""
-	po.domain.refBuffer = ""
+	po.domain.cmtBuffer = NewTranslation()
+	po.domain.obsBuffer = false
+
+	// Skip the UTF-8 byte order mark some editors write at the start of the file
+	buf = bytes.TrimPrefix(buf, []byte("\xEF\xBB\xBF"))
+
+	// Custom line splitting
+	var scanner *bufio.Scanner
+	if po.splitter != nil {
+		scanner = bufio.NewScanner(bytes.NewReader(buf))
+		scanner.Buffer(make([]byte, 0, 4096), len(buf)+4096)
+		scanner.Split(po.splitter)
+	}
 
+	var err error
 	state := head
-	for _, l := range lines {
+	parsed := 0
+	gap := 0 // Line of the first blank line or comment since the last keyword or string line, once an entry started
+	nl := -1 // Index of the next "\n" in buf, kept across lines so files with "\r" line endings are scanned once
+	for i := 0; ; i++ {
+		var line []byte
+		if scanner != nil {
+			if !scanner.Scan() {
+				if scanErr := scanner.Err(); scanErr != nil && err == nil {
+					err = newParseError("line %d: %v", i+1, scanErr)
+				}
+				break
+			}
+			line = scanner.Bytes()
+		} else if line, nl = nextLine(&buf, nl); line == nil {
+			break
+		}
+
 		// Trim spaces
-		l = strings.TrimSpace(l)
+		l := string(bytes.TrimSpace(line))
+
+		// Obsolete entries are commented out with "#~", parse them as regular lines
+		obsolete := false
+		if strings.HasPrefix(l, "#~") && !strings.HasPrefix(l, "#~|") {
+			l = strings.TrimSpace(l[2:])
+			obsolete = true
+		}
 
 		// Skip invalid lines
 		if !po.isValidLine(l) {
+			if err == nil && l != "" && l[0] != '#' {
+				err = newParseError("line %d: unexpected content %q", i+1, l)
+			}
+			if state != head && gap == 0 && (l == "" || l[0] == '#') {
+				gap = i + 1
+			}
 			po.parseComment(l, state)
 			continue
 		}
 
+		if err == nil && !isQuotedValue(l) {
+			err = newParseError("line %d: malformed string %q", i+1, l)
+		}
+
+		// Blank lines and comments interrupting an entry
+		if gap > 0 {
+			if strings.HasPrefix(l, "\"") || strings.HasPrefix(l, "msgstr") || strings.HasPrefix(l, "msgid_plural") ||
+				(state == msgCtxt && strings.HasPrefix(l, "msgid")) {
+				if po.strict && err == nil {
+					err = newParseError("line %d: blank line or comment inside an entry", gap)
+				}
+				if state == msgStr {
+					po.attachComments()
+				}
+			}
+			gap = 0
+		}
+
 		// Buffer context and continue
 		if strings.HasPrefix(l, "msgctxt") {
-			po.parseContext(l)
+			po.parseContext(l, obsolete)
 			state = msgCtxt
 			continue
 		}
 
 		// Buffer msgid and continue
 		if strings.HasPrefix(l, "msgid") && !strings.HasPrefix(l, "msgid_plural") {
-			po.parseID(l)
+			po.parseID(l, obsolete)
+			po.domain.trBuffer.Line = i + 1
 			state = msgID
+
+			parsed++
+			if po.progress != nil && parsed%progressInterval == 0 {
+				po.progress(parsed)
+			}
 			continue
 		}
 
 		// Check for plural form
 		if strings.HasPrefix(l, "msgid_plural") {
 			po.parsePluralID(l)
-			po.domain.pluralTranslations[po.domain.trBuffer.PluralID] = po.domain.trBuffer
+			if !po.domain.obsBuffer {
+				po.domain.pluralTranslations[po.domain.trBuffer.PluralID] = po.domain.trBuffer
+			}
 			state = msgIDPlural
 			continue
 		}
@@ -197,27 +335,86 @@ func (po *Po) Parse(buf []byte) {
 	// Save last Translation buffer.
 	po.saveBuffer()
 
+	if po.progress != nil {
+		po.progress(parsed)
+	}
+
 	// Parse headers
-	po.domain.parseHeaders()
+	if headerErr := po.domain.parseHeaders(); err == nil {
+		err = headerErr
+	}
 
 	// set values on this struct
 	// this is for backwards compatibility
 	po.Language = po.domain.Language
 	po.PluralForms = po.domain.PluralForms
 	po.Headers = po.domain.Headers
+
+	return err
+}
+
+// nextLine returns the next line of buf without its line ending, and advances buf past it, without copying
+// the whole buffer. "\r\n" and "\r" line endings are handled like "\n". nl is the index of the next "\n" in buf,
+// or -1 if unknown, and is returned updated. It returns a nil line when buf is empty.
+func nextLine(buf *[]byte, nl int) ([]byte, int) {
+	b := *buf
+	if len(b) == 0 {
+		return nil, nl
+	}
+	if nl < 0 {
+		if nl = bytes.IndexByte(b, '\n'); nl == -1 {
+			nl = len(b)
+		}
+	}
+	idx := nl
+	if cr := bytes.IndexByte(b[:nl], '\r'); cr != -1 {
+		idx = cr
+	}
+	next := idx + 1
+	if idx < len(b) && b[idx] == '\r' && next < len(b) && b[next] == '\n' {
+		next++
+	}
+	if next > len(b) {
+		next = len(b)
+	}
+	*buf = b[next:]
+	return b[:idx], nl - next
 }
 
 // saveBuffer takes the context and Translation buffers
 // and saves it on the translations collection
 func (po *Po) saveBuffer() {
-	// With no context...
-	if po.domain.ctxBuffer == "" {
+	po.domain.internTranslation(po.domain.trBuffer)
+	po.domain.ctxBuffer = po.domain.intern(po.domain.ctxBuffer)
+
+	if po.domain.obsBuffer {
+		// Obsolete entries are kept apart from the active ones, so they're never used for lookups.
+		// Keep the context buffer until its msgid is parsed.
+		if po.domain.trBuffer.ID != "" || po.domain.ctxBuffer == "" {
+			if _, ok := po.domain.obsoleteTranslations[po.domain.ctxBuffer]; !ok {
+				po.domain.obsoleteTranslations[po.domain.ctxBuffer] = make(map[string]*Translation)
+			}
+			po.domain.obsoleteTranslations[po.domain.ctxBuffer][po.domain.trBuffer.ID] = po.domain.trBuffer
+			po.domain.ctxBuffer = ""
+		}
+	} else if po.domain.ctxBuffer == "" {
+		// With no context...
+		if prev, ok := po.domain.translations[po.domain.trBuffer.ID]; ok {
+			po.saveDuplicate(prev)
+		} else {
+			po.domain.recordOrder("", po.domain.trBuffer.ID)
+		}
 		po.domain.translations[po.domain.trBuffer.ID] = po.domain.trBuffer
 	} else {
 		// With context...
 		if _, ok := po.domain.contexts[po.domain.ctxBuffer]; !ok {
 			po.domain.contexts[po.domain.ctxBuffer] = make(map[string]*Translation)
 		}
+		if prev, ok := po.domain.contexts[po.domain.ctxBuffer][po.domain.trBuffer.ID]; ok {
+			po.saveDuplicate(prev)
+		} else {
+			po.domain.recordOrder(po.domain.ctxBuffer, po.domain.trBuffer.ID)
+		}
 		po.domain.contexts[po.domain.ctxBuffer][po.domain.trBuffer.ID] = po.domain.trBuffer
 
 		// Cleanup current context buffer if needed
@@ -227,35 +424,89 @@ func (po *Po) saveBuffer() {
 	}
 
 	// Flush Translation buffer
-	if po.domain.refBuffer == "" {
-		po.domain.trBuffer = NewTranslation()
-	} else {
-		po.domain.trBuffer = NewTranslationWithRefs(strings.Split(po.domain.refBuffer, " "))
+	po.domain.trBuffer = NewTranslation()
+}
+
+// saveDuplicate keeps track of a previously parsed Translation about to be replaced
+// by the Translation buffer, so it can be reported by Domain.FindDuplicates
+func (po *Po) saveDuplicate(prev *Translation) {
+	// The header and context placeholders use an empty ID
+	if prev == nil || prev.ID == "" {
+		return
 	}
+
+	po.domain.duplicates = append(po.domain.duplicates, contextTranslation{po.domain.ctxBuffer, prev})
 }
 
 // Either preserves comments before the first "msgid", for later round-trip.
-// Or preserves source references for a given translation.
+// Or buffers source references, flags and comments for the next translation.
 func (po *Po) parseComment(l string, state parseState) {
-	if len(l) > 0 && l[0] == '#' {
-		if state == head {
-			po.domain.headerComments = append(po.domain.headerComments, l)
-		} else if len(l) > 1 {
-			switch l[1] {
-			case ':':
-				if len(l) > 2 {
-					po.domain.refBuffer = strings.TrimSpace(l[2:])
-				}
+	if len(l) == 0 || l[0] != '#' {
+		return
+	}
+
+	if state == head {
+		if po.domain.HeaderComment != "" {
+			po.domain.HeaderComment += "\n"
+		}
+		po.domain.HeaderComment += l
+		return
+	}
+
+	// Plural form comments are written back by MarshalText, see Domain.SetPluralComments
+	if isPluralComment(l) {
+		return
+	}
+
+	// Comments misplaced between msgid and msgstr belong to the entry being parsed
+	cmt := po.domain.cmtBuffer
+	if state == msgID || state == msgIDPlural {
+		cmt = po.domain.trBuffer
+	}
+
+	switch {
+	case strings.HasPrefix(l, "#:"):
+		cmt.Refs = append(cmt.Refs, strings.Fields(l[2:])...)
+
+	case strings.HasPrefix(l, "#,"):
+		for _, flag := range strings.Split(l[2:], ",") {
+			if flag = strings.TrimSpace(flag); flag != "" {
+				cmt.Flags = append(cmt.Flags, flag)
 			}
 		}
+
+	case strings.HasPrefix(l, "#."):
+		cmt.ExtractedComments = append(cmt.ExtractedComments, strings.TrimPrefix(l[2:], " "))
+
+	case strings.HasPrefix(l, "#~|"):
+		cmt.Previous = append(cmt.Previous, strings.TrimSpace(l[3:]))
+
+	case strings.HasPrefix(l, "#|"):
+		cmt.Previous = append(cmt.Previous, strings.TrimSpace(l[2:]))
+
+	default:
+		cmt.Comments = append(cmt.Comments, strings.TrimPrefix(l[1:], " "))
 	}
 }
 
+// attachComments moves the buffered comments to the current Translation buffer,
+// when they turn out to be inside its msgstr lines
+func (po *Po) attachComments() {
+	cmt, trans := po.domain.cmtBuffer, po.domain.trBuffer
+	trans.Refs = append(trans.Refs, cmt.Refs...)
+	trans.Comments = append(trans.Comments, cmt.Comments...)
+	trans.ExtractedComments = append(trans.ExtractedComments, cmt.ExtractedComments...)
+	trans.Flags = append(trans.Flags, cmt.Flags...)
+	trans.Previous = append(trans.Previous, cmt.Previous...)
+	po.domain.cmtBuffer = NewTranslation()
+}
+
 // parseContext takes a line starting with "msgctxt",
 // saves the current Translation buffer and creates a new context.
-func (po *Po) parseContext(l string) {
+func (po *Po) parseContext(l string, obsolete bool) {
 	// Save current Translation buffer.
 	po.saveBuffer()
+	po.domain.obsBuffer = obsolete
 
 	// Buffer context
 	po.domain.ctxBuffer, _ = strconv.Unquote(strings.TrimSpace(strings.TrimPrefix(l, "msgctxt")))
@@ -263,12 +514,22 @@ func (po *Po) parseContext(l string) {
 
 // parseID takes a line starting with "msgid",
 // saves the current Translation and creates a new msgid buffer.
-func (po *Po) parseID(l string) {
+func (po *Po) parseID(l string, obsolete bool) {
 	// Save current Translation buffer.
 	po.saveBuffer()
+	po.domain.obsBuffer = obsolete
 
 	// Set id
 	po.domain.trBuffer.ID, _ = strconv.Unquote(strings.TrimSpace(strings.TrimPrefix(l, "msgid")))
+
+	// Attach buffered comments
+	cmt := po.domain.cmtBuffer
+	po.domain.trBuffer.Refs = cmt.Refs
+	po.domain.trBuffer.Comments = cmt.Comments
+	po.domain.trBuffer.ExtractedComments = cmt.ExtractedComments
+	po.domain.trBuffer.Flags = cmt.Flags
+	po.domain.trBuffer.Previous = cmt.Previous
+	po.domain.cmtBuffer = NewTranslation()
 }
 
 // parsePluralID saves the plural id buffer from a line starting with "msgid_plural"
@@ -289,7 +550,7 @@ func (po *Po) parseMessage(l string) {
 		}
 
 		// Parse index
-		i, err := strconv.Atoi(l[1:idx])
+		i, err := strconv.Atoi(strings.TrimSpace(l[1:idx]))
 		if err != nil {
 			// Skip wrong index formatting
 			return
@@ -331,6 +592,17 @@ func (po *Po) parseString(l string, state parseState) {
 	}
 }
 
+// isQuotedValue checks the line, after its keyword, ends with a single well formed quoted string.
+func isQuotedValue(l string) bool {
+	idx := strings.Index(l, "\"")
+	if idx == -1 {
+		return false
+	}
+
+	_, err := strconv.Unquote(l[idx:])
+	return err == nil
+}
+
 // isValidLine checks for line prefixes to detect valid syntax.
 func (po *Po) isValidLine(l string) bool {
 	// Check prefix
@@ -6,8 +6,17 @@
 package gotext
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 const (
@@ -752,3 +761,962 @@ func TestPoTextEncoding(t *testing.T) {
 		t.Errorf("Expected 'This one is plural in a Ctx context: Test' but got '%s'", tr)
 	}
 }
+
+func TestDomain_HeaderGetters(t *testing.T) {
+	po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Project-Id-Version: gotext 1.5.0\n"
+"Report-Msgid-Bugs-To: bugs@example.com\n"
+"POT-Creation-Date: YEAR-MO-DA HO:MI+ZONE\n"
+"PO-Revision-Date: 2021-06-15 18:30+0200\n"
+"Last-Translator: Jane Doe <jane@example.com>\n"
+"Language-Team: French <fr@example.com>\n"
+"Language: fr\n"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := po.GetDomain()
+
+	tests := map[string][2]string{
+		"ProjectIDVersion":  {domain.ProjectIDVersion(), "gotext 1.5.0"},
+		"ReportMsgidBugsTo": {domain.ReportMsgidBugsTo(), "bugs@example.com"},
+		"LastTranslator":    {domain.LastTranslator(), "Jane Doe <jane@example.com>"},
+		"LanguageTeam":      {domain.LanguageTeam(), "French <fr@example.com>"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+
+	revision, err := domain.RevisionDate()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := time.Date(2021, 6, 15, 16, 30, 0, 0, time.UTC)
+	if !revision.Equal(expected) {
+		t.Errorf("Expected '%s' but got '%s'", expected, revision)
+	}
+
+	// Placeholder and missing headers give the zero value
+	if creation, err := domain.CreationDate(); err != nil || !creation.IsZero() {
+		t.Errorf("Expected zero time but got '%s' (%v)", creation, err)
+	}
+	empty := NewDomain()
+	if revision, err := empty.RevisionDate(); err != nil || !revision.IsZero() {
+		t.Errorf("Expected zero time but got '%s' (%v)", revision, err)
+	}
+	if name := empty.LastTranslator(); name != "" {
+		t.Errorf("Expected empty string but got '%s'", name)
+	}
+
+	domain.Headers.Set("PO-Revision-Date", "yesterday")
+	if _, err = domain.RevisionDate(); !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+}
+
+func TestPoParseBOM(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/bom.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\xEF\xBB\xBF")) {
+		t.Fatal("Expected the fixture to start with a BOM")
+	}
+
+	po := NewPo()
+	if err = po.ParseBytes(data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if po.Language != "en_US" {
+		t.Errorf("Expected 'en_US' but got '%s'", po.Language)
+	}
+	if formula := po.GetDomain().PluralFormula(); formula != "(n != 1)" {
+		t.Errorf("Expected '(n != 1)' but got '%s'", formula)
+	}
+	if tr := po.Get("My text"); tr != translatedText {
+		t.Errorf("Expected '%s' but got '%s'", translatedText, tr)
+	}
+}
+
+func TestPoParseWhitespace(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/whitespace.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	po := NewPo()
+	if err = po.ParseBytes(data); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := map[string][2]string{
+		"tab":         {po.Get("Tab separated"), "Tab translation"},
+		"multi-space": {po.Get("Multi-space separated"), "Multi-space translation"},
+		"context":     {po.GetC("Context", "Ctx"), "Context translation"},
+		"index":       {po.GetN("One file", "%d files", 1), "One translated file"},
+		"plural":      {po.GetN("One file", "%d files", 2, 2), "2 translated files"},
+		"multi-line":  {po.Get("Multi-line id"), "Multi-line translation"},
+	}
+	for name, tc := range tests {
+		if tc[0] != tc[1] {
+			t.Errorf("%s: expected '%s' but got '%s'", name, tc[1], tc[0])
+		}
+	}
+}
+
+func TestPoParseLineEndings(t *testing.T) {
+	lf := NewPo()
+	f, err := enUSFixture.Open("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf.ParseFile(f)
+
+	data, err := enUSFixture.ReadFile("fixtures/en_US/crlf.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\r\n")) {
+		t.Fatal("Expected the fixture to use CRLF line endings")
+	}
+
+	for name, data := range map[string][]byte{
+		"CRLF": data,
+		"CR":   bytes.Replace(data, []byte("\r\n"), []byte("\r"), -1),
+	} {
+		po := NewPo()
+		po.Parse(data)
+
+		if !reflect.DeepEqual(po.Headers, lf.Headers) {
+			t.Errorf("%s: expected headers %v but got %v", name, lf.Headers, po.Headers)
+		}
+
+		translations := po.GetDomain().GetTranslations()
+		if len(translations) != len(lf.GetDomain().GetTranslations()) {
+			t.Errorf("%s: expected %d translations but got %d", name, len(lf.GetDomain().GetTranslations()), len(translations))
+		}
+		for id, trans := range lf.GetDomain().GetTranslations() {
+			if !trans.Equal(translations[id]) {
+				t.Errorf("%s: expected the same Translation for '%s'", name, id)
+			}
+		}
+		if tr := po.GetC("Some random in a context", "Ctx"); tr != "Some random translation in a context" {
+			t.Errorf("%s: expected 'Some random translation in a context' but got '%s'", name, tr)
+		}
+	}
+}
+
+func TestDomain_SetMarshalOptions(t *testing.T) {
+	catalog := func(date string) *Domain {
+		po, err := NewPoFromString(`msgid ""
+msgstr ""
+"Project-Id-Version: gotext\n"
+"POT-Creation-Date: ` + date + `\n"
+"PO-Revision-Date: ` + date + `\n"
+"X-Generator: gotext ` + date + `\n"
+"Language: fr\n"
+
+msgid "One"
+msgstr "Un"
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return po.GetDomain()
+	}
+
+	first, second := catalog("2020-01-01 10:00+0000"), catalog("2021-06-15 18:30+0200")
+	opts := MarshalOptions{OmitDates: true, OmitHeaders: []string{"x-generator"}}
+	first.SetMarshalOptions(opts)
+	second.SetMarshalOptions(opts)
+
+	a, err := first.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := first.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := second.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(a) != string(b) || string(a) != string(c) {
+		t.Errorf("Expected identical output but got:\n%s\n%s", a, c)
+	}
+	for _, header := range []string{"POT-Creation-Date", "PO-Revision-Date", "X-Generator"} {
+		if strings.Contains(string(a), header) {
+			t.Errorf("Expected '%s' to be omitted", header)
+		}
+	}
+	if !strings.Contains(string(a), "Project-Id-Version: gotext") || !strings.Contains(string(a), `msgstr "Un"`) {
+		t.Errorf("Expected other headers and entries to be kept, got:\n%s", a)
+	}
+
+	// Headers are still available
+	if date := first.GetHeader("PO-Revision-Date"); date != "2020-01-01 10:00+0000" {
+		t.Errorf("Expected '2020-01-01 10:00+0000' but got '%s'", date)
+	}
+}
+
+func TestPoCommentsRoundTrip(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/comments.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	po := NewPo()
+	po.Parse(data)
+
+	// Obsolete entries aren't used for lookups
+	if tr := po.Get("Obsolete"); tr != "Obsolete" {
+		t.Errorf("Expected 'Obsolete' but got '%s'", tr)
+	}
+	if tr := po.GetC("Context text", "Context"); tr != "Context text translated" {
+		t.Errorf("Expected 'Context text translated' but got '%s'", tr)
+	}
+
+	expected := "# Translations for the comments fixture.\n# This file is distributed under the same license as the gotext package."
+	if po.GetDomain().HeaderComment != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, po.GetDomain().HeaderComment)
+	}
+
+	trans := po.GetDomain().translations["Text %d"]
+	if len(trans.Flags) != 2 || trans.Flags[0] != "fuzzy" || trans.Flags[1] != "go-format" {
+		t.Errorf("Unexpected flags: %v", trans.Flags)
+	}
+	if len(trans.Previous) != 1 || trans.Previous[0] != `msgid "Old text %d"` {
+		t.Errorf("Unexpected previous comments: %v", trans.Previous)
+	}
+
+	buff, err := po.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buff) != string(data) {
+		t.Errorf("Expected round-trip to be byte-stable, got:\n%s", buff)
+	}
+}
+
+func TestPoObsoletePluralsRoundTrip(t *testing.T) {
+	data, err := enUSFixture.ReadFile("fixtures/en_US/obsolete_plurals.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	po := NewPo()
+	po.Parse(data)
+
+	// Obsolete plural entries aren't used for lookups, by msgid nor by plural msgid
+	folder, folders := "%d folder", "%d folders"
+	if tr := po.GetN(folder, folders, 2, 2); tr != "2 folders" {
+		t.Errorf("Expected '2 folders' but got '%s'", tr)
+	}
+	if tr := po.Get(folders, 2); tr != "2 folders" {
+		t.Errorf("Expected '2 folders' but got '%s'", tr)
+	}
+
+	trans := po.GetDomain().obsoleteTranslations[""]["%d message"]
+	if trans == nil {
+		t.Fatal("Expected the obsolete plural entry to be parsed")
+	}
+	if trans.PluralID != "%d messages" || len(trans.Trs) != 2 || trans.Trs[0] != "%d message translated" || trans.Trs[1] != "" {
+		t.Errorf("Unexpected obsolete plural entry: %+v", trans)
+	}
+	if len(trans.Previous) != 2 || trans.Previous[1] != `msgid_plural "%d old messages"` {
+		t.Errorf("Unexpected previous comments: %v", trans.Previous)
+	}
+	if trans = po.GetDomain().obsoleteTranslations["Menu"]["%d item"]; trans == nil || trans.Trs[1] != "%d items translated" {
+		t.Errorf("Unexpected obsolete plural entry in context: %+v", trans)
+	}
+
+	buff, err := po.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buff) != string(data) {
+		t.Errorf("Expected round-trip to be byte-stable, got:\n%s", buff)
+	}
+
+	// Revived plural entries get all their forms back
+	if !po.GetDomain().Revive(folder) {
+		t.Fatal("Expected the obsolete plural entry to be revived")
+	}
+	if tr := po.GetN(folder, folders, 2, 2); tr != "2 folders translated" {
+		t.Errorf("Expected '2 folders translated' but got '%s'", tr)
+	}
+}
+
+func TestPoFindDuplicates(t *testing.T) {
+	str := `
+msgid "One"
+msgstr "First one"
+
+msgid "Two"
+msgstr "Two"
+
+msgid "One"
+msgstr "Second one"
+
+msgctxt "Ctx"
+msgid "One"
+msgstr "One in context"
+
+msgctxt "Ctx"
+msgid "One"
+msgstr "Another one in context"
+
+msgid "Two "
+msgstr "Two with space"
+`
+
+	po := NewPo()
+	po.Parse([]byte(str))
+
+	dups := po.GetDomain().FindDuplicates(false)
+	if len(dups) != 2 {
+		t.Fatalf("Expected 2 duplicate groups but got %d", len(dups))
+	}
+	if len(dups[0]) != 2 || dups[0][0].Get() != "First one" || dups[0][1].Get() != "Second one" {
+		t.Errorf("Unexpected duplicates for 'One': %v", dups[0])
+	}
+	if len(dups[1]) != 2 || dups[1][0].Get() != "One in context" || dups[1][1].Get() != "Another one in context" {
+		t.Errorf("Unexpected duplicates for 'One' in context: %v", dups[1])
+	}
+
+	// Last entry wins for lookups
+	if tr := po.Get("One"); tr != "Second one" {
+		t.Errorf("Expected 'Second one' but got '%s'", tr)
+	}
+
+	dups = po.GetDomain().FindDuplicates(true)
+	if len(dups) != 3 {
+		t.Fatalf("Expected 3 duplicate groups but got %d", len(dups))
+	}
+	if len(dups[1]) != 2 || dups[1][0].ID != "Two" || dups[1][1].ID != "Two " {
+		t.Errorf("Unexpected duplicates for 'Two': %v", dups[1])
+	}
+}
+
+func TestNewPoFromString(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid ""
+msgstr ""
+"Language: en\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+# Some comment
+msgid "My text"
+msgstr "Translated text"
+
+msgid "One with var: %s"
+msgid_plural "Several with vars: %s"
+msgstr[0] "This one is the singular: %s"
+msgstr[1] "This one is the plural: %s"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if tr := po.Get("My text"); tr != "Translated text" {
+		t.Errorf("Expected 'Translated text' but got '%s'", tr)
+	}
+	if tr := po.GetN("One with var: %s", "Several with vars: %s", 2, "v"); tr != "This one is the plural: v" {
+		t.Errorf("Expected 'This one is the plural: v' but got '%s'", tr)
+	}
+
+	_, err = NewPoFromString(`
+msgid "My text"
+msgstr "Translated
+`)
+	if err == nil {
+		t.Error("Expected an error for an unterminated string")
+	}
+
+	_, err = NewPoFromString(`
+msgid "My text"
+this is not a po line
+msgstr "Translated text"
+`)
+	if err == nil {
+		t.Error("Expected an error for an invalid line")
+	}
+
+	// Fixtures are valid
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewPoFromString(string(data)); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestTranslation_IsTranslated(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid "Same"
+msgstr "Same"
+
+msgid "Empty"
+msgstr ""
+
+msgid "One"
+msgid_plural "Several"
+msgstr[0] "One"
+msgstr[1] ""
+
+msgid "Missing"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := map[string]bool{
+		"Same":    true,
+		"Empty":   false,
+		"One":     false,
+		"Missing": false,
+	}
+	for id, expected := range tests {
+		if tr := po.GetDomain().translations[id].IsTranslated(); tr != expected {
+			t.Errorf("Expected IsTranslated() for '%s' to be %v but got %v", id, expected, tr)
+		}
+	}
+}
+
+func TestTranslation_CheckFormat(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid "Hello %s"
+msgstr "Bonjour %s"
+
+msgid "Wrong %s"
+msgstr "Faux %d"
+
+msgid "Reordered %s %d"
+msgstr "%[2]d réordonné %[1]s"
+
+msgid "Missing %s"
+msgstr "Manquant"
+
+msgid "Untranslated %s"
+msgstr ""
+
+#, no-go-format
+msgid "Literal %s"
+msgstr "Littéral"
+
+#, go-format
+msgid "Flagged"
+msgstr "Drapeau %d"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+
+msgid "%d dir"
+msgid_plural "%d dirs"
+msgstr[0] "%d dossier"
+msgstr[1] "%s dossiers"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := map[string]bool{
+		"Hello %s":        true,
+		"Wrong %s":        false,
+		"Reordered %s %d": true,
+		"Missing %s":      false,
+		"Untranslated %s": true,
+		"Literal %s":      true,
+		"Flagged":         false,
+		"%d file":         true,
+		"%d dir":          false,
+	}
+	for id, valid := range tests {
+		err := po.GetDomain().translations[id].CheckFormat()
+		if valid && err != nil {
+			t.Errorf("Unexpected error for '%s': %s", id, err)
+		}
+		if !valid && !errors.Is(err, ErrFormat) {
+			t.Errorf("Expected ErrFormat for '%s' but got '%v'", id, err)
+		}
+	}
+
+	if line := po.GetDomain().translations["Wrong %s"].Line; line != 5 {
+		t.Errorf("Expected line 5 but got %d", line)
+	}
+}
+
+func TestTranslation_IsFormat(t *testing.T) {
+	po, err := NewPoFromString(`
+msgid "Hello %s"
+msgstr "Bonjour %s"
+
+msgid "Progress"
+msgstr "Progression : 100 %"
+
+msgid "Discount"
+msgstr "Remise de 20% sur %s"
+
+#, no-c-format
+msgid "Off by %s"
+msgstr "Réduction de %s"
+
+#, c-format
+msgid "Literal"
+msgstr "100%%"
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "Un fichier"
+msgstr[1] "%d fichiers"
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tests := map[string]bool{
+		"Hello %s":   true,
+		"Progress":   false,
+		"Discount":   true,
+		"Off by %s":  false,
+		"Literal":    true,
+		"%d file":    true,
+		"Missing %s": true,
+	}
+	domain := po.GetDomain()
+	for id, expected := range tests {
+		trans := domain.translations[id]
+		if trans == nil {
+			trans = NewTranslation()
+			trans.ID = id
+		}
+		if format := trans.IsFormat(); format != expected {
+			t.Errorf("Expected IsFormat %v for '%s' but got %v", expected, id, format)
+		}
+	}
+
+	// Translations of non-format strings aren't formatted, even with vars
+	progress, literal := "Progress", "Literal"
+	for _, tc := range []struct {
+		result, expected string
+	}{
+		{po.Get(progress, "ignored"), "Progression : 100 %"},
+		{po.Get("Off by %s", "x"), "Réduction de %s"},
+		{po.Get(literal, "ignored"), "100%%!(EXTRA string=ignored)"},
+		{po.Get("Hello %s", "Alice"), "Bonjour Alice"},
+		{po.GetN("%d file", "%d files", 1, 1), "Un fichier%!(EXTRA int=1)"},
+		{po.Get("Untranslated %s", "x"), "Untranslated x"},
+	} {
+		if tc.result != tc.expected {
+			t.Errorf("Expected '%s' but got '%s'", tc.expected, tc.result)
+		}
+	}
+}
+
+func TestPoParseErrors(t *testing.T) {
+	_, err := NewPoFromString(`
+msgid "My text"
+msgstr "Translated
+`)
+	if !errors.Is(err, ErrParse) {
+		t.Errorf("Expected ErrParse but got '%v'", err)
+	}
+
+	_, err = NewPoFromString(`
+msgid ""
+msgstr ""
+"Plural-Forms: nplurals=2; plural=(n != ;\n"
+`)
+	if !errors.Is(err, ErrBadPluralForm) {
+		t.Errorf("Expected ErrBadPluralForm but got '%v'", err)
+	}
+}
+
+// largePo returns a catalog with n singular, plural and context entries
+func largePo(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("msgid \"\"\nmsgstr \"\"\n\"Language: en\\n\"\n\"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "\n#: main.go:%d\nmsgid \"Text %d\"\nmsgstr \"Translated %d\"\n", i, i, i)
+		fmt.Fprintf(&buf, "\nmsgid \"One %d\"\nmsgid_plural \"Several %d\"\nmsgstr[0] \"One translated %d\"\nmsgstr[1] \"Several translated %d\"\n", i, i, i, i)
+		fmt.Fprintf(&buf, "\nmsgctxt \"Ctx\"\nmsgid \"Text %d\"\nmsgstr \"\"\n\"Multi-line \"\n\"translated %d\"\n", i, i)
+	}
+	return buf.Bytes()
+}
+
+// catalogPo returns a catalog of n msgids in 5 contexts, with references and flags, translated to lang
+func catalogPo(lang string, n int) []byte {
+	contexts := []string{"menu", "toolbar", "dialog", "status bar", "settings"}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "msgid \"\"\nmsgstr \"\"\n\"Language: %s\\n\"\n\"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"\n", lang)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "\n#: internal/app/handlers/handler%d.go:%d\n#, go-format\nmsgctxt \"%s\"\n", i/20, i, contexts[i%len(contexts)])
+		fmt.Fprintf(&buf, "msgid \"Open the selected item number %%d of the list %d\"\nmsgstr \"[%s] Open item %%d %d\"\n", i, lang, i)
+	}
+	return buf.Bytes()
+}
+
+func TestPo_Interning(t *testing.T) {
+	pool := newStringPool()
+
+	var domains []*Domain
+	for _, lang := range []string{"fr", "de"} {
+		po := NewPo()
+		po.GetDomain().pool = pool
+		if err := po.ParseBytes(catalogPo(lang, 10)); err != nil {
+			t.Fatal(err)
+		}
+		domains = append(domains, po.GetDomain())
+
+		if tr := po.GetC("Open the selected item number %d of the list 3", "status bar", 7); tr != "["+lang+"] Open item 7 3" {
+			t.Errorf("Expected '[%s] Open item 7 3' but got '%s'", lang, tr)
+		}
+	}
+
+	var ids []string
+	for _, do := range domains {
+		do.ForEachC(func(ctx, id string, trans *Translation) bool {
+			if ctx == "menu" && id == "Open the selected item number %d of the list 0" {
+				ids = append(ids, trans.ID)
+			}
+			return true
+		})
+	}
+	if len(ids) != 2 || stringData(ids[0]) != stringData(ids[1]) {
+		t.Error("Expected both domains to share the same msgid string")
+	}
+}
+
+// stringData returns the address of the bytes of s
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func BenchmarkPo_ParseInterning(b *testing.B) {
+	var catalogs [][]byte
+	for _, lang := range []string{"fr", "de", "es", "it"} {
+		catalogs = append(catalogs, catalogPo(lang, 2000))
+	}
+
+	for _, interning := range []bool{false, true} {
+		b.Run(fmt.Sprintf("interning=%v", interning), func(b *testing.B) {
+			var stats runtime.MemStats
+			retained := uint64(0)
+			for i := 0; i < b.N; i++ {
+				var pool *stringPool
+				if interning {
+					pool = newStringPool()
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				before := stats.HeapAlloc
+
+				domains := make([]*Domain, len(catalogs))
+				for n, data := range catalogs {
+					po := NewPo()
+					po.GetDomain().pool = pool
+					po.ParseBytes(data)
+					domains[n] = po.GetDomain()
+				}
+
+				// The pool is kept by the domains, as it is by Locale
+				runtime.GC()
+				runtime.ReadMemStats(&stats)
+				retained += stats.HeapAlloc - before
+				runtime.KeepAlive(domains)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func TestTranslation_Range(t *testing.T) {
+	str := `msgid ""
+msgstr "Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+#, go-format, range: 0..9, x-custom-flag
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] "%d élément"
+msgstr[1] "%d éléments"
+
+#, range: 10..1
+msgid "Bad"
+msgstr "Mauvais"
+`
+	po, err := NewPoFromString(str)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	translations := po.GetDomain().GetTranslations()
+	if lo, hi, ok := translations["%d item"].Range(); !ok || lo != 0 || hi != 9 {
+		t.Errorf("Expected range 0..9 but got %d..%d (%v)", lo, hi, ok)
+	}
+	if _, _, ok := translations["Bad"].Range(); ok {
+		t.Error("Expected an invalid range")
+	}
+	if _, _, ok := NewTranslation().Range(); ok {
+		t.Error("Expected no range")
+	}
+
+	// Flags are preserved verbatim
+	text, err := po.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, flags := range []string{"#, go-format, range: 0..9, x-custom-flag\n", "#, range: 10..1\n"} {
+		if !strings.Contains(string(text), flags) {
+			t.Errorf("Expected '%s' in:\n%s", flags, text)
+		}
+	}
+}
+
+func TestPo_SetProgress(t *testing.T) {
+	var calls []int
+	po := NewPo()
+	po.SetProgress(func(parsed int) {
+		calls = append(calls, parsed)
+	})
+	if err := po.ParseBytes(largePo(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3000 entries and the header
+	expected := []int{1000, 2000, 3000, 3001}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls with %v but got %v", expected, calls)
+	}
+}
+
+func TestPo_SetLineSplitter(t *testing.T) {
+	// Lines separated by form feeds and vertical tabs
+	str := "msgid \"\"\fmsgstr \"\"\v\"Language: fr\\n\"\f\fmsgid \"One\"\vmsgstr \"Un\"\f"
+
+	split := func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexAny(data, "\n\f\v"); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+
+	po := NewPo()
+	po.SetLineSplitter(split)
+	if err := po.ParseBytes([]byte(str)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if tr := po.Get("One"); tr != "Un" {
+		t.Errorf("Expected 'Un' but got '%s'", tr)
+	}
+	if lang := po.GetDomain().Language; lang != "fr" {
+		t.Errorf("Expected 'fr' but got '%s'", lang)
+	}
+
+	// The built-in splitting doesn't know about form feeds
+	po = NewPo()
+	po.SetLineSplitter(split)
+	po.SetLineSplitter(nil)
+	po.ParseBytes([]byte(str))
+	if tr := po.Get("One"); tr == "Un" {
+		t.Error("Expected the built-in splitting to be restored")
+	}
+
+	// Splitter errors are reported
+	po = NewPo()
+	po.SetLineSplitter(func(data []byte, atEOF bool) (int, []byte, error) {
+		return 0, nil, errors.New("split failed")
+	})
+	if err := po.ParseBytes([]byte(str)); err == nil || !strings.Contains(err.Error(), "split failed") {
+		t.Errorf("Expected split error but got '%v'", err)
+	}
+}
+
+func TestPo_ParseBytes(t *testing.T) {
+	fixture, err := enUSFixture.ReadFile("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{fixture, largePo(100)} {
+		po := NewPo()
+		if err := po.ParseBytes(data); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		other := NewPo()
+		other.Parse(data)
+
+		expected, _ := other.MarshalText()
+		result, _ := po.MarshalText()
+		if string(result) != string(expected) {
+			t.Errorf("Expected ParseBytes and Parse results to be identical, got:\n%s", result)
+		}
+	}
+
+	po := NewPo()
+	po.ParseBytes(largePo(100))
+	if tr := po.GetC("Text 42", "Ctx"); tr != "Multi-line translated 42" {
+		t.Errorf("Expected 'Multi-line translated 42' but got '%s'", tr)
+	}
+}
+
+func BenchmarkPo_Parse(b *testing.B) {
+	data := largePo(10000)
+
+	b.Run("ParseFile", func(b *testing.B) {
+		dir := b.TempDir()
+		name := filepath.Join(dir, "large.po")
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f, err := os.Open(name)
+			if err != nil {
+				b.Fatal(err)
+			}
+			NewPo().ParseFile(f)
+			f.Close()
+		}
+	})
+
+	b.Run("ParseBytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewPo().ParseBytes(data)
+		}
+	})
+}
+
+func TestPo_SetStrict(t *testing.T) {
+	str := `msgid ""
+msgstr "Language: en\n"
+
+#. Before the entry
+msgid "One"
+
+# Between msgid and msgstr
+msgstr ""
+# Between msgstr lines
+"Un"
+
+msgctxt "menu"
+#: menu.go:10
+msgid "Open"
+msgstr "Ouvrir"
+# Belongs to the next entry
+
+msgid "Two"
+msgid_plural "Twos"
+
+msgstr[0] "Deux"
+#, fuzzy
+msgstr[1] "Deuxs"
+`
+
+	for _, strict := range []bool{false, true} {
+		po := NewPo()
+		po.SetStrict(strict)
+		err := po.ParseBytes([]byte(str))
+
+		if strict && !errors.Is(err, ErrParse) {
+			t.Errorf("Expected a parse error in strict mode but got '%v'", err)
+		}
+		if !strict && err != nil {
+			t.Errorf("Expected no error but got '%v'", err)
+		}
+
+		// Entries are loaded the same way in both modes
+		domain := po.GetDomain()
+		if tr := po.Get("One"); tr != "Un" {
+			t.Errorf("Expected 'Un' but got '%s'", tr)
+		}
+		if tr := po.GetN("Two", "Twos", 2); tr != "Deuxs" {
+			t.Errorf("Expected 'Deuxs' but got '%s'", tr)
+		}
+
+		one := domain.GetTranslations()["One"]
+		if strings.Join(one.ExtractedComments, "|") != "Before the entry" ||
+			strings.Join(one.Comments, "|") != "Between msgid and msgstr|Between msgstr lines" {
+			t.Errorf("Expected comments attached to 'One' but got '%v' and '%v'", one.ExtractedComments, one.Comments)
+		}
+
+		var open *Translation
+		domain.ForEachC(func(ctx, id string, trans *Translation) bool {
+			if ctx == "menu" && id == "Open" {
+				open = trans
+			}
+			return true
+		})
+		if open == nil || len(open.Refs) != 1 || len(open.Comments) != 0 {
+			t.Errorf("Expected a single ref on 'Open' but got '%v' and '%v'", open.Refs, open.Comments)
+		}
+
+		two := domain.GetTranslations()["Two"]
+		if strings.Join(two.Comments, "|") != "Belongs to the next entry" || !two.IsFuzzy() {
+			t.Errorf("Expected comments attached to 'Two' but got '%v' and '%v'", two.Comments, two.Flags)
+		}
+	}
+
+	// Well-formed files parse fine in strict mode
+	data, err := enUSFixture.ReadFile("fixtures/en_US/default.po")
+	if err != nil {
+		t.Fatal(err)
+	}
+	po := NewPo()
+	po.SetStrict(true)
+	if err = po.ParseBytes(data); err != nil {
+		t.Errorf("Expected no error but got '%v'", err)
+	}
+}
+
+func TestTranslation_SetN(t *testing.T) {
+	trans := NewTranslation()
+	trans.ID = "%d file"
+	trans.SetPluralID("%d files")
+	if trans.PluralID != "%d files" || trans.IsStale() {
+		t.Errorf("Expected a dirty plural entry but got '%s'", trans.PluralID)
+	}
+
+	trans.SetN(2, "%d plików")
+	trans.SetN(0, "%d plik")
+	trans.SetN(-1, "ignored")
+	if len(trans.Trs) != 3 || trans.Trs[1] != "" {
+		t.Errorf("Expected 3 forms with an empty one but got '%v'", trans.Trs)
+	}
+	if trans.IsTranslated() {
+		t.Error("Expected the entry not to be translated with an empty form")
+	}
+	trans.SetN(1, "%d pliki")
+
+	var buf bytes.Buffer
+	writeEntry(&buf, "", trans, false, false, nil)
+	expected := `
+
+msgid "%d file"
+msgid_plural "%d files"
+msgstr[0] "%d plik"
+msgstr[1] "%d pliki"
+msgstr[2] "%d plików"`
+	if buf.String() != expected {
+		t.Errorf("Expected '%s' but got '%s'", expected, buf.String())
+	}
+	if !trans.IsTranslated() || trans.GetN(2) != "%d plików" {
+		t.Errorf("Expected a translated entry but got '%v'", trans.Trs)
+	}
+}
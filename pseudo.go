@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PseudoOptions configures the transformations made by Domain.Pseudolocalize
+type PseudoOptions struct {
+	// Accents replaces ASCII letters with accented look-alikes ("Hello" -> "Ħëłłö")
+	Accents bool
+
+	// Expansion pads each string by this fraction of its length, to simulate longer languages (i.e. 0.3 for 30% longer)
+	Expansion float64
+
+	// Brackets wraps each string with "[" and "]", to spot truncated and concatenated strings
+	Brackets bool
+}
+
+// pseudoPlaceholderRe matches the placeholders kept untouched by Pseudolocalize:
+// fmt verbs, named verbs used by Sprintf and {name} style placeholders
+var pseudoPlaceholderRe = regexp.MustCompile(`%%|%\([a-zA-Z0-9_]+\)[.0-9]*[a-zA-Z]|%(\[\d+\])?[+\-# 0]*(\[\d+\])?(\d+|\*)?(\.(\[\d+\])?(\d+|\*)?)?[a-zA-Z]|\{[^{}]*\}`)
+
+var pseudoAccents = map[rune]rune{
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Đ', 'E': 'Ë', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ħ', 'I': 'Ï', 'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ł', 'M': 'Ṁ',
+	'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ŧ', 'U': 'Ü', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+	'a': 'å', 'b': 'ƀ', 'c': 'ç', 'd': 'đ', 'e': 'ë', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ħ', 'i': 'ï', 'j': 'ĵ', 'k': 'ķ', 'l': 'ł', 'm': 'ṁ',
+	'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ', 's': 'š', 't': 'ŧ', 'u': 'ü', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+}
+
+// Pseudolocalize returns a new domain where every Translation is transformed according to opts,
+// to find layout issues and untranslated strings in UI tests. Untranslated entries use their msgid (or plural id)
+// as source. Placeholders like "%s", "%[1]d", "%(name)s" or "{name}" are kept untouched.
+func (do *Domain) Pseudolocalize(opts PseudoOptions) *Domain {
+	pseudo := NewDomain()
+
+	do.pluralMutex.RLock()
+	pseudo.nplurals = do.nplurals
+	pseudo.plural = do.plural
+	pseudo.pluralforms = do.pluralforms
+	do.pluralMutex.RUnlock()
+
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	for k, v := range do.Headers {
+		pseudo.Headers[k] = append([]string(nil), v...)
+	}
+	pseudo.Language = do.Language
+	pseudo.tag = do.tag
+	pseudo.PluralForms = do.PluralForms
+	pseudo.HeaderComment = do.HeaderComment
+
+	forms := pseudo.nplurals
+	if forms < 2 {
+		forms = 2
+	}
+
+	pseudoTranslation := func(trans *Translation) *Translation {
+		newTrans := NewTranslationWithRefs(append([]string(nil), trans.Refs...))
+		newTrans.ID = trans.ID
+		newTrans.PluralID = trans.PluralID
+
+		// Keep the header untouched
+		trans.load()
+		if trans.ID == "" {
+			for k, v := range trans.Trs {
+				newTrans.Trs[k] = v
+			}
+			return newTrans
+		}
+
+		if trans.PluralID == "" {
+			newTrans.Trs[0] = opts.apply(trans.Get())
+			return newTrans
+		}
+
+		for n := 0; n < forms || n < len(trans.Trs); n++ {
+			newTrans.Trs[n] = opts.apply(trans.GetN(n))
+		}
+		return newTrans
+	}
+
+	for id, trans := range do.translations {
+		pseudo.translations[id] = pseudoTranslation(trans)
+		if trans.PluralID != "" {
+			pseudo.pluralTranslations[trans.PluralID] = pseudo.translations[id]
+		}
+	}
+	for ctx, translations := range do.contexts {
+		pseudo.contexts[ctx] = make(map[string]*Translation, len(translations))
+		for id, trans := range translations {
+			pseudo.contexts[ctx][id] = pseudoTranslation(trans)
+		}
+	}
+
+	return pseudo
+}
+
+// apply transforms str, leaving placeholders untouched
+func (opts PseudoOptions) apply(str string) string {
+	var buf strings.Builder
+
+	if opts.Brackets {
+		buf.WriteString("[")
+	}
+
+	last := 0
+	for _, loc := range pseudoPlaceholderRe.FindAllStringIndex(str, -1) {
+		buf.WriteString(opts.accent(str[last:loc[0]]))
+		buf.WriteString(str[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	buf.WriteString(opts.accent(str[last:]))
+
+	if pad := int(math.Ceil(float64(utf8.RuneCountInString(str)) * opts.Expansion)); pad > 0 {
+		buf.WriteString(" " + strings.Repeat("!", pad-1))
+	}
+
+	if opts.Brackets {
+		buf.WriteString("]")
+	}
+
+	return buf.String()
+}
+
+// accent replaces ASCII letters of str with accented look-alikes, if enabled
+func (opts PseudoOptions) accent(str string) string {
+	if !opts.Accents {
+		return str
+	}
+
+	return strings.Map(func(r rune) rune {
+		if a, ok := pseudoAccents[r]; ok {
+			return a
+		}
+		return r
+	}, str)
+}
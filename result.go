@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+// TranslationResult is the string returned by a Get* method along with what's known about its Translation,
+// i.e. to mark untranslated strings in a debug build. See Locale.GetResult.
+type TranslationResult struct {
+	// Text is the formatted string, as returned by the matching Get* method
+	Text string
+
+	// Translated reports whether Text comes from a non-empty Translation
+	Translated bool
+
+	// Fuzzy reports whether that Translation is flagged as fuzzy
+	Fuzzy bool
+
+	// Language is the language of the Translation, empty if there's none
+	Language string
+
+	// UsedFallback reports whether Text isn't a Translation in the language of the Locale:
+	// it's the source string (or an empty string, see SetFallbackToSource), or in key mode a Translation
+	// of the base language
+	UsedFallback bool
+}
+
+// GetResult works like Get, returning the Translation along with its metadata.
+func (l *Locale) GetResult(str string, vars ...interface{}) TranslationResult {
+	return l.GetNDCResult(l.GetDomain(), str, "", 0, "", vars...)
+}
+
+// GetNDCResult works like GetNDC, returning the Translation along with its metadata.
+// An empty plural works like GetDC, looking up the singular form, and an empty ctx like GetND, looking up the string
+// without context, so GetNDCResult(dom, str, "", 0, "") gives the Text of GetD(dom, str).
+func (l *Locale) GetNDCResult(dom, str, plural string, n int, ctx string, vars ...interface{}) TranslationResult {
+	var text string
+	switch {
+	case plural == "" && ctx == "":
+		text = l.GetD(dom, str, vars...)
+	case plural == "":
+		text = l.GetDC(dom, str, ctx, vars...)
+	case ctx == "":
+		text = l.GetND(dom, str, plural, n, vars...)
+	default:
+		text = l.GetNDC(dom, str, plural, n, ctx, vars...)
+	}
+
+	l.RLock()
+	res := l.lookupState(dom, str, ctx, plural != "", n)
+	l.RUnlock()
+
+	res.Text = text
+	return res
+}
+
+// lookupState returns the TranslationResult, without Text, of the lookup of str by the Get* methods.
+// The Locale must be read-locked.
+func (l *Locale) lookupState(dom, str, ctx string, plural bool, n int) TranslationResult {
+	var res TranslationResult
+	if _, ok := l.envOverride(dom, ctx, str); ok {
+		res.Translated, res.Language = true, l.lang
+		return res
+	}
+	if tr := l.Domains[dom]; tr != nil {
+		if res.Translated, res.Fuzzy = tr.GetDomain().translationState(str, ctx, plural, n); res.Translated {
+			res.Language = l.lang
+			return res
+		}
+	}
+
+	// Key mode falls back to the base language
+	res.UsedFallback = true
+	if l.keyMode && l.base != nil {
+		l.base.RLock()
+		tr, lang := l.base.Domains[dom], l.base.lang
+		l.base.RUnlock()
+
+		if tr != nil {
+			if res.Translated, res.Fuzzy = tr.GetDomain().translationState(str, ctx, plural, n); res.Translated {
+				res.Language = lang
+			}
+		}
+	}
+	return res
+}
+
+// translationState reports whether the form looked up by the Get* methods for str, in ctx or without context
+// if ctx is empty, has a non-empty Translation, and whether it's fuzzy. n selects the plural form if plural is set.
+func (do *Domain) translationState(str, ctx string, plural bool, n int) (translated, fuzzy bool) {
+	form := 0
+	if plural {
+		form = do.pluralForm(n)
+	}
+
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	var trans *Translation
+	var ok bool
+	if ctx == "" {
+		trans, ok = do.lookup(str, nil)
+	} else {
+		trans, ok = do.lookup(str, &ctx)
+	}
+	if !ok || !trans.IsTranslatedN(form) {
+		return false, false
+	}
+	return true, trans.IsFuzzy()
+}
+
+// getResult works like GetN and GetNC, or Get and GetC when plural is false, returning the formatted string along
+// with whether the looked up form has a non-empty Translation, and whether it's fuzzy, from a single lookup.
+// ctx is nil to look up str without context. The Language and UsedFallback of the result aren't set.
+func (do *Domain) getResult(str, plural string, n int, ctx *string, isPlural bool, vars []interface{}) TranslationResult {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	form := 0
+	if isPlural {
+		form = do.pluralForm(n)
+	}
+
+	if trans, ok := do.lookup(str, ctx); ok {
+		translated := trans.IsTranslatedN(form)
+		return TranslationResult{
+			Text:       trans.format(do.translated(trans, form), vars),
+			Translated: translated,
+			Fuzzy:      translated && trans.IsFuzzy(),
+		}
+	}
+
+	// Like GetN, and GetNC which only tells the singular with n == 1
+	if isPlural && ((ctx == nil && form != 0) || (ctx != nil && n != 1)) {
+		return TranslationResult{Text: Printf(plural, vars...)}
+	}
+	return TranslationResult{Text: Printf(str, vars...)}
+}
@@ -5,6 +5,15 @@
 
 package gotext
 
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 // Translation is the struct for the Translations parsed via Po or Mo files and all coming parsers
 type Translation struct {
 	ID       string
@@ -12,7 +21,43 @@ type Translation struct {
 	Trs      map[int]string
 	Refs     []string
 
+	// Comments are preserved (without their prefix) for round-trip
+	Comments          []string // Translator comments (#)
+	ExtractedComments []string // Extracted comments (#.)
+	Flags             []string // Flags (#,)
+	Previous          []string // Previous msgctxt/msgid lines (#| or #~|), kept verbatim
+
+	// Line of the msgid in the parsed .po file, or 0 if unknown
+	Line int
+
 	dirty bool
+
+	// Undecoded msgstr of an entry parsed by a lazy Mo, nil otherwise
+	lazy *lazyMsgstr
+}
+
+// lazyMsgstr holds the msgstr of a .mo entry, the plural forms separated by NUL, until it's decoded into Trs
+type lazyMsgstr struct {
+	once sync.Once
+	data []byte
+
+	// Memory mapped file data points to, kept alive until the msgstr is decoded, or nil
+	src *mapping
+}
+
+// load decodes the msgstr of a Translation parsed by a lazy Mo into Trs, on first call.
+// It's safe for concurrent use, and must be called before using Trs directly.
+func (t *Translation) load() {
+	if t.lazy == nil {
+		return
+	}
+	t.lazy.once.Do(func() {
+		for i, s := range bytes.Split(t.lazy.data, []byte(NulSeparator)) {
+			t.Trs[i] = string(s)
+		}
+		t.lazy.data = nil
+		t.lazy.src = nil
+	})
 }
 
 // NewTranslation returns the Translation object and initialized it.
@@ -29,22 +74,128 @@ func NewTranslationWithRefs(refs []string) *Translation {
 	}
 }
 
+// clone returns a deep copy of the Translation
+func (t *Translation) clone() *Translation {
+	t.load()
+
+	newTrans := NewTranslation()
+	newTrans.ID = t.ID
+	newTrans.PluralID = t.PluralID
+	newTrans.Line = t.Line
+	newTrans.dirty = t.dirty
+	if len(t.Refs) > 0 {
+		newTrans.Refs = make([]string, len(t.Refs))
+		copy(newTrans.Refs, t.Refs)
+	}
+	newTrans.Comments = append([]string(nil), t.Comments...)
+	newTrans.ExtractedComments = append([]string(nil), t.ExtractedComments...)
+	newTrans.Flags = append([]string(nil), t.Flags...)
+	newTrans.Previous = append([]string(nil), t.Previous...)
+	for k, v := range t.Trs {
+		newTrans.Trs[k] = v
+	}
+
+	return newTrans
+}
+
+// Equal reports whether t and other hold the same entry:
+// IDs, plural ID, all translation forms, refs, comments and flags must match.
+// The Line and the internal dirty state aren't compared.
+func (t *Translation) Equal(other *Translation) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+
+	if t.ID != other.ID || t.PluralID != other.PluralID {
+		return false
+	}
+
+	t.load()
+	other.load()
+	if len(t.Trs) != len(other.Trs) {
+		return false
+	}
+	for k, v := range t.Trs {
+		if o, ok := other.Trs[k]; !ok || o != v {
+			return false
+		}
+	}
+
+	return equalStrings(t.Refs, other.Refs) &&
+		equalStrings(t.Comments, other.Comments) &&
+		equalStrings(t.ExtractedComments, other.ExtractedComments) &&
+		equalStrings(t.Flags, other.Flags) &&
+		equalStrings(t.Previous, other.Previous)
+}
+
+// equalStrings compares two string slices, treating nil and empty slices as equal
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// subsetStrings reports whether every string of the sorted slice a is in the sorted slice b, as many times
+func subsetStrings(a, b []string) bool {
+	j := 0
+	for _, s := range a {
+		for j < len(b) && b[j] < s {
+			j++
+		}
+		if j == len(b) || b[j] != s {
+			return false
+		}
+		j++
+	}
+	return true
+}
+
 func (t *Translation) IsStale() bool {
 	return t.dirty == false
 }
 
+// SetRefs replaces the source references of the Translation, written as "#:" comments by MarshalText.
+// Duplicated references are only kept once, in their first position.
 func (t *Translation) SetRefs(refs []string) {
-	t.Refs = refs
+	t.Refs = nil
+	for _, ref := range refs {
+		t.addRef(ref)
+	}
+	t.dirty = true
+}
+
+// AddRef adds a source reference, i.e. "main.go:42", unless the Translation already has it
+func (t *Translation) AddRef(ref string) {
+	t.addRef(ref)
 	t.dirty = true
 }
 
+func (t *Translation) addRef(ref string) {
+	for _, r := range t.Refs {
+		if r == ref {
+			return
+		}
+	}
+	t.Refs = append(t.Refs, ref)
+}
+
+// Set sets the translation of a singular entry, which is also the first plural form, and marks it dirty
 func (t *Translation) Set(str string) {
+	t.load()
 	t.Trs[0] = str
 	t.dirty = true
 }
 
 // Get returns the string of the translation
 func (t *Translation) Get() string {
+	t.load()
+
 	// Look for Translation index 0
 	if _, ok := t.Trs[0]; ok {
 		if t.Trs[0] != "" {
@@ -56,13 +207,211 @@ func (t *Translation) Get() string {
 	return t.ID
 }
 
+// SetN sets the (N)th plural form of the translation, 0 being the singular form, and marks it dirty.
+// Setting a form beyond the current ones adds the forms in between as empty strings, so they're all written by MarshalText.
+// Negative indexes are ignored.
 func (t *Translation) SetN(n int, str string) {
+	if n < 0 {
+		return
+	}
+
+	t.load()
+	for i := 0; i < n; i++ {
+		if _, ok := t.Trs[i]; !ok {
+			t.Trs[i] = ""
+		}
+	}
 	t.Trs[n] = str
 	t.dirty = true
 }
 
+// SetPluralID sets the msgid_plural of the entry and marks it dirty.
+// Entries with a msgid_plural are plural entries, whose forms are set with SetN.
+func (t *Translation) SetPluralID(plural string) {
+	t.PluralID = plural
+	t.dirty = true
+}
+
+// IsTranslated reports whether the entry has a translation for all of its forms.
+// A msgstr identical to the msgid counts as translated, only empty ones don't.
+func (t *Translation) IsTranslated() bool {
+	t.load()
+
+	if len(t.Trs) == 0 {
+		return false
+	}
+
+	for _, tr := range t.Trs {
+		if tr == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsFuzzy reports whether the entry is flagged as fuzzy (#, fuzzy), meaning its Translation needs review
+func (t *Translation) IsFuzzy() bool {
+	for _, flag := range t.Flags {
+		if flag == "fuzzy" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Range returns the bounds of the "range: lo..hi" flag, which tells the plural entry is only used with n in that range,
+// and whether the entry has a valid one. Like other flags, it's kept as is by MarshalText.
+func (t *Translation) Range() (lo, hi int, ok bool) {
+	for _, flag := range t.Flags {
+		if !strings.HasPrefix(flag, "range:") {
+			continue
+		}
+
+		bounds := strings.SplitN(strings.TrimSpace(flag[len("range:"):]), "..", 2)
+		if len(bounds) != 2 {
+			return 0, 0, false
+		}
+
+		lo, errLo := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		hi, errHi := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if errLo != nil || errHi != nil || lo > hi {
+			return 0, 0, false
+		}
+		return lo, hi, true
+	}
+
+	return 0, 0, false
+}
+
+// IsGoFormat reports whether the entry is a Go format string: it's flagged as go-format,
+// or it isn't flagged as no-go-format and its msgid or plural id holds fmt verbs.
+func (t *Translation) IsGoFormat() bool {
+	for _, flag := range t.Flags {
+		switch flag {
+		case "go-format":
+			return true
+		case "no-go-format":
+			return false
+		}
+	}
+
+	return len(formatVerbs(t.ID)) > 0 || len(formatVerbs(t.PluralID)) > 0
+}
+
+// IsFormat reports whether the entry is a format string, to be formatted with the vars given to the Get* methods.
+// Flags take precedence: c-format or go-format make it one, no-c-format or no-go-format don't.
+// Without such flags, it's a format string if its msgid, plural id or translations hold fmt verbs (or escaped percents).
+// The Get* methods return the translations of other entries as is, even when vars are given,
+// so a translation legitimately holding a lone "%" isn't mangled by fmt.
+func (t *Translation) IsFormat() bool {
+	if format, ok := t.formatFlag(); ok {
+		return format
+	}
+	if formatVerbRe.MatchString(t.ID) || formatVerbRe.MatchString(t.PluralID) {
+		return true
+	}
+
+	t.load()
+	for _, str := range t.Trs {
+		if formatVerbRe.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFlag returns whether the entry is flagged as a format string, with ok false if it has no such flag
+func (t *Translation) formatFlag() (format, ok bool) {
+	for _, flag := range t.Flags {
+		switch flag {
+		case "c-format", "go-format":
+			return true, true
+		case "no-c-format", "no-go-format":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// format formats str, a translation of the entry, with vars if the entry is a format string.
+// Like IsFormat, but only str is checked among the translations.
+func (t *Translation) format(str string, vars []interface{}) string {
+	if len(vars) == 0 {
+		return str
+	}
+
+	format, ok := t.formatFlag()
+	if !ok {
+		format = formatVerbRe.MatchString(str) || formatVerbRe.MatchString(t.ID) || formatVerbRe.MatchString(t.PluralID)
+	}
+	if !format {
+		return str
+	}
+	return fmt.Sprintf(str, vars...)
+}
+
+// CheckFormat verifies every translated form of a Go format string uses the same fmt verbs as its source,
+// so formatting it gives the same result as formatting the msgid. Verbs are compared by type, in any order.
+// Plural forms can match either the msgid or the plural id, and they may leave verbs out,
+// as in "One file" for "%d file", but they can't add or change any.
+// It returns an error wrapping ErrFormat for the first mismatch, or nil.
+func (t *Translation) CheckFormat() error {
+	t.load()
+
+	if t.ID == "" || !t.IsGoFormat() {
+		return nil
+	}
+
+	sources := [][]string{formatVerbs(t.ID)}
+	if t.PluralID != "" {
+		sources = append(sources, formatVerbs(t.PluralID))
+	}
+
+	forms := make([]int, 0, len(t.Trs))
+	for n := range t.Trs {
+		forms = append(forms, n)
+	}
+	sort.Ints(forms)
+
+	for _, n := range forms {
+		if t.Trs[n] == "" {
+			continue
+		}
+
+		verbs := formatVerbs(t.Trs[n])
+		match := false
+		for _, source := range sources {
+			if t.PluralID == "" {
+				match = match || equalStrings(verbs, source)
+			} else {
+				match = match || subsetStrings(verbs, source)
+			}
+		}
+		if !match {
+			if t.PluralID == "" {
+				return fmt.Errorf("%w: msgstr %q doesn't match the verbs of msgid %q", ErrFormat, t.Trs[n], t.ID)
+			}
+			return fmt.Errorf("%w: msgstr[%d] %q doesn't match the verbs of msgid %q", ErrFormat, n, t.Trs[n], t.ID)
+		}
+	}
+
+	return nil
+}
+
+// IsTranslatedN reports whether the (N)th plural form index has a non-empty translation
+func (t *Translation) IsTranslatedN(n int) bool {
+	t.load()
+
+	tr, ok := t.Trs[n]
+	return ok && tr != ""
+}
+
 // GetN returns the string of the plural translation
 func (t *Translation) GetN(n int) string {
+	t.load()
+
 	// Look for Translation index
 	if _, ok := t.Trs[n]; ok {
 		if t.Trs[n] != "" {
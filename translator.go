@@ -28,6 +28,8 @@ type Translator interface {
 }
 
 // TranslatorEncoding is used as intermediary storage to encode Translator objects to Gob.
+// The package encodes it as a concrete type, so it isn't registered with gob.Register: applications encoding it
+// within an interface value have to register it themselves.
 type TranslatorEncoding struct {
 	// Headers storage
 	Headers HeaderMap
@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) 2018 DeineAgentur UG https://www.deineagentur.com. All rights reserved.
+ * Licensed under the MIT License. See LICENSE file in the project root for full license information.
+ */
+
+package gotext
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+)
+
+const (
+	// XLIFFNamespace is the XML namespace of XLIFF 1.2 documents
+	XLIFFNamespace = "urn:oasis:names:tc:xliff:document:1.2"
+
+	// XLIFFSourceLanguage is the source-language declared on documents created by MarshalXLIFF
+	XLIFFSourceLanguage = "en"
+
+	xliffPluralRestype   = "x-gettext-plurals"
+	xliffContextType     = "x-gettext-msgctxt"
+	xliffContextPurpose  = "information"
+	xliffDefaultDatatype = "po"
+)
+
+// XLIFF 1.2 document structure, limited to the elements used to exchange gettext catalogs
+type xliffDocument struct {
+	XMLName xml.Name  `xml:"xliff"`
+	Xmlns   string    `xml:"xmlns,attr,omitempty"`
+	Version string    `xml:"version,attr"`
+	File    xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string    `xml:"original,attr"`
+	SourceLanguage string    `xml:"source-language,attr"`
+	TargetLanguage string    `xml:"target-language,attr,omitempty"`
+	Datatype       string    `xml:"datatype,attr"`
+	Body           xliffBody `xml:"body"`
+}
+
+type xliffBody struct {
+	Units  []xliffUnit  `xml:"trans-unit"`
+	Groups []xliffGroup `xml:"group"`
+}
+
+type xliffGroup struct {
+	ID       string              `xml:"id,attr"`
+	Restype  string              `xml:"restype,attr,omitempty"`
+	Contexts []xliffContextGroup `xml:"context-group,omitempty"`
+	Units    []xliffUnit         `xml:"trans-unit"`
+}
+
+type xliffUnit struct {
+	ID       string              `xml:"id,attr"`
+	Resname  string              `xml:"resname,attr,omitempty"`
+	Source   string              `xml:"source"`
+	Target   *string             `xml:"target"`
+	Contexts []xliffContextGroup `xml:"context-group,omitempty"`
+}
+
+type xliffContextGroup struct {
+	Purpose  string         `xml:"purpose,attr,omitempty"`
+	Contexts []xliffContext `xml:"context"`
+}
+
+type xliffContext struct {
+	Type  string `xml:"context-type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// newXLIFFContexts returns the context-group holding the msgctxt, or nil for entries without context
+func newXLIFFContexts(ctx string) []xliffContextGroup {
+	if ctx == "" {
+		return nil
+	}
+
+	return []xliffContextGroup{{
+		Purpose:  xliffContextPurpose,
+		Contexts: []xliffContext{{Type: xliffContextType, Value: ctx}},
+	}}
+}
+
+// xliffMsgctxt returns the msgctxt stored on a list of context-group elements
+func xliffMsgctxt(groups []xliffContextGroup) string {
+	for _, g := range groups {
+		for _, c := range g.Contexts {
+			if c.Type == xliffContextType {
+				return c.Value
+			}
+		}
+	}
+
+	return ""
+}
+
+// MarshalXLIFF returns the domain as an XLIFF 1.2 document.
+// Each entry is written as a <trans-unit>, with the msgid as resname and source and the Translation as target.
+// Plural entries are written as a <group> of trans-units, one per plural form, with the msgid as source of the first one
+// and the plural id as source of the others. The msgctxt, if any, is stored in a <context-group>.
+// The header entry and obsolete entries aren't exported.
+func (do *Domain) MarshalXLIFF() ([]byte, error) {
+	do.trMutex.RLock()
+	defer do.trMutex.RUnlock()
+
+	do.pluralMutex.RLock()
+	nplurals := do.nplurals
+	do.pluralMutex.RUnlock()
+
+	doc := xliffDocument{
+		Xmlns:   XLIFFNamespace,
+		Version: "1.2",
+		File: xliffFile{
+			Original:       "messages",
+			SourceLanguage: XLIFFSourceLanguage,
+			Datatype:       xliffDefaultDatatype,
+		},
+	}
+	if do.Language != "" {
+		// XLIFF uses BCP 47 language tags (i.e. "en-US" instead of "en_US")
+		doc.File.TargetLanguage = do.tag.String()
+	}
+
+	// sort contexts and ids for a stable output
+	contexts := make([]string, 0, len(do.contexts)+1)
+	contexts = append(contexts, "")
+	for ctx := range do.contexts {
+		contexts = append(contexts, ctx)
+	}
+	sort.Strings(contexts[1:])
+
+	seq := 0
+	for _, ctx := range contexts {
+		entries := do.translations
+		if ctx != "" {
+			entries = do.contexts[ctx]
+		}
+
+		ids := make([]string, 0, len(entries))
+		for id := range entries {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			trans := entries[id]
+			trans.load()
+			seq++
+			unitID := strconv.Itoa(seq)
+
+			if trans.PluralID == "" {
+				doc.File.Body.Units = append(doc.File.Body.Units, xliffUnit{
+					ID:       unitID,
+					Resname:  trans.ID,
+					Source:   trans.ID,
+					Target:   xliffTarget(trans, 0),
+					Contexts: newXLIFFContexts(ctx),
+				})
+				continue
+			}
+
+			forms := nplurals
+			for n := range trans.Trs {
+				if n+1 > forms {
+					forms = n + 1
+				}
+			}
+			if forms < 2 {
+				forms = 2
+			}
+
+			group := xliffGroup{
+				ID:       unitID,
+				Restype:  xliffPluralRestype,
+				Contexts: newXLIFFContexts(ctx),
+			}
+			for n := 0; n < forms; n++ {
+				source := trans.PluralID
+				if n == 0 {
+					source = trans.ID
+				}
+				group.Units = append(group.Units, xliffUnit{
+					ID:      unitID + "[" + strconv.Itoa(n) + "]",
+					Resname: trans.ID,
+					Source:  source,
+					Target:  xliffTarget(trans, n),
+				})
+			}
+			doc.File.Body.Groups = append(doc.File.Body.Groups, group)
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// xliffTarget returns the target of the (N)th form of trans, or nil if it isn't translated
+func xliffTarget(trans *Translation, n int) *string {
+	trans.load()
+	if tr, ok := trans.Trs[n]; ok && tr != "" {
+		return &tr
+	}
+
+	return nil
+}
+
+// UnmarshalXLIFF loads the translations of an XLIFF 1.2 document into the domain,
+// as written by MarshalXLIFF and returned by localization vendors.
+// Existing entries get their Translation updated and unknown entries are added.
+// Trans-units without target are loaded as untranslated.
+func (do *Domain) UnmarshalXLIFF(data []byte) error {
+	var doc xliffDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return newParseError("%w", err)
+	}
+
+	do.trMutex.Lock()
+	defer do.trMutex.Unlock()
+
+	if doc.File.TargetLanguage != "" && do.Language == "" {
+		do.Language = doc.File.TargetLanguage
+	}
+
+	for _, unit := range doc.File.Body.Units {
+		trans := do.xliffEntry(xliffMsgctxt(unit.Contexts), xliffID(unit), "")
+		trans.SetN(0, xliffTargetValue(unit))
+	}
+
+	for _, group := range doc.File.Body.Groups {
+		if len(group.Units) == 0 {
+			continue
+		}
+
+		plural := ""
+		if len(group.Units) > 1 {
+			plural = group.Units[1].Source
+		}
+
+		trans := do.xliffEntry(xliffMsgctxt(group.Contexts), xliffID(group.Units[0]), plural)
+		for n, unit := range group.Units {
+			trans.SetN(n, xliffTargetValue(unit))
+		}
+	}
+
+	return nil
+}
+
+// xliffID returns the msgid of a trans-unit
+func xliffID(unit xliffUnit) string {
+	if unit.Resname != "" {
+		return unit.Resname
+	}
+
+	return unit.Source
+}
+
+// xliffTargetValue returns the target of a trans-unit, or an empty string if missing
+func xliffTargetValue(unit xliffUnit) string {
+	if unit.Target == nil {
+		return ""
+	}
+
+	return *unit.Target
+}
+
+// xliffEntry returns the Translation for id in ctx, creating it if needed. trMutex must be held.
+func (do *Domain) xliffEntry(ctx, id, plural string) *Translation {
+	entries := do.translations
+	if ctx != "" {
+		if _, ok := do.contexts[ctx]; !ok {
+			do.contexts[ctx] = make(map[string]*Translation)
+		}
+		entries = do.contexts[ctx]
+	}
+
+	trans, ok := entries[id]
+	if !ok {
+		trans = NewTranslation()
+		trans.ID = id
+		entries[id] = trans
+	}
+
+	if plural != "" {
+		trans.PluralID = plural
+		do.pluralTranslations[plural] = trans
+	}
+
+	return trans
+}